@@ -0,0 +1,69 @@
+package alerts
+
+import (
+	"cashier-api/models"
+	"context"
+	"log"
+	"time"
+)
+
+// Source - Implemented by repositories.ProductRepository
+type Source interface {
+	ClaimLowStockAlerts(defaultReorderLevel int) ([]models.LowStockItem, error)
+	ResetLowStockAlerts(defaultReorderLevel int) (int64, error)
+}
+
+// Checker - Periodically looks for products that crossed their reorder level
+// and notifies once per crossing. A product alerts again only after its
+// stock went back above the level and dropped again.
+type Checker struct {
+	source              Source
+	notifier            Notifier
+	interval            time.Duration
+	defaultReorderLevel int
+}
+
+func NewChecker(source Source, notifier Notifier, interval time.Duration, defaultReorderLevel int) *Checker {
+	return &Checker{
+		source:              source,
+		notifier:            notifier,
+		interval:            interval,
+		defaultReorderLevel: defaultReorderLevel,
+	}
+}
+
+// Run - Check immediately and then every interval until ctx is cancelled
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Check(ctx); err != nil {
+			log.Printf("alerts: low-stock check failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check - Run one check. Alerts are claimed before notifying, so a failed
+// delivery is logged and not retried (at most once per crossing).
+func (c *Checker) Check(ctx context.Context) error {
+	if _, err := c.source.ResetLowStockAlerts(c.defaultReorderLevel); err != nil {
+		return err
+	}
+
+	items, err := c.source.ClaimLowStockAlerts(c.defaultReorderLevel)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	return c.notifier.Notify(ctx, items)
+}
@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cashier-api/models"
+)
+
+// fakeSource - Products with a stock, a reorder level and the alerted flag
+// kept by the low_stock_alerted_at column
+type fakeSource struct {
+	stock    map[int]int64
+	level    map[int]int64 // missing = the default level
+	alerted  map[int]bool
+	claimErr error
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{stock: map[int]int64{}, level: map[int]int64{}, alerted: map[int]bool{}}
+}
+
+func (s *fakeSource) low(id int, defaultLevel int) bool {
+	level, ok := s.level[id]
+	if !ok {
+		level = int64(defaultLevel)
+	}
+	return s.stock[id] <= level
+}
+
+func (s *fakeSource) ClaimLowStockAlerts(defaultReorderLevel int) ([]models.LowStockItem, error) {
+	if s.claimErr != nil {
+		return nil, s.claimErr
+	}
+	var items []models.LowStockItem
+	for id := range s.stock {
+		if !s.alerted[id] && s.low(id, defaultReorderLevel) {
+			s.alerted[id] = true
+			items = append(items, models.LowStockItem{ProductID: id, Stock: models.NewQuantity(s.stock[id])})
+		}
+	}
+	return items, nil
+}
+
+func (s *fakeSource) ResetLowStockAlerts(defaultReorderLevel int) (int64, error) {
+	var reset int64
+	for id := range s.stock {
+		if s.alerted[id] && !s.low(id, defaultReorderLevel) {
+			s.alerted[id] = false
+			reset++
+		}
+	}
+	return reset, nil
+}
+
+// recorder - Notifier remembering the products of every call
+type recorder struct {
+	calls [][]int
+	err   error
+}
+
+func (r *recorder) Notify(ctx context.Context, items []models.LowStockItem) error {
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+	r.calls = append(r.calls, ids)
+	return r.err
+}
+
+func TestCheckerOncePerCrossing(t *testing.T) {
+	source := newFakeSource()
+	source.stock[1] = 20
+	source.stock[2] = 20
+	source.level[2] = 10
+	notifier := &recorder{}
+	checker := NewChecker(source, notifier, 0, 5)
+
+	steps := []struct {
+		name  string
+		stock map[int]int64
+		want  []int // alerted products, nil = no notification
+	}{
+		{"above the levels", nil, nil},
+		{"1 at the default level", map[int]int64{1: 5}, []int{1}},
+		{"1 still low", map[int]int64{1: 3}, nil},
+		{"2 below its own level", map[int]int64{2: 9}, []int{2}},
+		{"1 recovers", map[int]int64{1: 6}, nil},
+		{"1 drops again", map[int]int64{1: 0}, []int{1}},
+		{"2 lower still", map[int]int64{2: 8}, nil},
+	}
+
+	for _, step := range steps {
+		for id, stock := range step.stock {
+			source.stock[id] = stock
+		}
+		calls := len(notifier.calls)
+		if err := checker.Check(context.Background()); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		switch {
+		case step.want == nil && len(notifier.calls) != calls:
+			t.Errorf("%s: notified %v", step.name, notifier.calls[calls:])
+		case step.want != nil && (len(notifier.calls) != calls+1 || !slices.Equal(notifier.calls[calls], step.want)):
+			t.Errorf("%s: notified %v, want %v", step.name, notifier.calls[calls:], step.want)
+		}
+	}
+}
+
+func TestCheckerNotifierFailure(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	source := newFakeSource()
+	source.stock[1] = 0
+	notifier := &recorder{err: errors.New("connection refused")}
+	checker := NewChecker(source, notifier, time.Hour, 5)
+
+	// Run checks once before looking at the cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checker.Run(ctx)
+
+	if !strings.Contains(logged.String(), "low-stock check failed: connection refused") {
+		t.Errorf("failure not logged: %q", logged.String())
+	}
+	if !source.alerted[1] {
+		t.Error("alert not claimed before notifying")
+	}
+
+	notifier.err = nil
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.calls) != 1 {
+		t.Errorf("notified %d times, want the failed alert not retried", len(notifier.calls))
+	}
+}
+
+func TestCheckerSourceFailure(t *testing.T) {
+	source := newFakeSource()
+	source.stock[1] = 0
+	source.claimErr = errors.New("database down")
+	notifier := &recorder{}
+
+	err := NewChecker(source, notifier, 0, 5).Check(context.Background())
+	if err != source.claimErr {
+		t.Errorf("error = %v, want %v", err, source.claimErr)
+	}
+	if len(notifier.calls) != 0 {
+		t.Error("notified without alerts")
+	}
+}
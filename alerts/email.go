@@ -0,0 +1,40 @@
+package alerts
+
+import (
+	"cashier-api/models"
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// EmailNotifier - Send alerts by email through an SMTP server without
+// authentication (a local relay or a stand-in such as MailHog)
+type EmailNotifier struct {
+	addr string
+	from string
+	to   []string
+}
+
+func NewEmailNotifier(addr, from string, to []string) *EmailNotifier {
+	return &EmailNotifier{addr: addr, from: from, to: to}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, items []models.LowStockItem) error {
+	var body strings.Builder
+	body.WriteString("The following products are at or below their reorder level:\r\n\r\n")
+	for _, item := range items {
+		body.WriteString("- " + describe(item) + "\r\n")
+	}
+
+	message := "From: " + n.from + "\r\n" +
+		"To: " + strings.Join(n.to, ", ") + "\r\n" +
+		fmt.Sprintf("Subject: Low stock: %d product(s)\r\n", len(items)) +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body.String()
+
+	if err := smtp.SendMail(n.addr, nil, n.from, n.to, []byte(message)); err != nil {
+		return fmt.Errorf("low-stock email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpServer - Minimal SMTP server accepting one message per connection;
+// reject makes it refuse the recipients. Returns its address and the
+// received message data.
+func smtpServer(t *testing.T, reject bool) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "RCPT") && reject:
+				reply("550 mailbox unavailable")
+			case command == "DATA":
+				reply("354 end with .")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestEmailNotifier(t *testing.T) {
+	addr, messages := smtpServer(t, false)
+	notifier := NewEmailNotifier(addr, "cashier@example.com", []string{"owner@example.com", "buyer@example.com"})
+
+	if err := notifier.Notify(context.Background(), lowStockItems()); err != nil {
+		t.Fatal(err)
+	}
+	message := <-messages
+	for _, want := range []string{
+		"From: cashier@example.com\r\n",
+		"To: owner@example.com, buyer@example.com\r\n",
+		"Subject: Low stock: 2 product(s)\r\n",
+		"- Indomie Godog (#1, Food): stock 3 pcs, reorder level 5, suggested order 24 pcs\r\n",
+		"- Beras (#2, Food): stock in Cabang Bandung 1 kg, reorder level 10, suggested order 25 kg\r\n",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message has no %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestEmailNotifierRejected(t *testing.T) {
+	addr, _ := smtpServer(t, true)
+	err := NewEmailNotifier(addr, "cashier@example.com", []string{"owner@example.com"}).
+		Notify(context.Background(), lowStockItems())
+	if err == nil || !strings.Contains(err.Error(), "low-stock email: 550") {
+		t.Fatalf("error = %v, want the SMTP rejection", err)
+	}
+}
@@ -0,0 +1,17 @@
+package alerts
+
+import (
+	"cashier-api/models"
+	"context"
+	"log"
+)
+
+// LogNotifier - Write alerts to the standard logger
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, items []models.LowStockItem) error {
+	for _, item := range items {
+		log.Printf("low stock: %s", describe(item))
+	}
+	return nil
+}
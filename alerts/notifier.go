@@ -0,0 +1,72 @@
+package alerts
+
+import (
+	"cashier-api/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Notifier - Delivers low-stock alerts (log, webhook, email, ...)
+type Notifier interface {
+	Notify(ctx context.Context, items []models.LowStockItem) error
+}
+
+// Multi - Send every alert through all notifiers; one failing does not stop
+// the others
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, items []models.LowStockItem) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, items); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Config - Settings for the notifiers selected by name in Names
+type Config struct {
+	Names      []string // log, webhook, email
+	WebhookURL string
+	SMTPAddr   string // host:port of the SMTP server, e.g. a local MailHog
+	EmailFrom  string
+	EmailTo    []string
+}
+
+// New - Build the notifiers listed in cfg.Names
+func New(cfg Config) (Notifier, error) {
+	var notifiers Multi
+	for _, name := range cfg.Names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "log":
+			notifiers = append(notifiers, LogNotifier{})
+		case "webhook":
+			if cfg.WebhookURL == "" {
+				return nil, errors.New("webhook notifier needs ALERT_WEBHOOK_URL")
+			}
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL))
+		case "email":
+			if cfg.SMTPAddr == "" || cfg.EmailFrom == "" || len(cfg.EmailTo) == 0 {
+				return nil, errors.New("email notifier needs ALERT_SMTP_ADDR, ALERT_EMAIL_FROM and ALERT_EMAIL_TO")
+			}
+			notifiers = append(notifiers, NewEmailNotifier(cfg.SMTPAddr, cfg.EmailFrom, cfg.EmailTo))
+		default:
+			return nil, fmt.Errorf("unknown alert notifier %q", name)
+		}
+	}
+	return notifiers, nil
+}
+
+// describe - One line per item, shared by the text based notifiers
+func describe(item models.LowStockItem) string {
+	where := ""
+	if item.StoreName != nil {
+		where = " in " + *item.StoreName
+	}
+	return fmt.Sprintf("%s (#%d, %s): stock%s %s %s, reorder level %s, suggested order %s %s",
+		item.Name, item.ProductID, item.CategoryName, where, item.Stock, item.Unit,
+		item.ReorderLevel, item.SuggestedQuantity, item.Unit)
+}
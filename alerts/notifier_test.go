@@ -0,0 +1,53 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    int // notifiers
+		wantErr bool
+	}{
+		{"none", Config{}, 0, false},
+		{"log", Config{Names: []string{"log"}}, 1, false},
+		{"case and spaces", Config{Names: []string{" LOG ", "Webhook"}, WebhookURL: "http://hooks.example"}, 2, false},
+		{"webhook without URL", Config{Names: []string{"webhook"}}, 0, true},
+		{"email", Config{Names: []string{"email"}, SMTPAddr: "localhost:1025",
+			EmailFrom: "cashier@example.com", EmailTo: []string{"owner@example.com"}}, 1, false},
+		{"email without recipients", Config{Names: []string{"email"}, SMTPAddr: "localhost:1025",
+			EmailFrom: "cashier@example.com"}, 0, true},
+		{"unknown", Config{Names: []string{"log", "sms"}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && len(n.(Multi)) != tt.want {
+				t.Errorf("%d notifiers, want %d", len(n.(Multi)), tt.want)
+			}
+		})
+	}
+}
+
+// TestMultiKeepsGoing - One failing notifier does not stop the others, and
+// its error is returned
+func TestMultiKeepsGoing(t *testing.T) {
+	failure := errors.New("connection refused")
+	failing, other := &recorder{err: failure}, &recorder{}
+
+	err := Multi{failing, other}.Notify(context.Background(), lowStockItems())
+	if !errors.Is(err, failure) {
+		t.Errorf("error = %v, want %v", err, failure)
+	}
+	if len(other.calls) != 1 {
+		t.Error("notifier after the failing one not called")
+	}
+}
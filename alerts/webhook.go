@@ -0,0 +1,54 @@
+package alerts
+
+import (
+	"bytes"
+	"cashier-api/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier - POST alerts as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// webhookPayload - Body sent to the webhook URL
+type webhookPayload struct {
+	Event string                `json:"event"`
+	Items []models.LowStockItem `json:"items"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, items []models.LowStockItem) error {
+	body, err := json.Marshal(webhookPayload{Event: "inventory.low_stock", Items: items})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("low-stock webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("low-stock webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
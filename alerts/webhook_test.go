@@ -0,0 +1,109 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cashier-api/models"
+)
+
+func lowStockItems() []models.LowStockItem {
+	store, storeName := 2, "Cabang Bandung"
+	return []models.LowStockItem{
+		{ProductID: 1, Name: "Indomie Godog", CategoryName: "Food", Unit: "pcs", Stock: models.NewQuantity(3),
+			ReorderLevel: models.NewQuantity(5), SuggestedQuantity: models.NewQuantity(24)},
+		{ProductID: 2, Name: "Beras", CategoryName: "Food", Unit: "kg", Stock: models.NewQuantity(1),
+			ReorderLevel: models.NewQuantity(10), SuggestedQuantity: models.NewQuantity(25),
+			StoreID: &store, StoreName: &storeName},
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got struct {
+		method, contentType string
+		body                []byte
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method, got.contentType = r.Method, r.Header.Get("Content-Type")
+		got.body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookNotifier(server.URL).Notify(context.Background(), lowStockItems()); err != nil {
+		t.Fatal(err)
+	}
+	if got.method != http.MethodPost || got.contentType != "application/json" {
+		t.Errorf("%s with Content-Type %q, want a JSON POST", got.method, got.contentType)
+	}
+
+	var payload struct {
+		Event string `json:"event"`
+		Items []struct {
+			ProductID         int             `json:"product_id"`
+			Stock             json.RawMessage `json:"stock"`
+			SuggestedQuantity json.RawMessage `json:"suggested_quantity"`
+			StoreName         *string         `json:"store_name"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", got.body, err)
+	}
+	if payload.Event != "inventory.low_stock" || len(payload.Items) != 2 {
+		t.Fatalf("payload %s", got.body)
+	}
+	first, second := payload.Items[0], payload.Items[1]
+	if first.ProductID != 1 || string(first.Stock) != "3" || string(first.SuggestedQuantity) != "24" || first.StoreName != nil {
+		t.Errorf("central item %+v", first)
+	}
+	if second.StoreName == nil || *second.StoreName != "Cabang Bandung" {
+		t.Errorf("store item without its store: %+v", second)
+	}
+}
+
+func TestWebhookNotifierFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string // "" = delivered
+	}{
+		{"created", http.StatusCreated, ""},
+		{"no content", http.StatusNoContent, ""},
+		{"not modified", http.StatusNotModified, "unexpected status 304"},
+		{"client error", http.StatusBadRequest, "unexpected status 400"},
+		{"server error", http.StatusBadGateway, "unexpected status 502"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewWebhookNotifier(server.URL).Notify(context.Background(), lowStockItems())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		err := NewWebhookNotifier(server.URL).Notify(context.Background(), lowStockItems())
+		if err == nil || !strings.HasPrefix(err.Error(), "low-stock webhook:") {
+			t.Fatalf("error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"cashier-api/cache"
+	"cashier-api/config"
+	"cashier-api/database"
+	"cashier-api/events"
+	"cashier-api/metrics"
+	"cashier-api/models"
+	"cashier-api/repositories"
+	"cashier-api/services"
+	"cashier-api/storage"
+)
+
+// app - Connections, repositories and services shared by the server and the
+// data commands (seed, user, export, import)
+type app struct {
+	cfg     *config.Config
+	db      *sql.DB
+	metrics *metrics.Metrics
+	store   storage.Storage
+	broker  *events.Broker
+
+	categoryRepo *repositories.CategoryRepository
+	storeRepo    *repositories.StoreRepository
+	productRepo  *repositories.ProductRepository
+	webhookRepo  *repositories.WebhookRepository
+
+	categoryService  *services.CategoryService
+	storeService     *services.StoreService
+	imageService     *services.ProductImageService
+	productService   *services.ProductService
+	priceService     *services.PriceService
+	variantService   *services.VariantService
+	inventoryService *services.InventoryService
+	stocktakeService *services.StocktakeService
+	transferService  *services.TransferService
+	auditService     *services.AuditService
+	webhookService   *services.WebhookService
+	apiKeyService    *services.APIKeyService
+	eventService     *services.EventService
+	seedService      *services.SeedService
+}
+
+// openDB - Database connection with the configured pool
+func openDB(cfg *config.Config) (*sql.DB, error) {
+	db, err := database.InitDB(cfg.DBConn, database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return db, nil
+}
+
+// newApp - Open the database, cache and file storage and wire the
+// repositories and services. The caller closes a.db.
+func newApp(cfg *config.Config) (*app, error) {
+	// Decimal places of stock quantities (e.g. 3 = grams for kg products)
+	if err := models.SetQuantityPrecision(cfg.QuantityPrecision); err != nil {
+		return nil, fmt.Errorf("invalid QUANTITY_PRECISION: %w", err)
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a := &app{cfg: cfg, db: db}
+
+	// Metrics (HTTP, connection pool, cache and business counters)
+	a.metrics = metrics.New()
+
+	// Cache of the product and category lists (CACHE_ENABLED=false disables it)
+	var catalogCache *cache.Cache
+	if cfg.CacheEnabled {
+		catalogCache, err = cache.New(cache.Config{
+			Driver:        cfg.CacheDriver,
+			TTL:           cfg.CacheTTL,
+			MaxEntries:    cfg.CacheMaxEntries,
+			RedisAddr:     cfg.CacheRedisAddr,
+			RedisPassword: cfg.CacheRedisPassword,
+			RedisDB:       cfg.CacheRedisDB,
+			Observe:       a.metrics.ObserveCache,
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize cache: %w", err)
+		}
+	}
+
+	// File storage for product images (local directory or S3-compatible)
+	a.store, err = storage.New(storage.Config{
+		Driver:      cfg.StorageDriver,
+		LocalDir:    cfg.StorageLocalDir,
+		PublicURL:   cfg.StoragePublicURL,
+		S3Endpoint:  cfg.StorageS3Endpoint,
+		S3Region:    cfg.StorageS3Region,
+		S3Bucket:    cfg.StorageS3Bucket,
+		S3AccessKey: cfg.StorageS3AccessKey,
+		S3SecretKey: cfg.StorageS3SecretKey,
+		S3UseSSL:    cfg.StorageS3UseSSL,
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	// Live product and stock events (GET /api/events)
+	a.broker = events.NewBroker(cfg.EventBufferSize)
+
+	// Category layer first (products need categories)
+	a.categoryRepo = repositories.NewCategoryRepository(db, catalogCache)
+	a.categoryService = services.NewCategoryService(a.categoryRepo)
+
+	// Store layer (branches with their own stock and prices)
+	a.storeRepo = repositories.NewStoreRepository(db)
+	a.storeService = services.NewStoreService(a.storeRepo)
+
+	// Product layer (depends on category repo for validation)
+	a.productRepo = repositories.NewProductRepository(db, catalogCache)
+	productRepo := services.ProductRepo(a.productRepo)
+	variantRepo := repositories.NewVariantRepository(db)
+	imageRepo := repositories.NewProductImageRepository(db)
+	a.imageService = services.NewProductImageService(imageRepo, productRepo, a.store, cfg.ImageMaxBytes)
+	a.productService = services.NewProductService(productRepo, a.categoryRepo, variantRepo, a.imageService, a.broker)
+
+	// Price history and scheduled prices
+	priceRepo := repositories.NewPriceRepository(db)
+	a.priceService = services.NewPriceService(priceRepo, productRepo, a.broker)
+
+	// Variant layer (size/color/flavor of a product)
+	a.variantService = services.NewVariantService(variantRepo, productRepo)
+
+	// Inventory reports (reorder levels)
+	a.inventoryService = services.NewInventoryService(productRepo, cfg.LowStockThreshold)
+
+	// Stock take layer (physical inventory counts)
+	stocktakeRepo := repositories.NewStocktakeRepository(db)
+	a.stocktakeService = services.NewStocktakeService(stocktakeRepo, productRepo, a.categoryRepo, a.storeRepo, a.broker)
+
+	// Stock transfers between stores
+	transferRepo := repositories.NewTransferRepository(db)
+	a.transferService = services.NewTransferService(transferRepo, a.storeRepo, productRepo, a.broker)
+
+	// Audit log of catalog changes
+	auditRepo := repositories.NewAuditRepository(db)
+	a.auditService = services.NewAuditService(auditRepo)
+
+	// Webhook subscriptions and deliveries
+	a.webhookRepo = repositories.NewWebhookRepository(db)
+	a.webhookService = services.NewWebhookService(a.webhookRepo, cfg.WebhookAllowPrivate)
+
+	// API keys of machine clients
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	a.apiKeyService = services.NewAPIKeyService(apiKeyRepo, a.storeRepo)
+
+	a.eventService = services.NewEventService(a.broker, a.categoryRepo, a.storeRepo)
+
+	// Fixture loading of the seed command
+	a.seedService = services.NewSeedService(a.categoryService, a.storeService, a.productService, a.variantService)
+
+	return a, nil
+}
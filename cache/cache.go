@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Store - Backend of the cache. Entries are grouped into namespaces with a
+// generation; invalidating a namespace moves it to the next generation, so
+// older entries are never read again and expire on their own.
+type Store interface {
+	// Generation - Current generation of namespace
+	Generation(ctx context.Context, namespace string) (uint64, error)
+	// Get - Value stored for key in generation gen of namespace
+	Get(ctx context.Context, namespace string, gen uint64, key string) ([]byte, bool, error)
+	// Set - Store value for key in generation gen of namespace for ttl
+	Set(ctx context.Context, namespace string, gen uint64, key string, value []byte, ttl time.Duration) error
+	// Invalidate - Move namespace to the next generation
+	Invalidate(ctx context.Context, namespace string) error
+}
+
+// Results passed to Config.Observe
+const (
+	ResultHit   = "hit"
+	ResultMiss  = "miss"
+	ResultError = "error" // the store failed, the value was loaded without it
+)
+
+// Config - Settings for the store selected by Driver (memory or redis)
+type Config struct {
+	Driver string
+	TTL    time.Duration // how long an entry is served at most
+
+	// In-process LRU
+	MaxEntries int
+
+	// Redis or any server speaking its protocol (Valkey, KeyDB, Dragonfly, ...)
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
+	// Observe - Called with the namespace and result of every lookup
+	Observe func(namespace, result string)
+}
+
+// Cache - Read-through cache of encoded query results. A nil *Cache is a
+// disabled cache: every lookup loads.
+type Cache struct {
+	store   Store
+	ttl     time.Duration
+	observe func(namespace, result string)
+}
+
+// New - Create the cache on the store selected by cfg.Driver
+func New(cfg Config) (*Cache, error) {
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("cache TTL must be positive")
+	}
+
+	var store Store
+	switch strings.ToLower(cfg.Driver) {
+	case "", "memory":
+		store = NewMemory(cfg.MaxEntries)
+	case "redis":
+		redis, err := NewRedis(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+		if err != nil {
+			return nil, err
+		}
+		store = redis
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+	}
+
+	observe := cfg.Observe
+	if observe == nil {
+		observe = func(string, string) {}
+	}
+	return &Cache{store: store, ttl: cfg.TTL, observe: observe}, nil
+}
+
+// Invalidate - Drop every entry of namespace. Failures are logged; the
+// entries then live until their TTL runs out.
+func (c *Cache) Invalidate(namespace string) {
+	if c == nil {
+		return
+	}
+	if err := c.store.Invalidate(context.Background(), namespace); err != nil {
+		log.Printf("cache: failed to invalidate %s: %v", namespace, err)
+	}
+}
+
+// Fetch - Cached value of key in namespace, or the result of load, which is
+// cached for the next call. The generation is read before loading, so a
+// result loaded while the namespace is invalidated is never served.
+func Fetch[T any](c *Cache, namespace, key string, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+	ctx := context.Background()
+
+	gen, err := c.store.Generation(ctx, namespace)
+	if err != nil {
+		log.Printf("cache: failed to read %s generation: %v", namespace, err)
+		c.observe(namespace, ResultError)
+		return load()
+	}
+
+	data, ok, err := c.store.Get(ctx, namespace, gen, key)
+	if err != nil {
+		log.Printf("cache: failed to get %s %s: %v", namespace, key, err)
+	}
+	if ok {
+		var value T
+		if err = json.Unmarshal(data, &value); err == nil {
+			c.observe(namespace, ResultHit)
+			return value, nil
+		}
+		log.Printf("cache: failed to decode %s %s: %v", namespace, key, err)
+	}
+	if err != nil {
+		c.observe(namespace, ResultError)
+	} else {
+		c.observe(namespace, ResultMiss)
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err != nil {
+		log.Printf("cache: failed to encode %s %s: %v", namespace, key, err)
+	} else if err := c.store.Set(ctx, namespace, gen, key, data, c.ttl); err != nil {
+		log.Printf("cache: failed to set %s %s: %v", namespace, key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// observed - Lookup results passed to Config.Observe, by namespace
+type observed map[string][]string
+
+func (o observed) observe(namespace, result string) {
+	o[namespace] = append(o[namespace], result)
+}
+
+// testCaches - A Cache on each backend, with the results it observed
+func testCaches(t *testing.T) map[string]func() (*Cache, observed) {
+	return map[string]func() (*Cache, observed){
+		"memory": func() (*Cache, observed) {
+			o := observed{}
+			return &Cache{store: NewMemory(10), ttl: time.Minute, observe: o.observe}, o
+		},
+		"redis": func() (*Cache, observed) {
+			r, _ := newTestRedis(t)
+			o := observed{}
+			return &Cache{store: r, ttl: time.Minute, observe: o.observe}, o
+		},
+	}
+}
+
+// counter - load function counting its calls
+func counter(calls *int) func() ([]int, error) {
+	return func() ([]int, error) {
+		*calls++
+		return []int{*calls}, nil
+	}
+}
+
+func TestFetch(t *testing.T) {
+	for name, newCache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			c, results := newCache()
+			calls := 0
+
+			for i := 0; i < 2; i++ {
+				value, err := Fetch(c, "products", "list", counter(&calls))
+				if err != nil || len(value) != 1 || value[0] != 1 {
+					t.Fatalf("Fetch %d = %v, %v, want [1]", i+1, value, err)
+				}
+			}
+			if calls != 1 {
+				t.Errorf("loaded %d times, want once", calls)
+			}
+
+			// Invalidation loads again; other namespaces keep their entries
+			Fetch(c, "categories", "list", counter(new(int)))
+			c.Invalidate("products")
+			if value, _ := Fetch(c, "products", "list", counter(&calls)); value[0] != 2 {
+				t.Errorf("Fetch after Invalidate = %v, want a new load", value)
+			}
+			Fetch(c, "categories", "list", counter(new(int)))
+
+			want := observed{
+				"products":   {ResultMiss, ResultHit, ResultMiss},
+				"categories": {ResultMiss, ResultHit},
+			}
+			for namespace, w := range want {
+				if got := results[namespace]; !slices.Equal(got, w) {
+					t.Errorf("%s results = %v, want %v", namespace, got, w)
+				}
+			}
+		})
+	}
+}
+
+// TestFetchRacingInvalidate - A value loaded while the namespace is
+// invalidated is returned once but never served from the cache
+func TestFetchRacingInvalidate(t *testing.T) {
+	for name, newCache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			c, _ := newCache()
+			calls := 0
+
+			stale, err := Fetch(c, "products", "list", func() ([]int, error) {
+				calls++
+				c.Invalidate("products") // a write commits during the load
+				return []int{calls}, nil
+			})
+			if err != nil || stale[0] != 1 {
+				t.Fatalf("Fetch = %v, %v", stale, err)
+			}
+
+			if value, _ := Fetch(c, "products", "list", counter(&calls)); value[0] != 2 {
+				t.Errorf("Fetch served %v loaded before the invalidation", value)
+			}
+		})
+	}
+}
+
+func TestFetchLoadError(t *testing.T) {
+	c, _ := testCaches(t)["memory"]()
+	failure := errors.New("connection refused")
+
+	if _, err := Fetch(c, "products", "list", func() ([]int, error) { return nil, failure }); err != failure {
+		t.Fatalf("error = %v, want %v", err, failure)
+	}
+	calls := 0
+	Fetch(c, "products", "list", counter(&calls))
+	if calls != 1 {
+		t.Error("failed load was cached")
+	}
+}
+
+// failingStore - Store whose every call fails
+type failingStore struct{}
+
+func (failingStore) Generation(context.Context, string) (uint64, error) {
+	return 0, errors.New("connection refused")
+}
+func (failingStore) Get(context.Context, string, uint64, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+func (failingStore) Set(context.Context, string, uint64, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+func (failingStore) Invalidate(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+func TestFetchStoreDown(t *testing.T) {
+	results := observed{}
+	c := &Cache{store: failingStore{}, ttl: time.Minute, observe: results.observe}
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if value, err := Fetch(c, "products", "list", counter(&calls)); err != nil || value[0] != i+1 {
+			t.Fatalf("Fetch = %v, %v, want the loaded value", value, err)
+		}
+	}
+	c.Invalidate("products") // only logged
+
+	if got := results["products"]; !slices.Equal(got, []string{ResultError, ResultError}) {
+		t.Errorf("results = %v, want two errors", got)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	calls := 0
+	Fetch(c, "products", "list", counter(&calls))
+	Fetch(c, "products", "list", counter(&calls))
+	c.Invalidate("products")
+	if calls != 2 {
+		t.Errorf("loaded %d times, want every time", calls)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"memory", Config{Driver: "memory", TTL: time.Minute}, false},
+		{"default driver", Config{TTL: time.Minute}, false},
+		{"no TTL", Config{Driver: "memory"}, true},
+		{"unknown driver", Config{Driver: "memcached", TTL: time.Minute}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && c.observe == nil {
+				t.Error("no default Observe")
+			}
+		})
+	}
+}
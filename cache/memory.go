@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries - Size of the in-process LRU when none is configured
+const DefaultMaxEntries = 1000
+
+// Memory - In-process LRU with per-entry expiry. Each server instance has
+// its own, so writes made through another instance are only seen once the
+// TTL runs out.
+type Memory struct {
+	mu          sync.Mutex
+	maxEntries  int
+	entries     map[string]*list.Element
+	order       *list.List // most recently used first
+	generations map[string]uint64
+	now         func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Memory{
+		maxEntries:  maxEntries,
+		entries:     map[string]*list.Element{},
+		order:       list.New(),
+		generations: map[string]uint64{},
+		now:         time.Now,
+	}
+}
+
+func (m *Memory) Generation(ctx context.Context, namespace string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.generations[namespace], nil
+}
+
+func (m *Memory) Get(ctx context.Context, namespace string, gen uint64, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[memoryKey(namespace, gen, key)]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if m.now().After(entry.expiresAt) {
+		m.remove(element)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, namespace string, gen uint64, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if gen != m.generations[namespace] {
+		// Loaded before an invalidation: nobody reads this generation again
+		return nil
+	}
+
+	entry := &memoryEntry{key: memoryKey(namespace, gen, key), value: value, expiresAt: m.now().Add(ttl)}
+	if element, ok := m.entries[entry.key]; ok {
+		element.Value = entry
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[entry.key] = m.order.PushFront(entry)
+	for m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+// Invalidate - Entries of older generations stay until they are evicted
+func (m *Memory) Invalidate(ctx context.Context, namespace string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generations[namespace]++
+	return nil
+}
+
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).key)
+}
+
+func memoryKey(namespace string, gen uint64, key string) string {
+	return namespace + ":" + strconv.FormatUint(gen, 10) + ":" + key
+}
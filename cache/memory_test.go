@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(3)
+	for i := 1; i <= 3; i++ {
+		m.Set(ctx, "products", 0, strconv.Itoa(i), []byte("v"), time.Minute)
+	}
+
+	// 1 becomes the most recently used, so 2 goes first
+	if _, ok, _ := m.Get(ctx, "products", 0, "1"); !ok {
+		t.Fatal("entry 1 missing below capacity")
+	}
+	m.Set(ctx, "products", 0, "4", []byte("v"), time.Minute)
+	// Overwriting an entry does not evict another one
+	m.Set(ctx, "products", 0, "4", []byte("w"), time.Minute)
+
+	for key, want := range map[string]bool{"1": true, "2": false, "3": true, "4": true} {
+		if _, ok, _ := m.Get(ctx, "products", 0, key); ok != want {
+			t.Errorf("entry %s cached = %v, want %v", key, ok, want)
+		}
+	}
+	if len(m.entries) != 3 || m.order.Len() != 3 {
+		t.Errorf("%d entries, %d in LRU order, want 3", len(m.entries), m.order.Len())
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMemory(0)
+	m.now = func() time.Time { return now }
+
+	m.Set(ctx, "products", 0, "list", []byte("v"), time.Minute)
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := m.Get(ctx, "products", 0, "list"); !ok {
+		t.Error("entry expired at its TTL, want it served until then")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := m.Get(ctx, "products", 0, "list"); ok {
+		t.Error("entry served after its TTL")
+	}
+	if len(m.entries) != 0 {
+		t.Error("expired entry not removed")
+	}
+}
+
+func TestMemoryInvalidate(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(0)
+	m.Set(ctx, "products", 0, "list", []byte("v"), time.Minute)
+	m.Set(ctx, "categories", 0, "list", []byte("v"), time.Minute)
+
+	m.Invalidate(ctx, "products")
+
+	if gen, _ := m.Generation(ctx, "products"); gen != 1 {
+		t.Errorf("generation %d, want 1", gen)
+	}
+	if _, ok, _ := m.Get(ctx, "products", 1, "list"); ok {
+		t.Error("entry of the old generation served")
+	}
+	if _, ok, _ := m.Get(ctx, "categories", 0, "list"); !ok {
+		t.Error("other namespace invalidated")
+	}
+
+	// A value loaded before the invalidation is not stored
+	m.Set(ctx, "products", 0, "detail", []byte("stale"), time.Minute)
+	if _, ok := m.entries[memoryKey("products", 0, "detail")]; ok {
+		t.Error("Set of an old generation stored")
+	}
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix - Prefix of every key written to Redis
+const redisPrefix = "cashier:cache:"
+
+// Redis - Cache shared by every server instance, on Redis or a server
+// speaking its protocol (Valkey, KeyDB, Dragonfly, ...)
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis - Connect to the server at addr and check that it answers
+func NewRedis(addr, password string, db int) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Redis{client: client}, nil
+}
+
+func (r *Redis) Generation(ctx context.Context, namespace string) (uint64, error) {
+	gen, err := r.client.Get(ctx, redisGenerationKey(namespace)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+func (r *Redis) Get(ctx context.Context, namespace string, gen uint64, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, redisKey(namespace, gen, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, namespace string, gen uint64, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, redisKey(namespace, gen, key), value, ttl).Err()
+}
+
+// Invalidate - Entries of older generations expire with their TTL
+func (r *Redis) Invalidate(ctx context.Context, namespace string) error {
+	return r.client.Incr(ctx, redisGenerationKey(namespace)).Err()
+}
+
+func redisGenerationKey(namespace string) string {
+	return redisPrefix + namespace + ":generation"
+}
+
+func redisKey(namespace string, gen uint64, key string) string {
+	return redisPrefix + namespace + ":" + strconv.FormatUint(gen, 10) + ":" + key
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	r, err := NewRedis(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.client.Close() })
+	return r, server
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	r, server := newTestRedis(t)
+
+	if gen, err := r.Generation(ctx, "products"); err != nil || gen != 0 {
+		t.Fatalf("generation of a new namespace = %d, %v, want 0", gen, err)
+	}
+	if _, ok, err := r.Get(ctx, "products", 0, "list"); ok || err != nil {
+		t.Fatalf("Get of a missing key = %v, %v, want a miss", ok, err)
+	}
+
+	if err := r.Set(ctx, "products", 0, "list", []byte(`[1]`), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := r.Get(ctx, "products", 0, "list")
+	if err != nil || !ok || string(value) != `[1]` {
+		t.Fatalf("Get = %q, %v, %v, want [1]", value, ok, err)
+	}
+	if ttl := server.TTL(redisKey("products", 0, "list")); ttl != time.Minute {
+		t.Errorf("TTL %v, want %v", ttl, time.Minute)
+	}
+
+	server.FastForward(time.Minute + time.Second)
+	if _, ok, _ := r.Get(ctx, "products", 0, "list"); ok {
+		t.Error("entry served after its TTL")
+	}
+
+	if err := r.Invalidate(ctx, "products"); err != nil {
+		t.Fatal(err)
+	}
+	if gen, _ := r.Generation(ctx, "products"); gen != 1 {
+		t.Errorf("generation after Invalidate = %d, want 1", gen)
+	}
+	if gen, _ := r.Generation(ctx, "categories"); gen != 0 {
+		t.Errorf("other namespace at generation %d, want 0", gen)
+	}
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	if _, err := NewRedis(addr, "", 0); err == nil {
+		t.Error("NewRedis succeeded without a server")
+	}
+}
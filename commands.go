@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cashier-api/config"
+	"cashier-api/database"
+	"cashier-api/models"
+	"cashier-api/services"
+	"cashier-api/spreadsheet"
+)
+
+// migrateCommand - cashier-api migrate up|down|status [--steps N]
+func migrateCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, "usage: cashier-api migrate up|down|status [--steps N] [flags]")
+		return 2
+	}
+	action := args[0]
+
+	flags := config.Flags("cashier-api migrate " + action)
+	steps := flags.Int("steps", 0, "number of migrations to apply (up, 0 = all) or revert (down, default 1)")
+	cfg, _, err := setup(flags, args[1:], 0)
+	if err != nil {
+		return fail(err)
+	}
+	if *steps < 0 {
+		return fail(errors.New("--steps must not be negative"))
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		applied, err := database.MigrateUp(db, *steps)
+		for _, m := range applied {
+			fmt.Printf("Applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+
+	case "down":
+		if *steps == 0 {
+			*steps = 1
+		}
+		reverted, err := database.MigrateDown(db, *steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+
+	case "status":
+		status, err := database.GetMigrationStatus(db)
+		if err != nil {
+			return fail(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	}
+	return 0
+}
+
+// pendingMigrations - Number of migrations not applied yet
+func pendingMigrations(status []database.MigrationStatus) int {
+	pending := 0
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+// seedCommand - cashier-api seed [--file FILE]: load the sample catalog or a
+// JSON/YAML fixture; existing entries are left alone
+func seedCommand(args []string) int {
+	flags := config.Flags("cashier-api seed")
+	file := flags.String("file", "", "JSON or YAML fixture (default: the sample catalog)")
+	actorName := flags.String("actor", "cli", "name recorded in the audit log")
+	cfg, _, err := setup(flags, args, 0)
+	if err != nil {
+		return fail(err)
+	}
+
+	data := database.SampleFixture
+	if *file != "" {
+		if data, err = os.ReadFile(*file); err != nil {
+			return fail(err)
+		}
+	}
+	fixture, err := services.ParseFixture(data)
+	if err != nil {
+		return fail(err)
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer a.db.Close()
+
+	result, err := a.seedService.Seed(fixture, models.Actor{Name: *actorName})
+	if result != nil {
+		fmt.Printf("Created %d categories, %d stores, %d products and %d variants; %d already existed\n",
+			result.Categories, result.Stores, result.Products, result.Variants, result.Skipped)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// userCommand - cashier-api user create --name NAME [--scopes LIST]: an API
+// key for bootstrapping, admin unless other scopes are given
+func userCommand(args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, "usage: cashier-api user create --name NAME [--scopes admin] [flags]")
+		return 2
+	}
+
+	flags := config.Flags("cashier-api user create")
+	name := flags.String("name", "", "name of the key, e.g. the person or client using it")
+	scopes := flags.String("scopes", models.ScopeAdmin, "comma separated scopes: "+strings.Join(models.APIKeyScopes, ", "))
+	cfg, _, err := setup(flags, args[1:], 0)
+	if err != nil {
+		return fail(err)
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer a.db.Close()
+
+	key, err := a.apiKeyService.Create(models.APIKeyInput{Name: *name, Scopes: splitList(*scopes)})
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Created API key %d (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ", "))
+	fmt.Println("Key (shown only once):", key.Key)
+	return 0
+}
+
+// exportCommand - cashier-api export [--format csv|xlsx] [--output FILE]:
+// the product catalog in the import layout
+func exportCommand(args []string) int {
+	flags := config.Flags("cashier-api export")
+	format := flags.String("format", "", "csv or xlsx (default: from --output, else csv)")
+	output := flags.String("output", "", "file to write (default: standard output)")
+	categoryID := flags.Int("category-id", 0, "only this category and its subcategories")
+	includeDeleted := flags.Bool("include-deleted", false, "include archived products")
+	storeID := flags.Int("store-id", 0, "stock and prices of this store")
+	cfg, _, err := setup(flags, args, 0)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *format == "" {
+		*format = spreadsheet.FormatFromFilename(*output)
+	}
+	if *format == "" {
+		*format = spreadsheet.FormatCSV
+	}
+	if *format != spreadsheet.FormatCSV && *format != spreadsheet.FormatXLSX {
+		return fail(spreadsheet.ErrUnsupportedFormat)
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer a.db.Close()
+
+	products, err := a.productServiceFor(*storeID)
+	if err != nil {
+		return fail(err)
+	}
+	rows, err := products.Export(models.ProductFilter{IncludeDeleted: *includeDeleted, CategoryID: *categoryID})
+	if err != nil {
+		return fail(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := spreadsheet.Write(w, *format, rows); err != nil {
+		return fail(err)
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d products to %s\n", len(rows)-1, *output)
+	}
+	return 0
+}
+
+// importCommand - cashier-api import FILE [--dry-run]: create products from
+// a CSV or XLSX file ("-" reads standard input); nothing is created when a
+// row is invalid
+func importCommand(args []string) int {
+	flags := config.Flags("cashier-api import")
+	format := flags.String("format", "", "csv or xlsx (default: from the file name)")
+	dryRun := flags.Bool("dry-run", false, "only validate the file")
+	storeID := flags.Int("store-id", 0, "stock and prices of this store")
+	actorName := flags.String("actor", "cli", "name recorded in the audit log")
+	cfg, files, err := setup(flags, args, 1)
+	if err != nil {
+		return fail(err)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: cashier-api import FILE [--format csv|xlsx] [--dry-run] [flags]")
+		return 2
+	}
+
+	file := files[0]
+	if *format == "" {
+		*format = spreadsheet.FormatFromFilename(file)
+	}
+	if *format == "" {
+		return fail(fmt.Errorf("cannot detect the format of %s, use --format", file))
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	rows, err := spreadsheet.Read(r, *format)
+	if err != nil {
+		return fail(fmt.Errorf("invalid %s file: %w", *format, err))
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer a.db.Close()
+
+	products, err := a.productServiceFor(*storeID)
+	if err != nil {
+		return fail(err)
+	}
+	result, err := products.Import(rows, *dryRun, models.Actor{Name: *actorName})
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Fprintf(os.Stderr, "Row %d: %s\n", rowErr.Row, strings.Join(rowErr.Errors, "; "))
+	}
+	if len(result.Errors) > 0 {
+		return fail(fmt.Errorf("%d of %d rows are invalid, nothing was imported",
+			result.TotalRows-result.ValidRows, result.TotalRows))
+	}
+	if result.DryRun {
+		fmt.Printf("All %d rows are valid (dry run, nothing was imported)\n", result.TotalRows)
+	} else {
+		fmt.Printf("Imported %d products\n", result.Created)
+	}
+	return 0
+}
+
+// productServiceFor - Product service of a store (0 = the central stock),
+// failing when the store does not exist
+func (a *app) productServiceFor(storeID int) (*services.ProductService, error) {
+	if storeID != 0 {
+		if _, err := a.storeService.GetByID(storeID); err != nil {
+			return nil, fmt.Errorf("store %d: %w", storeID, err)
+		}
+	}
+	return a.productService.ForStore(storeID), nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCommandArguments - migrate and seed reject bad arguments before they
+// connect to the database
+func TestCommandArguments(t *testing.T) {
+	// Never reached: the arguments fail first
+	t.Setenv("DB_CONN", "postgres://cashier@127.0.0.1:1/cashier?sslmode=disable&connect_timeout=1")
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("categories:\n  - name: Food\n    colour: red\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		command  func(args []string) int
+		args     []string
+		wantCode int
+	}{
+		{"migrate without action", migrateCommand, nil, 2},
+		{"migrate unknown action", migrateCommand, []string{"sideways"}, 2},
+		{"migrate negative steps", migrateCommand, []string{"down", "--steps", "-1"}, 1},
+		{"migrate unknown flag", migrateCommand, []string{"up", "--force"}, 1},
+		{"seed missing file", seedCommand, []string{"--file", filepath.Join(dir, "missing.yaml")}, 1},
+		{"seed invalid fixture", seedCommand, []string{"--file", invalid}, 1},
+		{"seed extra argument", seedCommand, []string{"catalog.yaml"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := tt.command(tt.args); code != tt.wantCode {
+				t.Errorf("exit code %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
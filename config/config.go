@@ -0,0 +1,382 @@
+package config
+
+import (
+	"cashier-api/cache"
+	"cashier-api/models"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Config - Settings of the server. Every field is read from the key of its
+// mapstructure tag, in this order of precedence (highest first):
+//
+//  1. command-line flags (--db-conn, --cache-ttl, ...)
+//  2. environment variables (DB_CONN, CACHE_TTL, ...)
+//  3. the .env file in the working directory
+//  4. a YAML or TOML file given with --config or CONFIG_FILE
+//  5. the defaults below
+//
+// Fields tagged secret are redacted by Print.
+type Config struct {
+	// Server
+	Env                     string        `mapstructure:"ENV"` // development, staging or production
+	Port                    string        `mapstructure:"PORT"`
+	ServerReadHeaderTimeout time.Duration `mapstructure:"SERVER_READ_HEADER_TIMEOUT"`
+	ServerReadTimeout       time.Duration `mapstructure:"SERVER_READ_TIMEOUT"`  // whole request, body included (imports)
+	ServerWriteTimeout      time.Duration `mapstructure:"SERVER_WRITE_TIMEOUT"` // lifted for the event stream
+	ServerIdleTimeout       time.Duration `mapstructure:"SERVER_IDLE_TIMEOUT"`  // keep-alive connections
+
+	// Logging
+	LogLevel  string `mapstructure:"LOG_LEVEL"`  // debug, info, warn or error
+	LogFormat string `mapstructure:"LOG_FORMAT"` // text or json
+
+	// Database and its connection pool
+	DBConn            string        `mapstructure:"DB_CONN" secret:"true"`
+	DBMaxOpenConns    int           `mapstructure:"DB_MAX_OPEN_CONNS"` // 0 = unlimited
+	DBMaxIdleConns    int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"` // 0 = forever
+
+	// Feature toggles
+	DocsEnabled    bool `mapstructure:"DOCS_ENABLED"`    // /openapi.json and /docs
+	MetricsEnabled bool `mapstructure:"METRICS_ENABLED"` // /metrics
+
+	LowStockThreshold int `mapstructure:"LOW_STOCK_THRESHOLD"`
+	QuantityPrecision int `mapstructure:"QUANTITY_PRECISION"`
+
+	// Low-stock alerts
+	LowStockCheckInterval time.Duration `mapstructure:"LOW_STOCK_CHECK_INTERVAL"`
+	AlertNotifiers        string        `mapstructure:"ALERT_NOTIFIERS"`
+	AlertWebhookURL       string        `mapstructure:"ALERT_WEBHOOK_URL" secret:"true"`
+	AlertSMTPAddr         string        `mapstructure:"ALERT_SMTP_ADDR"`
+	AlertEmailFrom        string        `mapstructure:"ALERT_EMAIL_FROM"`
+	AlertEmailTo          string        `mapstructure:"ALERT_EMAIL_TO"`
+
+	// Product images
+	ImageMaxBytes      int64  `mapstructure:"IMAGE_MAX_BYTES"`
+	StorageDriver      string `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalDir    string `mapstructure:"STORAGE_LOCAL_DIR"`
+	StoragePublicURL   string `mapstructure:"STORAGE_PUBLIC_URL"`
+	StorageS3Endpoint  string `mapstructure:"STORAGE_S3_ENDPOINT"`
+	StorageS3Region    string `mapstructure:"STORAGE_S3_REGION"`
+	StorageS3Bucket    string `mapstructure:"STORAGE_S3_BUCKET"`
+	StorageS3AccessKey string `mapstructure:"STORAGE_S3_ACCESS_KEY"`
+	StorageS3SecretKey string `mapstructure:"STORAGE_S3_SECRET_KEY" secret:"true"`
+	StorageS3UseSSL    bool   `mapstructure:"STORAGE_S3_USE_SSL"`
+
+	// Scheduled prices
+	PriceScheduleInterval time.Duration `mapstructure:"PRICE_SCHEDULE_INTERVAL"`
+
+	// Audit log: take the client IP from X-Forwarded-For (behind a proxy
+	// only), skipping the addresses added by the proxies after the first
+	TrustProxy     bool `mapstructure:"TRUST_PROXY"`
+	TrustProxyHops int  `mapstructure:"TRUST_PROXY_HOPS"` // proxies in front of the server
+
+	// Webhook deliveries
+	WebhookDispatchInterval time.Duration `mapstructure:"WEBHOOK_DISPATCH_INTERVAL"`
+	WebhookTimeout          time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts      int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryBase        time.Duration `mapstructure:"WEBHOOK_RETRY_BASE"`
+	WebhookRetryMax         time.Duration `mapstructure:"WEBHOOK_RETRY_MAX"`
+	WebhookAllowPrivate     bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE"` // local development only
+
+	// Event stream: events kept for Last-Event-ID resume
+	EventBufferSize int `mapstructure:"EVENT_BUFFER_SIZE"`
+
+	// Catalog read cache
+	CacheEnabled       bool          `mapstructure:"CACHE_ENABLED"`
+	CacheDriver        string        `mapstructure:"CACHE_DRIVER"`
+	CacheTTL           time.Duration `mapstructure:"CACHE_TTL"`
+	CacheMaxEntries    int           `mapstructure:"CACHE_MAX_ENTRIES"`
+	CacheRedisAddr     string        `mapstructure:"CACHE_REDIS_ADDR"`
+	CacheRedisPassword string        `mapstructure:"CACHE_REDIS_PASSWORD" secret:"true"`
+	CacheRedisDB       int           `mapstructure:"CACHE_REDIS_DB"`
+
+	// Request body limits in bytes (image uploads use IMAGE_MAX_BYTES)
+	BodyLimit       int64 `mapstructure:"BODY_LIMIT"`
+	BodyLimitBatch  int64 `mapstructure:"BODY_LIMIT_BATCH"`
+	BodyLimitImport int64 `mapstructure:"BODY_LIMIT_IMPORT"`
+
+	// Rate limits per client in requests per second (0 = unlimited)
+	RateLimitReadRPS    float64 `mapstructure:"RATE_LIMIT_READ_RPS"`
+	RateLimitReadBurst  int     `mapstructure:"RATE_LIMIT_READ_BURST"`
+	RateLimitWriteRPS   float64 `mapstructure:"RATE_LIMIT_WRITE_RPS"`
+	RateLimitWriteBurst int     `mapstructure:"RATE_LIMIT_WRITE_BURST"`
+
+	// API keys: scopes of /api/ requests without one (comma separated, empty
+	// = rejected), bootstrap admin secret
+	AuthAnonymousScopes string `mapstructure:"AUTH_ANONYMOUS_SCOPES"`
+	AdminToken          string `mapstructure:"ADMIN_TOKEN" secret:"true"`
+	// Failed authentications (401) per second per client IP, before keys
+	// are looked up at all (0 = unlimited)
+	AuthFailureRPS   float64 `mapstructure:"AUTH_FAILURE_RPS"`
+	AuthFailureBurst int     `mapstructure:"AUTH_FAILURE_BURST"`
+
+	// CORS for browser frontends on other origins (comma separated lists)
+	CORSAllowedOrigins   string        `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedMethods   string        `mapstructure:"CORS_ALLOWED_METHODS"`
+	CORSAllowedHeaders   string        `mapstructure:"CORS_ALLOWED_HEADERS"`
+	CORSAllowCredentials bool          `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	CORSMaxAge           time.Duration `mapstructure:"CORS_MAX_AGE"`
+}
+
+// defaults - Values of keys not set anywhere else
+var defaults = map[string]any{
+	"ENV":                        "development",
+	"PORT":                       "8080",
+	"SERVER_READ_HEADER_TIMEOUT": "10s",
+	"SERVER_READ_TIMEOUT":        "1m",
+	"SERVER_WRITE_TIMEOUT":       "2m",
+	"SERVER_IDLE_TIMEOUT":        "2m",
+	"LOG_LEVEL":                  "info",
+	"LOG_FORMAT":                 "text",
+	"DB_MAX_OPEN_CONNS":          25,
+	"DB_MAX_IDLE_CONNS":          5,
+	"DB_CONN_MAX_LIFETIME":       "5m",
+	"DOCS_ENABLED":               true,
+	"METRICS_ENABLED":            true,
+	"LOW_STOCK_THRESHOLD":        5,
+	"QUANTITY_PRECISION":         3,
+	"LOW_STOCK_CHECK_INTERVAL":   "1m",
+	"ALERT_NOTIFIERS":            "log",
+	"IMAGE_MAX_BYTES":            5 << 20,
+	"STORAGE_DRIVER":             "local",
+	"STORAGE_LOCAL_DIR":          "uploads",
+	"PRICE_SCHEDULE_INTERVAL":    "1m",
+	"TRUST_PROXY":                false,
+	"TRUST_PROXY_HOPS":           1,
+	"WEBHOOK_DISPATCH_INTERVAL":  "5s",
+	"WEBHOOK_TIMEOUT":            "10s",
+	"WEBHOOK_MAX_ATTEMPTS":       10,
+	"WEBHOOK_RETRY_BASE":         "30s",
+	"WEBHOOK_RETRY_MAX":          "1h",
+	"WEBHOOK_ALLOW_PRIVATE":      false,
+	"EVENT_BUFFER_SIZE":          1000,
+	"CACHE_ENABLED":              true,
+	"CACHE_DRIVER":               "memory",
+	"CACHE_TTL":                  "30s",
+	"CACHE_MAX_ENTRIES":          cache.DefaultMaxEntries,
+	"CACHE_REDIS_ADDR":           "localhost:6379",
+	"BODY_LIMIT":                 1 << 20,
+	"BODY_LIMIT_BATCH":           10 << 20,
+	"BODY_LIMIT_IMPORT":          20 << 20,
+	"RATE_LIMIT_READ_RPS":        20,
+	"RATE_LIMIT_READ_BURST":      40,
+	"RATE_LIMIT_WRITE_RPS":       5,
+	"RATE_LIMIT_WRITE_BURST":     20,
+	"AUTH_ANONYMOUS_SCOPES":      "",
+	"AUTH_FAILURE_RPS":           0.2,
+	"AUTH_FAILURE_BURST":         10,
+	"CORS_ALLOWED_METHODS":       "GET,POST,PUT,PATCH,DELETE",
+	"CORS_ALLOWED_HEADERS":       "Content-Type,Authorization,X-API-Key,X-Store-ID,X-Actor,X-Request-ID,If-Match,If-None-Match,Last-Event-ID",
+	"CORS_ALLOW_CREDENTIALS":     false,
+	"CORS_MAX_AGE":               "10m",
+}
+
+// FileEnv - Environment variable naming the configuration file when
+// --config is not given
+const FileEnv = "CONFIG_FILE"
+
+// Flags - Command-line flags for every key, --db-conn for DB_CONN and so
+// on, plus --config for the configuration file
+func Flags(name string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.String("config", "", "YAML or TOML configuration file (or "+FileEnv+")")
+	for _, field := range fields() {
+		name := flagName(field.key)
+		flags.String(name, "", "overrides "+field.key)
+		if field.kind == reflect.Bool {
+			flags.Lookup(name).NoOptDefVal = "true"
+		}
+	}
+	return flags
+}
+
+// Load - Read the configuration from all sources; flags must already be
+// parsed (or nil). The result is not validated.
+func Load(flags *pflag.FlagSet) (*Config, error) {
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	file := os.Getenv(FileEnv)
+	if flags != nil {
+		if path, _ := flags.GetString("config"); path != "" {
+			file = path
+		}
+	}
+	if file != "" {
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+	}
+
+	if _, err := os.Stat(".env"); err == nil {
+		v.SetConfigFile(".env")
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read .env: %w", err)
+		}
+	}
+
+	v.AutomaticEnv()
+	for _, field := range fields() {
+		// Known keys, so that Unmarshal also looks up unset ones in the environment
+		v.BindEnv(field.key)
+		if flags != nil {
+			if flag := flags.Lookup(flagName(field.key)); flag != nil && flag.Changed {
+				v.Set(field.key, flag.Value.String())
+			}
+		}
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Validate - Check every value; all problems are reported together
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(slices.Contains([]string{"development", "staging", "production"}, c.Env),
+		"ENV must be development, staging or production")
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port <= 65535, "PORT must be a port number")
+	check(c.ServerReadHeaderTimeout >= 0 && c.ServerReadTimeout >= 0 && c.ServerWriteTimeout >= 0 &&
+		c.ServerIdleTimeout >= 0, "SERVER_*_TIMEOUT must not be negative")
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, c.LogLevel),
+		"LOG_LEVEL must be debug, info, warn or error")
+	check(c.LogFormat == "text" || c.LogFormat == "json", "LOG_FORMAT must be text or json")
+
+	check(c.DBConn != "", "DB_CONN is required")
+	check(c.DBMaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.DBMaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.DBMaxOpenConns == 0 || c.DBMaxIdleConns <= c.DBMaxOpenConns,
+		"DB_MAX_IDLE_CONNS must not be larger than DB_MAX_OPEN_CONNS")
+	check(c.DBConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+
+	check(c.LowStockThreshold >= 0, "LOW_STOCK_THRESHOLD must not be negative")
+	check(c.QuantityPrecision >= 0 && c.QuantityPrecision <= models.MaxQuantityPrecision,
+		"QUANTITY_PRECISION must be between 0 and %d", models.MaxQuantityPrecision)
+	check(c.LowStockCheckInterval >= 0, "LOW_STOCK_CHECK_INTERVAL must not be negative")
+
+	check(c.ImageMaxBytes > 0, "IMAGE_MAX_BYTES must be positive")
+	check(c.StorageDriver == "local" || c.StorageDriver == "s3", "STORAGE_DRIVER must be local or s3")
+	check(c.PriceScheduleInterval >= 0, "PRICE_SCHEDULE_INTERVAL must not be negative")
+	check(c.TrustProxyHops > 0, "TRUST_PROXY_HOPS must be positive")
+
+	check(c.WebhookDispatchInterval >= 0, "WEBHOOK_DISPATCH_INTERVAL must not be negative")
+	check(c.WebhookTimeout > 0, "WEBHOOK_TIMEOUT must be positive")
+	check(c.WebhookMaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive")
+	check(c.WebhookRetryBase > 0 && c.WebhookRetryMax >= c.WebhookRetryBase,
+		"WEBHOOK_RETRY_BASE must be positive and not larger than WEBHOOK_RETRY_MAX")
+	check(c.EventBufferSize > 0, "EVENT_BUFFER_SIZE must be positive")
+
+	check(c.CacheDriver == "memory" || c.CacheDriver == "redis", "CACHE_DRIVER must be memory or redis")
+	check(!c.CacheEnabled || c.CacheTTL > 0, "CACHE_TTL must be positive")
+	check(c.CacheMaxEntries >= 0, "CACHE_MAX_ENTRIES must not be negative")
+
+	check(c.BodyLimit > 0, "BODY_LIMIT must be positive")
+	check(c.BodyLimitBatch > 0 && c.BodyLimitImport > 0, "BODY_LIMIT_BATCH and BODY_LIMIT_IMPORT must be positive")
+	check(c.RateLimitReadRPS >= 0 && c.RateLimitWriteRPS >= 0, "RATE_LIMIT_*_RPS must not be negative")
+	check(c.RateLimitReadBurst >= 0 && c.RateLimitWriteBurst >= 0, "RATE_LIMIT_*_BURST must not be negative")
+
+	check(c.AuthFailureRPS >= 0 && c.AuthFailureBurst >= 0, "AUTH_FAILURE_RPS and AUTH_FAILURE_BURST must not be negative")
+	for _, scope := range strings.Split(c.AuthAnonymousScopes, ",") {
+		scope = strings.TrimSpace(scope)
+		check(scope == "" || slices.Contains(models.APIKeyScopes, scope) && scope != models.ScopeAdmin &&
+			!strings.HasPrefix(scope, "webhooks:"),
+			"AUTH_ANONYMOUS_SCOPES: %q is not a scope requests without a key can have", scope)
+	}
+
+	check(c.CORSMaxAge >= 0, "CORS_MAX_AGE must not be negative")
+	// Any origin with credentials would let every site act with the
+	// browser's cookies and keys
+	anyOrigin := slices.ContainsFunc(strings.Split(c.CORSAllowedOrigins, ","), func(origin string) bool {
+		return strings.TrimSpace(origin) == "*"
+	})
+	check(!anyOrigin || !c.CORSAllowCredentials, "CORS_ALLOW_CREDENTIALS cannot be combined with CORS_ALLOWED_ORIGINS=*")
+
+	return errors.Join(errs...)
+}
+
+// Print - Every key with its effective value, in .env format. Secrets are
+// replaced by xxxxx; of DB_CONN only the password is.
+func (c *Config) Print(w io.Writer) {
+	value := reflect.ValueOf(c).Elem()
+	for i, field := range fields() {
+		text := fmt.Sprint(value.Field(i).Interface())
+		if field.secret && text != "" {
+			if field.key == "DB_CONN" {
+				text = redactConn(text)
+			} else {
+				text = "xxxxx"
+			}
+		}
+		fmt.Fprintf(w, "%s=%s\n", field.key, text)
+	}
+}
+
+// passwordParam - password=... in a key=value connection string
+var passwordParam = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// urlPassword - user:password@ of a URL that does not parse
+var urlPassword = regexp.MustCompile(`^([a-z][a-z0-9+.-]*://[^:/@]*:)[^@]*@`)
+
+// redactConn - Connection string (URL or key=value) with its password
+// replaced by xxxxx, in the user info or a password query parameter
+func redactConn(conn string) string {
+	if u, err := url.Parse(conn); err == nil && u.Scheme != "" {
+		if query := u.Query(); query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+	if urlPassword.MatchString(conn) {
+		return urlPassword.ReplaceAllString(conn, "${1}xxxxx@")
+	}
+	return passwordParam.ReplaceAllString(conn, "${1}xxxxx")
+}
+
+type field struct {
+	key    string
+	kind   reflect.Kind
+	secret bool
+}
+
+// fields - Keys of Config in declaration order
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	list := make([]field, t.NumField())
+	for i := range list {
+		f := t.Field(i)
+		list[i] = field{key: f.Tag.Get("mapstructure"), kind: f.Type.Kind(), secret: f.Tag.Get("secret") == "true"}
+	}
+	return list
+}
+
+// flagName - DB_CONN -> db-conn
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
@@ -8,7 +8,15 @@ import (
 	_ "github.com/lib/pq"
 )
 
-func InitDB(connectionString string) (*sql.DB, error) {
+// PoolConfig - Connection pool settings. A MaxOpenConns or ConnMaxLifetime
+// of 0 means no limit, a MaxIdleConns of 0 keeps no idle connections.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func InitDB(connectionString string, pool PoolConfig) (*sql.DB, error) {
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
 		return nil, err
@@ -21,9 +29,9 @@ func InitDB(connectionString string) (*sql.DB, error) {
 	}
 
 	// Connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
 
 	log.Println("✅ Database connected successfully")
 	return db, nil
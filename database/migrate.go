@@ -0,0 +1,227 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock - pg_advisory_xact_lock key, so that concurrent runs apply
+// every migration once
+const migrationLock = 4182735
+
+// Migration - One schema change: migrations/<version>_<name>.up.sql and
+// its .down.sql
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus - A migration and when it was applied, nil when pending
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations - The embedded migrations in version order
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		name, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", base)
+		}
+		number, name, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", base)
+		}
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: needs both an .up.sql and a .down.sql file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp - Apply up to steps pending migrations (0 = all) in version
+// order, each in its own transaction. Returns the applied ones.
+func MigrateUp(db *sql.DB, steps int) ([]Migration, error) {
+	status, err := GetMigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, s := range status {
+		if s.AppliedAt != nil {
+			continue
+		}
+		if steps > 0 && len(applied) == steps {
+			break
+		}
+		if err := runMigration(db, s.Migration, true); err != nil {
+			return applied, err
+		}
+		applied = append(applied, s.Migration)
+	}
+	return applied, nil
+}
+
+// MigrateDown - Revert the steps newest applied migrations. Returns the
+// reverted ones.
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	status, err := GetMigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(status) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if status[i].AppliedAt == nil {
+			continue
+		}
+		if err := runMigration(db, status[i].Migration, false); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, status[i].Migration)
+	}
+	return reverted, nil
+}
+
+// GetMigrationStatus - Every migration with the time it was applied
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := createMigrationTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		status[i].Migration = m
+		if at, ok := appliedAt[m.Version]; ok {
+			status[i].AppliedAt = &at
+		}
+	}
+	return status, nil
+}
+
+func createMigrationTable(db *sql.DB) error {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	return err
+}
+
+// runMigration - Apply (up) or revert one migration together with its
+// schema_migrations row. Skipped when another run got there first.
+func runMigration(db *sql.DB, m Migration, up bool) error {
+	err := withTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationLock); err != nil {
+			return err
+		}
+
+		var applied bool
+		err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", m.Version).
+			Scan(&applied)
+		if err != nil || applied == up {
+			// Already in the wanted state
+			return err
+		}
+
+		if up {
+			if _, err := tx.Exec(m.up); err != nil {
+				return err
+			}
+			_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+			return err
+		}
+		if _, err := tx.Exec(m.down); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", m.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// withTx - Run fn in a transaction, committing on success and rolling back on error
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %04d_%s: want version %d, versions must have no gaps", m.Version, m.Name, i+1)
+		}
+		if strings.TrimSpace(m.up) == "" || strings.TrimSpace(m.down) == "" {
+			t.Errorf("migration %04d_%s: empty up or down", m.Version, m.Name)
+		}
+	}
+}
+
+// expectStatus - GetMigrationStatus with the first applied migrations
+// recorded in schema_migrations
+func expectStatus(mock sqlmock.Sqlmock, applied int) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for v := 1; v <= applied; v++ {
+		rows.AddRow(v, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(rows)
+}
+
+// expectRun - runMigration of m in its own transaction under the advisory
+// lock; failure makes the migration SQL fail
+func expectRun(mock sqlmock.Sqlmock, m Migration, up bool, failure error) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
+		WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(m.Version).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(!up))
+
+	sql := m.down
+	if up {
+		sql = m.up
+	}
+	exec := mock.ExpectExec(regexp.QuoteMeta(sql))
+	if failure != nil {
+		exec.WillReturnError(failure)
+		mock.ExpectRollback()
+		return
+	}
+	exec.WillReturnResult(sqlmock.NewResult(0, 0))
+	if up {
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(m.Version, m.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	} else {
+		mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(m.Version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestMigrateUp(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) < 4 {
+		t.Skip("needs four migrations")
+	}
+
+	tests := []struct {
+		name      string
+		applied   int
+		steps     int
+		failAt    int // index in migrations, -1 = none
+		wantCount int
+	}{
+		{"all pending", len(migrations) - 3, 0, -1, 3},
+		{"steps", 1, 2, -1, 2},
+		{"up to date", len(migrations), 0, -1, 0},
+		{"stops at the failing one", len(migrations) - 3, 0, len(migrations) - 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			expectStatus(mock, tt.applied)
+			for i := tt.applied; i < len(migrations); i++ {
+				if tt.steps > 0 && i-tt.applied == tt.steps {
+					break
+				}
+				if i == tt.failAt {
+					expectRun(mock, migrations[i], true, errors.New("syntax error"))
+					break
+				}
+				expectRun(mock, migrations[i], true, nil)
+			}
+
+			applied, err := MigrateUp(db, tt.steps)
+			if (err != nil) != (tt.failAt >= 0) {
+				t.Fatalf("error = %v", err)
+			}
+			if err != nil && !strings.Contains(err.Error(), migrations[tt.failAt].Name) {
+				t.Errorf("error %q does not name the migration", err)
+			}
+			if len(applied) != tt.wantCount {
+				t.Errorf("applied %d migrations, want %d", len(applied), tt.wantCount)
+			}
+			for i, m := range applied {
+				if m.Version != tt.applied+i+1 {
+					t.Errorf("applied[%d] = %d, want version order", i, m.Version)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Newest first
+	applied := len(migrations) - 1
+	expectStatus(mock, applied)
+	expectRun(mock, migrations[applied-1], false, nil)
+	expectRun(mock, migrations[applied-2], false, nil)
+
+	reverted, err := MigrateDown(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != 2 || reverted[0].Version != applied || reverted[1].Version != applied-1 {
+		t.Errorf("reverted %v, want versions %d and %d", reverted, applied, applied-1)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestStocktakeStoresDown - Reverting 0016 removes the open stock takes of
+// stores before the global one-open index comes back
+func TestStocktakeStoresDown(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if m.Version != 16 {
+			continue
+		}
+		deleteOpen := strings.Index(m.down, "DELETE FROM stocktakes WHERE store_id IS NOT NULL AND status = 'open'")
+		createIndex := strings.Index(m.down, "CREATE UNIQUE INDEX")
+		if deleteOpen < 0 || createIndex < deleteOpen {
+			t.Error("0016 down must delete open store stock takes before recreating uq_stocktakes_open")
+		}
+		return
+	}
+	t.Fatal("migration 0016 not found")
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_products_active;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
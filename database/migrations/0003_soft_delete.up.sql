@@ -0,0 +1,4 @@
+-- Soft delete (archived rows keep their history)
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_products_active ON products (id) WHERE deleted_at IS NULL;
@@ -0,0 +1,17 @@
+-- Product variants (size, color, flavor) with their own SKU, price and stock
+ALTER TABLE products ADD COLUMN IF NOT EXISTS option_types JSONB NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    barcode VARCHAR(64) UNIQUE,
+    options JSONB NOT NULL DEFAULT '{}',
+    price INTEGER CHECK (price > 0),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_product_variants_options UNIQUE (product_id, options)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants (product_id);
@@ -0,0 +1,5 @@
+-- Fractional stock is rounded to whole units
+DROP TABLE IF EXISTS product_units;
+ALTER TABLE product_variants ALTER COLUMN stock TYPE INTEGER USING ROUND(stock);
+ALTER TABLE products ALTER COLUMN stock TYPE INTEGER USING ROUND(stock);
+ALTER TABLE products DROP COLUMN IF EXISTS unit;
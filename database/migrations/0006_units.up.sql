@@ -0,0 +1,13 @@
+-- Units of measure and decimal stock (weighed / measured goods)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unit VARCHAR(8) NOT NULL DEFAULT 'pcs'
+    CHECK (unit IN ('pcs', 'kg', 'g', 'l', 'm'));
+ALTER TABLE products ALTER COLUMN stock TYPE NUMERIC(18, 6);
+ALTER TABLE product_variants ALTER COLUMN stock TYPE NUMERIC(18, 6);
+
+-- Packaging units: buy in boxes of 24, sell in pcs
+CREATE TABLE IF NOT EXISTS product_units (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(32) NOT NULL,
+    factor NUMERIC(18, 6) NOT NULL CHECK (factor > 0),
+    PRIMARY KEY (product_id, name)
+);
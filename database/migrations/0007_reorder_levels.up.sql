@@ -0,0 +1,6 @@
+-- Reorder levels and low-stock alerts
+-- reorder_level NULL = use LOW_STOCK_THRESHOLD; low_stock_alerted_at is set
+-- when an alert was sent and cleared once stock is back above the level
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_level NUMERIC(18, 6) CHECK (reorder_level >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_quantity NUMERIC(18, 6) NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS low_stock_alerted_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,22 @@
+-- Stock takes (physical inventory counts)
+CREATE TABLE IF NOT EXISTS stocktakes (
+    id SERIAL PRIMARY KEY,
+    note TEXT NOT NULL DEFAULT '',
+    category_id INTEGER REFERENCES categories(id) ON DELETE RESTRICT,
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'finalized')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finalized_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Only one stock take can be open at a time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stocktakes_open ON stocktakes ((status)) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS stocktake_items (
+    stocktake_id INTEGER NOT NULL REFERENCES stocktakes(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    expected NUMERIC(18, 6) NOT NULL,
+    counted NUMERIC(18, 6) CHECK (counted >= 0),
+    price INTEGER NOT NULL,
+    counted_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (stocktake_id, product_id)
+);
@@ -0,0 +1,2 @@
+-- The image files stay in the storage
+DROP TABLE IF EXISTS product_images;
@@ -0,0 +1,29 @@
+-- Price history and scheduled prices
+CREATE TABLE IF NOT EXISTS product_scheduled_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price INTEGER NOT NULL CHECK (price > 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One pending price per product and effective time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_product_scheduled_prices_pending
+    ON product_scheduled_prices (product_id, effective_from) WHERE applied_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_product_scheduled_prices_due
+    ON product_scheduled_prices (effective_from) WHERE applied_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_price INTEGER NOT NULL,
+    new_price INTEGER NOT NULL,
+    changed_by VARCHAR(255),
+    source VARCHAR(16) NOT NULL CHECK (source IN ('manual', 'scheduled')),
+    scheduled_price_id INTEGER REFERENCES product_scheduled_prices(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product_id ON product_price_history (product_id, changed_at);
@@ -0,0 +1,3 @@
+ALTER TABLE product_price_history DROP COLUMN IF EXISTS store_id;
+DROP TABLE IF EXISTS store_inventory;
+DROP TABLE IF EXISTS stores;
@@ -0,0 +1,23 @@
+-- Stores with their own stock and price overrides
+CREATE TABLE IF NOT EXISTS stores (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Stock of a product in a store; price NULL = the product price
+CREATE TABLE IF NOT EXISTS store_inventory (
+    store_id INTEGER NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    stock NUMERIC(18, 6) NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    price INTEGER CHECK (price > 0),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (store_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_store_inventory_product_id ON store_inventory (product_id);
+
+ALTER TABLE product_price_history ADD COLUMN IF NOT EXISTS store_id INTEGER REFERENCES stores(id) ON DELETE CASCADE;
@@ -0,0 +1,27 @@
+-- Stock transfers between stores
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id SERIAL PRIMARY KEY,
+    source_store_id INTEGER NOT NULL REFERENCES stores(id) ON DELETE RESTRICT,
+    destination_store_id INTEGER NOT NULL REFERENCES stores(id) ON DELETE RESTRICT,
+    status VARCHAR(16) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'shipped', 'received')),
+    note TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255),
+    shipped_by VARCHAR(255),
+    received_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    shipped_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE,
+    CHECK (source_store_id <> destination_store_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_source ON stock_transfers (source_store_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_destination ON stock_transfers (destination_store_id);
+
+-- Quantities in the product unit; received is set when the transfer arrives
+CREATE TABLE IF NOT EXISTS stock_transfer_lines (
+    transfer_id INTEGER NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    quantity NUMERIC(18, 6) NOT NULL CHECK (quantity > 0),
+    received NUMERIC(18, 6) CHECK (received >= 0),
+    PRIMARY KEY (transfer_id, product_id)
+);
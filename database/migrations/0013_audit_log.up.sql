@@ -0,0 +1,19 @@
+-- Audit log of catalog changes (written in the same transaction as the change)
+-- before/after hold only the changed fields; entity rows are not referenced so
+-- the history outlives them
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity VARCHAR(32) NOT NULL CHECK (entity IN ('product', 'category')),
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    actor VARCHAR(255),
+    request_id VARCHAR(64),
+    ip VARCHAR(45),
+    store_id INTEGER,
+    before JSONB,
+    after JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity, entity_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at DESC);
@@ -0,0 +1,10 @@
+ALTER TABLE stocktake_items DROP COLUMN IF EXISTS applied;
+
+-- Open stock takes of stores were not applied yet, and without store_id
+-- they would turn into counts of the central stock (several of which
+-- cannot be open at once): drop them and their counts. Finalized ones stay.
+DELETE FROM stocktakes WHERE store_id IS NOT NULL AND status = 'open';
+
+DROP INDEX IF EXISTS uq_stocktakes_open;
+ALTER TABLE stocktakes DROP COLUMN IF EXISTS store_id;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stocktakes_open ON stocktakes ((status)) WHERE status = 'open';
@@ -0,0 +1,10 @@
+-- Stock takes of one store count its store_inventory; NULL = the central stock
+ALTER TABLE stocktakes ADD COLUMN IF NOT EXISTS store_id INTEGER REFERENCES stores(id) ON DELETE RESTRICT;
+
+-- One open stock take per store (and one for the central stock)
+DROP INDEX IF EXISTS uq_stocktakes_open;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stocktakes_open ON stocktakes ((COALESCE(store_id, 0))) WHERE status = 'open';
+
+-- Stock change actually applied when finalizing; differs from counted -
+-- expected when the stock would have gone below zero
+ALTER TABLE stocktake_items ADD COLUMN IF NOT EXISTS applied NUMERIC(18, 6);
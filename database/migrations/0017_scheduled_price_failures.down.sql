@@ -0,0 +1,2 @@
+ALTER TABLE product_scheduled_prices DROP COLUMN IF EXISTS last_error;
+ALTER TABLE product_scheduled_prices DROP COLUMN IF EXISTS failed_at;
@@ -0,0 +1,3 @@
+-- Scheduled prices the scheduler could not apply; skipped until cancelled
+ALTER TABLE product_scheduled_prices ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE product_scheduled_prices ADD COLUMN IF NOT EXISTS last_error TEXT;
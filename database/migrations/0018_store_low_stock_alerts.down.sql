@@ -0,0 +1 @@
+ALTER TABLE store_inventory DROP COLUMN IF EXISTS low_stock_alerted_at;
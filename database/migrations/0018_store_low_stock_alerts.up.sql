@@ -0,0 +1,3 @@
+-- Low-stock alerts per store: set when an alert was sent for the stock of
+-- the product in the store and cleared once it is back above the level
+ALTER TABLE store_inventory ADD COLUMN IF NOT EXISTS low_stock_alerted_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,2 @@
+-- API keys bound to a store act for that store only; NULL = any store
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS store_id INTEGER REFERENCES stores(id) ON DELETE RESTRICT;
@@ -0,0 +1,9 @@
+package database
+
+import _ "embed"
+
+// SampleFixture - The sample catalog (seed.yaml) loaded by the seed command
+// when no fixture file is given
+//
+//go:embed seed.yaml
+var SampleFixture []byte
@@ -0,0 +1,19 @@
+package docs
+
+import "embed"
+
+// OpenAPISpec - OpenAPI 3 document describing every route
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// SwaggerUI - HTML page rendering OpenAPISpec with Swagger UI
+//
+//go:embed swagger.html
+var SwaggerUI []byte
+
+// SwaggerUIAssets - Vendored Swagger UI script and stylesheet, under
+// swagger-ui/, so that the page needs no third-party hosts
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var SwaggerUIAssets embed.FS
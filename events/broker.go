@@ -0,0 +1,171 @@
+package events
+
+import (
+	"cashier-api/models"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer - Events queued for one client before it counts as too
+// slow and is disconnected (it resumes with Last-Event-ID)
+const subscriberBuffer = 64
+
+// Event - One product or stock change, numbered in publishing order
+type Event struct {
+	ID   uint64
+	Type string
+	Data models.StreamEvent
+}
+
+// Filter - Events a client asked for
+type Filter struct {
+	CategoryIDs map[int]bool // nil = all categories
+	ProductIDs  map[int]bool // nil = all products
+	StoreID     int          // store of the client, 0 = central
+}
+
+// Match - Whether e is for this client. Stock changes are only sent to
+// clients of the same store; product changes made centrally reach every
+// client, those made in a store only the clients of that store.
+func (f Filter) Match(e Event) bool {
+	if f.CategoryIDs != nil && !f.CategoryIDs[e.Data.CategoryID] {
+		return false
+	}
+	if f.ProductIDs != nil && !f.ProductIDs[e.Data.ProductID] {
+		return false
+	}
+
+	storeID := 0
+	if e.Data.StoreID != nil {
+		storeID = *e.Data.StoreID
+	}
+	if e.Type == models.EventStockChanged || storeID != 0 {
+		return storeID == f.StoreID
+	}
+	return true
+}
+
+// Subscription - Events of one connected client
+type Subscription struct {
+	// Replay - Buffered events after the Last-Event-ID the client resumed from
+	Replay []Event
+	// Reset - The client missed events that are no longer buffered (or
+	// resumed from another server run) and has to reload
+	Reset bool
+	// LastID - ID of the newest event when the client subscribed
+	LastID uint64
+
+	filter Filter
+	events chan Event
+}
+
+// Events - Live events; closed when the client was too slow to keep up
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Broker - Fans out published events to the connected clients and keeps the
+// newest ones in a bounded ring buffer for resuming. IDs start at the server
+// start time in microseconds, so an ID of an earlier run is always too old
+// to be resumed from.
+type Broker struct {
+	mu          sync.Mutex
+	buffer      []Event // ring buffer, oldest at start
+	start       int
+	count       int
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroker(bufferSize int) *Broker {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &Broker{
+		buffer:      make([]Event, bufferSize),
+		lastID:      uint64(time.Now().UnixMicro()),
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Publish - Number and buffer the event and send it to every matching client
+func (b *Broker) Publish(eventType string, data models.StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Data: data}
+
+	if b.count < len(b.buffer) {
+		b.buffer[(b.start+b.count)%len(b.buffer)] = event
+		b.count++
+	} else {
+		b.buffer[b.start] = event
+		b.start = (b.start + 1) % len(b.buffer)
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe - Register a client. With resume set, the buffered events after
+// lastEventID that match the filter are returned in Replay.
+func (b *Broker) Subscribe(filter Filter, lastEventID uint64, resume bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{
+		LastID: b.lastID,
+		filter: filter,
+		events: make(chan Event, subscriberBuffer),
+	}
+
+	if resume {
+		oldest := b.lastID + 1
+		if b.count > 0 {
+			oldest = b.buffer[b.start].ID
+		}
+		if lastEventID+1 < oldest || lastEventID > b.lastID {
+			sub.Reset = true
+		} else {
+			for i := 0; i < b.count; i++ {
+				event := b.buffer[(b.start+i)%len(b.buffer)]
+				if event.ID > lastEventID && filter.Match(event) {
+					sub.Replay = append(sub.Replay, event)
+				}
+			}
+		}
+	}
+
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe - Stop sending events to a disconnected client
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Subscribers - Number of connected clients
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,186 @@
+package events
+
+import (
+	"slices"
+	"testing"
+
+	"cashier-api/models"
+)
+
+func storeID(id int) *int {
+	return &id
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		{"no filter", Filter{},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, CategoryID: 2}}, true},
+		{"category", Filter{CategoryIDs: map[int]bool{2: true}},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, CategoryID: 2}}, true},
+		{"other category", Filter{CategoryIDs: map[int]bool{3: true}},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, CategoryID: 2}}, false},
+		{"product", Filter{ProductIDs: map[int]bool{1: true}},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, CategoryID: 2}}, true},
+		{"other product", Filter{ProductIDs: map[int]bool{5: true}},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, CategoryID: 2}}, false},
+		{"category and other product", Filter{CategoryIDs: map[int]bool{2: true}, ProductIDs: map[int]bool{5: true}},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, CategoryID: 2}}, false},
+		{"central product change in a store", Filter{StoreID: 3},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1}}, true},
+		{"store product change in the store", Filter{StoreID: 3},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, StoreID: storeID(3)}}, true},
+		{"store product change elsewhere", Filter{StoreID: 4},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, StoreID: storeID(3)}}, false},
+		{"store product change centrally", Filter{},
+			Event{Type: models.EventProductUpdated, Data: models.StreamEvent{ProductID: 1, StoreID: storeID(3)}}, false},
+		{"central stock centrally", Filter{},
+			Event{Type: models.EventStockChanged, Data: models.StreamEvent{ProductID: 1}}, true},
+		{"central stock in a store", Filter{StoreID: 3},
+			Event{Type: models.EventStockChanged, Data: models.StreamEvent{ProductID: 1}}, false},
+		{"store stock in the store", Filter{StoreID: 3},
+			Event{Type: models.EventStockChanged, Data: models.StreamEvent{ProductID: 1, StoreID: storeID(3)}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.event); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// publish - n product updates of products 1, 2, ... n; returns their IDs
+func publish(b *Broker, n int) []uint64 {
+	var ids []uint64
+	for i := 1; i <= n; i++ {
+		b.Publish(models.EventProductUpdated, models.StreamEvent{ProductID: i})
+		ids = append(ids, b.lastID)
+	}
+	return ids
+}
+
+func replayedIDs(sub *Subscription) []uint64 {
+	var ids []uint64
+	for _, e := range sub.Replay {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestSubscribeResume(t *testing.T) {
+	b := NewBroker(3)
+	start := b.lastID
+	ids := publish(b, 5) // only the last 3 are buffered
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		resume      bool
+		wantReplay  []uint64
+		wantReset   bool
+	}{
+		{"without Last-Event-ID", 0, false, nil, false},
+		{"up to date", ids[4], true, nil, false},
+		{"within the buffer", ids[2], true, ids[3:], false},
+		{"just before the buffer", ids[1], true, ids[2:], false},
+		{"evicted", ids[0], true, nil, true},
+		{"previous server run", start - 100, true, nil, true},
+		{"from the future", ids[4] + 1, true, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := b.Subscribe(Filter{}, tt.lastEventID, tt.resume)
+			defer b.Unsubscribe(sub)
+
+			if sub.Reset != tt.wantReset {
+				t.Errorf("Reset = %v, want %v", sub.Reset, tt.wantReset)
+			}
+			if got := replayedIDs(sub); !slices.Equal(got, tt.wantReplay) {
+				t.Errorf("Replay = %v, want %v", got, tt.wantReplay)
+			}
+			if sub.LastID != ids[4] {
+				t.Errorf("LastID = %d, want %d", sub.LastID, ids[4])
+			}
+		})
+	}
+}
+
+func TestSubscribeResumeFiltered(t *testing.T) {
+	b := NewBroker(10)
+	ids := publish(b, 4)
+
+	sub := b.Subscribe(Filter{ProductIDs: map[int]bool{2: true, 4: true}}, ids[0], true)
+	if got := replayedIDs(sub); !slices.Equal(got, []uint64{ids[1], ids[3]}) {
+		t.Errorf("Replay = %v, want the events of products 2 and 4", got)
+	}
+
+	// An empty buffer can be resumed from the newest ID only
+	empty := NewBroker(10)
+	if sub := empty.Subscribe(Filter{}, empty.lastID, true); sub.Reset || len(sub.Replay) != 0 {
+		t.Errorf("resume on an empty buffer: Reset %v, Replay %v", sub.Reset, sub.Replay)
+	}
+}
+
+func TestPublishLive(t *testing.T) {
+	b := NewBroker(10)
+	all := b.Subscribe(Filter{}, 0, false)
+	one := b.Subscribe(Filter{ProductIDs: map[int]bool{2: true}}, 0, false)
+
+	ids := publish(b, 3)
+
+	for i, want := range ids {
+		if e := <-all.Events(); e.ID != want || e.Data.ProductID != i+1 {
+			t.Errorf("event %d = %+v, want ID %d", i+1, e, want)
+		}
+	}
+	if e := <-one.Events(); e.Data.ProductID != 2 {
+		t.Errorf("filtered client got product %d", e.Data.ProductID)
+	}
+	if len(one.Events()) != 0 {
+		t.Error("filtered client got other products")
+	}
+
+	b.Unsubscribe(all)
+	b.Unsubscribe(all) // twice is harmless
+	if _, open := <-all.Events(); open {
+		t.Error("events of an unsubscribed client still open")
+	}
+	if b.Subscribers() != 1 {
+		t.Errorf("%d subscribers, want 1", b.Subscribers())
+	}
+}
+
+// TestSlowSubscriberDropped - A client that does not read is disconnected
+// when its queue is full; the others keep receiving
+func TestSlowSubscriberDropped(t *testing.T) {
+	b := NewBroker(10)
+	slow := b.Subscribe(Filter{}, 0, false)
+	fast := b.Subscribe(Filter{}, 0, false)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(models.EventProductUpdated, models.StreamEvent{ProductID: 1})
+		<-fast.Events() // keeps up
+	}
+
+	if b.Subscribers() != 1 {
+		t.Fatalf("%d subscribers, want the slow one dropped", b.Subscribers())
+	}
+	queued := 0
+	for range slow.Events() {
+		queued++
+	}
+	if queued != subscriberBuffer {
+		t.Errorf("slow client got %d events before being closed, want %d", queued, subscriberBuffer)
+	}
+
+	// The dropped client resumes from its last event
+	resumed := b.Subscribe(Filter{}, slow.LastID+subscriberBuffer, true)
+	if resumed.Reset || len(resumed.Replay) != 1 {
+		t.Errorf("resume after drop: Reset %v, %d replayed, want 1", resumed.Reset, len(resumed.Replay))
+	}
+}
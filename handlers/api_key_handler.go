@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// GetAll - GET /api/admin/api-keys
+func (h *APIKeyHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// Create - POST /api/admin/api-keys; the response is the only one showing the key
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.APIKeyInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	key, err := h.service.Create(input)
+	if err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// GetByID - GET /api/admin/api-keys/{id}
+func (h *APIKeyHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid API key ID")
+	if !ok {
+		return
+	}
+
+	key, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
+
+// Revoke - DELETE /api/admin/api-keys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid API key ID")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revoke(id); err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "API key revoked successfully",
+	})
+}
+
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case err == models.ErrAPIKeyNotFound || err == models.ErrStoreNotFound:
+		return http.StatusNotFound
+	case err == models.ErrInvalidID || err == models.ErrAPIKeyName || err == models.ErrAPIKeyScopes ||
+		err == models.ErrInvalidStoreID ||
+		errors.Is(err, models.ErrUnknownScope):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
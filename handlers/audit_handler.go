@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// GetAll - GET /api/audit?entity=product&id=5&actor=budi&limit=50
+func (h *AuditHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	filter := models.AuditFilter{
+		Entity: strings.TrimSpace(r.URL.Query().Get("entity")),
+		Actor:  strings.TrimSpace(r.URL.Query().Get("actor")),
+	}
+	var ok bool
+	if filter.EntityID, ok = queryPositiveInt(w, r, "id"); !ok {
+		return
+	}
+	if filter.Limit, ok = queryPositiveInt(w, r, "limit"); !ok {
+		return
+	}
+
+	entries, err := h.service.GetAll(filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == models.ErrInvalidAuditEntity || err == models.ErrAuditIDWithoutType {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
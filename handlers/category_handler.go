@@ -34,6 +34,8 @@ func (h *CategoryHandler) HandleCategoryByID(w http.ResponseWriter, r *http.Requ
 		h.GetByID(w, r)
 	case http.MethodPut:
 		h.Update(w, r)
+	case http.MethodPatch:
+		h.Patch(w, r)
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
@@ -42,7 +44,12 @@ func (h *CategoryHandler) HandleCategoryByID(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.service.GetAll()
+	include, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+
+	categories, err := h.service.GetAll(include)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -52,14 +59,25 @@ func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(categories)
 }
 
+// GetTree - GET /api/categories/tree
+func (h *CategoryHandler) GetTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.service.GetTree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
 func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var category models.Category
-	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &category) {
 		return
 	}
 
-	if err := h.service.Create(&category); err != nil {
+	if err := h.service.Create(&category, actor(r)); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -77,7 +95,12 @@ func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	category, err := h.service.GetByID(id)
+	include, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := h.service.GetByID(id, include)
 	if err != nil {
 		status := http.StatusNotFound
 		if err == models.ErrInvalidID {
@@ -87,6 +110,13 @@ func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tag := etag(category.Version)
+	w.Header().Set("ETag", tag)
+	if notModified(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
 }
@@ -99,22 +129,91 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := requireIfMatch(w, r, h.storedVersion(id))
+	if !ok {
+		return
+	}
+
 	var category models.Category
-	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &category) {
 		return
 	}
 
 	category.ID = id
-	if err := h.service.Update(&category); err != nil {
+	category.Version = version
+	if err := h.service.Update(&category, actor(r)); err != nil {
 		status := http.StatusBadRequest
 		if err == models.ErrInvalidID {
 			status = http.StatusNotFound
+		} else if err == models.ErrVersionMismatch {
+			status = http.StatusPreconditionFailed
 		}
 		http.Error(w, err.Error(), status)
 		return
 	}
 
+	w.Header().Set("ETag", etag(category.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// Patch - PATCH /api/categories/{id} with a JSON Merge Patch body
+func (h *CategoryHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := requireIfMatch(w, r, h.storedVersion(id))
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := h.service.Patch(id, version, patch, actor(r))
+	if err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == "category not found" {
+			status = http.StatusNotFound
+		} else if err == models.ErrVersionMismatch {
+			status = http.StatusPreconditionFailed
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("ETag", etag(category.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// Restore - POST /api/categories/{id}/restore
+func (h *CategoryHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.Restore(id, actor(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "category not found" {
+			status = http.StatusNotFound
+		} else if err == models.ErrNotDeleted || err == models.ErrParentArchived {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("ETag", etag(category.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
 }
@@ -127,12 +226,19 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
+	version, ok := requireIfMatch(w, r, h.storedVersion(id))
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(id, version, actor(r)); err != nil {
 		status := http.StatusBadRequest
 		if err.Error() == "category not found" {
 			status = http.StatusNotFound
-		} else if err.Error() == "cannot delete category that has products" {
+		} else if err.Error() == "cannot delete category that has products" || err == models.ErrCategoryHasChildren {
 			status = http.StatusConflict
+		} else if err == models.ErrVersionMismatch {
+			status = http.StatusPreconditionFailed
 		}
 		http.Error(w, err.Error(), status)
 		return
@@ -143,3 +249,14 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		"message": "Category deleted successfully",
 	})
 }
+
+// storedVersion - Current version of a category, for If-Match lists
+func (h *CategoryHandler) storedVersion(id int) func() (int, error) {
+	return func() (int, error) {
+		category, err := h.service.GetByID(id, false)
+		if err != nil {
+			return 0, err
+		}
+		return category.Version, nil
+	}
+}
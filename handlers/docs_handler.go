@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"cashier-api/docs"
+	"net/http"
+)
+
+type DocsHandler struct {
+	assets http.Handler
+}
+
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{
+		assets: http.StripPrefix("/docs/", http.FileServerFS(docs.SwaggerUIAssets)),
+	}
+}
+
+// OpenAPI - GET /openapi.json
+func (h *DocsHandler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(docs.OpenAPISpec)
+}
+
+// SwaggerUI - GET /docs
+func (h *DocsHandler) SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docs.SwaggerUI)
+}
+
+// SwaggerUIAsset - GET /docs/swagger-ui/{file}: script and stylesheet of the
+// Swagger UI page
+func (h *DocsHandler) SwaggerUIAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	h.assets.ServeHTTP(w, r)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"cashier-api/events"
+	"cashier-api/middleware"
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// heartbeatInterval - Comment sent on an idle stream so proxies keep it open
+const heartbeatInterval = 15 * time.Second
+
+type EventHandler struct {
+	service *services.EventService
+}
+
+func NewEventHandler(service *services.EventService) *EventHandler {
+	return &EventHandler{service: service}
+}
+
+// Stream - GET /api/events?category_id=3&product_id=5,6 (Server-Sent Events).
+// Resumes after the Last-Event-ID header, or ?last_event_id= for clients
+// that cannot set headers; ?store_id= likewise stands in for X-Store-ID.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := queryPositiveInt(w, r, "category_id")
+	if !ok {
+		return
+	}
+	productIDs, ok := queryIDList(w, r, "product_id")
+	if !ok {
+		return
+	}
+	storeID := middleware.StoreID(r.Context())
+	if storeID == 0 {
+		if storeID, ok = queryPositiveInt(w, r, "store_id"); !ok {
+			return
+		}
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, err := h.service.Subscribe(categoryID, productIDs, storeID, lastEventID)
+	if err != nil {
+		http.Error(w, err.Error(), eventErrorStatus(err))
+		return
+	}
+	defer h.service.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// The stream outlives SERVER_READ_TIMEOUT and SERVER_WRITE_TIMEOUT
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+	if err := rc.Flush(); err != nil {
+		http.Error(w, models.ErrStreamUnsupported.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if sub.Reset {
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", sub.LastID)
+	}
+	for _, event := range sub.Replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-sub.Events():
+			if !open {
+				// Too slow to keep up: the client reconnects with Last-Event-ID
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent - One event in text/event-stream format
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// queryIDList - Parse an optional comma separated list of positive IDs
+func queryIDList(w http.ResponseWriter, r *http.Request, name string) ([]int, bool) {
+	var ids []int
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil || id <= 0 {
+				http.Error(w, "Invalid "+name+" parameter", http.StatusBadRequest)
+				return nil, false
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
+
+func eventErrorStatus(err error) int {
+	switch {
+	case err.Error() == "category not found" || err == models.ErrStoreNotFound:
+		return http.StatusNotFound
+	case err == models.ErrInvalidID || err == models.ErrInvalidLastEventID:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"cashier-api/middleware"
+	"cashier-api/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON - Strictly decode a JSON request body into v. Unknown fields,
+// wrong types, malformed or trailing JSON and bodies over the route limit
+// are answered with a message pointing at the problem.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil && decoder.More() {
+		err = errors.New("request body must contain a single JSON value")
+	}
+	if err == nil {
+		return true
+	}
+	if bodyTooLarge(w, err) {
+		return false
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	message := "Invalid request body: " + err.Error()
+	switch {
+	case errors.Is(err, io.EOF):
+		message = "Request body must not be empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		message = "Invalid request body: unexpected end of JSON"
+	case errors.As(err, &syntaxErr):
+		message = fmt.Sprintf("Invalid request body: malformed JSON at byte %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		message = fmt.Sprintf("Invalid request body: field %q must be %s", typeErr.Field, jsonType(typeErr.Type))
+	case errors.As(err, &typeErr):
+		message = fmt.Sprintf("Invalid request body: must be %s", jsonType(typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		message = "Invalid request body: unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	}
+	http.Error(w, message, http.StatusBadRequest)
+	return false
+}
+
+// jsonType - JSON name of the values a Go type is decoded from
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return t.String()
+}
+
+// bodyTooLarge - Answer 413 when err comes from reading past the body limit
+func bodyTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	http.Error(w, fmt.Sprintf("request body must not be larger than %d bytes", tooLarge.Limit),
+		http.StatusRequestEntityTooLarge)
+	return true
+}
+
+// readMergePatch - Read a JSON Merge Patch body. Accepts
+// application/merge-patch+json (RFC 7396) and plain application/json.
+func readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+			return nil, false
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil && bodyTooLarge(w, err) {
+		return nil, false
+	}
+	if err != nil || len(patch) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	return patch, true
+}
+
+// etag - Strong entity tag derived from the row version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// notModified - True when If-None-Match matches the current entity tag
+func notModified(r *http.Request, currentETag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == currentETag {
+			return true
+		}
+	}
+	return false
+}
+
+// requireIfMatch - Parse the If-Match header required on PUT/PATCH/DELETE
+// and return the version the write must find; "*" yields 0, which matches
+// any version. Tags are compared strongly (RFC 9110), so weak tags never
+// match. When several strong tags are listed, current supplies the stored
+// version to pick the matching one. A header that is not an entity-tag list
+// is answered with 400.
+func requireIfMatch(w http.ResponseWriter, r *http.Request, current func() (int, error)) (int, bool) {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if strings.TrimSpace(header) == "" {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return 0, true
+	}
+
+	tags, ok := parseETags(header)
+	if !ok {
+		http.Error(w, "If-Match must be * or a list of entity tags", http.StatusBadRequest)
+		return 0, false
+	}
+
+	var versions []int
+	for _, tag := range tags {
+		if tag.weak {
+			continue
+		}
+		if version, err := strconv.Atoi(tag.value); err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		http.Error(w, models.ErrVersionMismatch.Error(), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	if len(versions) == 1 {
+		return versions[0], true
+	}
+
+	// The write still checks the version, so a concurrent change between
+	// this read and the write is detected there. Lookup errors (e.g. not
+	// found) are left to the write as well.
+	stored, err := current()
+	if err != nil {
+		return versions[0], true
+	}
+	for _, version := range versions {
+		if version == stored {
+			return version, true
+		}
+	}
+	http.Error(w, models.ErrVersionMismatch.Error(), http.StatusPreconditionFailed)
+	return 0, false
+}
+
+type entityTag struct {
+	value string // without quotes
+	weak  bool
+}
+
+// parseETags - Parse a comma separated entity-tag list; empty list elements
+// are allowed as in every HTTP list
+func parseETags(header string) ([]entityTag, bool) {
+	var tags []entityTag
+	for _, element := range strings.Split(header, ",") {
+		element = strings.Trim(element, " \t")
+		if element == "" {
+			continue
+		}
+		var tag entityTag
+		if rest, ok := strings.CutPrefix(element, "W/"); ok {
+			tag.weak = true
+			element = rest
+		}
+		if len(element) < 2 || element[0] != '"' || element[len(element)-1] != '"' {
+			return nil, false
+		}
+		tag.value = element[1 : len(element)-1]
+		for i := 0; i < len(tag.value); i++ {
+			// etagc: visible characters except DQUOTE, and obs-text
+			if c := tag.value[i]; c < 0x21 || c == '"' || c == 0x7f {
+				return nil, false
+			}
+		}
+		tags = append(tags, tag)
+	}
+	return tags, len(tags) > 0
+}
+
+// includeDeleted - Parse the optional ?include_deleted=true query parameter
+func includeDeleted(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	return queryBool(w, r, "include_deleted")
+}
+
+// queryBool - Parse an optional boolean query parameter (false when absent)
+func queryBool(w http.ResponseWriter, r *http.Request, name string) (bool, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, true
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		http.Error(w, "Invalid "+name+" parameter", http.StatusBadRequest)
+		return false, false
+	}
+	return b, true
+}
+
+// queryPositiveInt - Parse an optional positive integer query parameter (0 when absent)
+func queryPositiveInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		http.Error(w, "Invalid "+name+" parameter", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// productFilter - Query parameters shared by the product list and export
+func productFilter(w http.ResponseWriter, r *http.Request) (models.ProductFilter, bool) {
+	var filter models.ProductFilter
+	var ok bool
+	if filter.IncludeDeleted, ok = includeDeleted(w, r); !ok {
+		return filter, false
+	}
+	if filter.CategoryID, ok = queryPositiveInt(w, r, "category_id"); !ok {
+		return filter, false
+	}
+	filter.Barcode = strings.TrimSpace(r.URL.Query().Get("barcode"))
+	return filter, true
+}
+
+// pathID - Parse a positive integer path wildcard such as {id}
+func pathID(w http.ResponseWriter, r *http.Request, name string, message string) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		http.Error(w, message, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// requireStore - Answer 403 unless the API key of the request may act for
+// one of storeIDs (0 = the central stock); keys not bound to a store may
+func requireStore(w http.ResponseWriter, r *http.Request, storeIDs ...int) bool {
+	if middleware.StoreAllowed(r.Context(), storeIDs...) {
+		return true
+	}
+	http.Error(w, models.ErrStoreForbidden.Error(), http.StatusForbidden)
+	return false
+}
+
+// maxActorLength - Length of the actor columns (VARCHAR(255))
+const maxActorLength = 255
+
+// actor - Who makes the request and from where. A request with an API key is
+// made by the key, "key:<name>"; X-Actor only adds the person using it, as
+// "key:<name>/<X-Actor>", so it cannot pass for another key. Keyless tills
+// are recorded by their X-Actor header.
+func actor(r *http.Request) models.Actor {
+	name := strings.TrimSpace(r.Header.Get("X-Actor"))
+	if key := middleware.APIKey(r.Context()); key != nil {
+		if name == "" {
+			name = "key:" + key.Name
+		} else {
+			name = "key:" + key.Name + "/" + name
+		}
+	}
+	if runes := []rune(name); len(runes) > maxActorLength {
+		name = string(runes[:maxActorLength])
+	}
+	return models.Actor{
+		Name:      name,
+		RequestID: middleware.RequestID(r.Context()),
+		IP:        middleware.ClientIP(r.Context()),
+	}
+}
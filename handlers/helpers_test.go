@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cashier-api/middleware"
+	"cashier-api/models"
+)
+
+func TestRequireIfMatch(t *testing.T) {
+	stored := func() (int, error) { return 4, nil }
+	tests := []struct {
+		header  string
+		version int
+		status  int // 0 = accepted
+	}{
+		{`"3"`, 3, 0},
+		{`*`, 0, 0},
+		{` "3" `, 3, 0},
+		{`"3", "4"`, 4, 0},
+		{`"4","3"`, 4, 0},
+		{`, "3" ,`, 3, 0},
+		{`W/"3", "5"`, 5, 0},
+		{`"2", "3"`, 0, http.StatusPreconditionFailed},
+		{`W/"3"`, 0, http.StatusPreconditionFailed},
+		{`"abc"`, 0, http.StatusPreconditionFailed},
+		{`"0"`, 0, http.StatusPreconditionFailed},
+		{``, 0, http.StatusPreconditionRequired},
+		{`3`, 0, http.StatusBadRequest},
+		{`"3`, 0, http.StatusBadRequest},
+		{`"3" "4"`, 0, http.StatusBadRequest},
+		{`W/3`, 0, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/api/products/1", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+
+		version, ok := requireIfMatch(w, r, stored)
+		if tt.status == 0 {
+			if !ok || version != tt.version {
+				t.Errorf("If-Match %s: got version %d, ok %v (status %d); want %d", tt.header, version, ok, w.Code, tt.version)
+			}
+			continue
+		}
+		if ok || w.Code != tt.status {
+			t.Errorf("If-Match %s: got ok %v, status %d; want %d", tt.header, ok, w.Code, tt.status)
+		}
+	}
+}
+
+func TestRequireIfMatchLookupError(t *testing.T) {
+	// Left to the write, which reports not found or the mismatch
+	r := httptest.NewRequest(http.MethodDelete, "/api/products/1", nil)
+	r.Header.Set("If-Match", `"3", "4"`)
+	w := httptest.NewRecorder()
+
+	version, ok := requireIfMatch(w, r, func() (int, error) { return 0, errors.New("product not found") })
+	if !ok || version != 3 {
+		t.Fatalf("got version %d, ok %v; want 3, true", version, ok)
+	}
+}
+
+type fakeKeys map[string]*models.APIKey
+
+func (f fakeKeys) Authenticate(secret string) (*models.APIKey, error) {
+	if key, ok := f[secret]; ok {
+		return key, nil
+	}
+	return nil, models.ErrInvalidAPIKey
+}
+
+func TestActor(t *testing.T) {
+	auth := middleware.Auth(middleware.AuthConfig{
+		Keys: fakeKeys{
+			"ck_sync": {Name: "shop sync", Scopes: []string{models.ScopeProductsWrite}},
+		},
+		AnonymousScopes: []string{models.ScopeProductsWrite},
+	})
+	tests := []struct {
+		key, header string
+		want        string
+	}{
+		{"", "", ""},
+		{"", "budi", "budi"},
+		{"ck_sync", "", "key:shop sync"},
+		{"ck_sync", "budi", "key:shop sync/budi"},
+		{"ck_sync", "key:admin", "key:shop sync/key:admin"},
+		{"", strings.Repeat("a", 300), strings.Repeat("a", maxActorLength)},
+	}
+
+	for _, tt := range tests {
+		var got models.Actor
+		handler := auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = actor(r) }))
+
+		r := httptest.NewRequest(http.MethodPut, "/api/products/1", nil)
+		if tt.key != "" {
+			r.Header.Set(middleware.APIKeyHeader, tt.key)
+		}
+		if tt.header != "" {
+			r.Header.Set("X-Actor", tt.header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if got.Name != tt.want {
+			t.Errorf("key %q, X-Actor %q: actor %q, want %q", tt.key, tt.header, got.Name, tt.want)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type input struct {
+		Name   string          `json:"name"`
+		Price  int             `json:"price"`
+		Stock  models.Quantity `json:"stock"`
+		Active *bool           `json:"active"`
+		Tags   []string        `json:"tags"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    int // 0 = decoded
+		wantMessage string
+	}{
+		{"valid", `{"name": "Tea", "price": 5000, "stock": "1.5", "tags": ["hot"]}`, 0, ""},
+		{"empty", ``, http.StatusBadRequest, "Request body must not be empty"},
+		{"unknown field", `{"name": "Tea", "colour": "red"}`, http.StatusBadRequest,
+			`Invalid request body: unknown field "colour"`},
+		{"string for integer", `{"price": "5000"}`, http.StatusBadRequest,
+			`Invalid request body: field "price" must be an integer`},
+		{"fraction for integer", `{"price": 1.5}`, http.StatusBadRequest,
+			`Invalid request body: field "price" must be an integer`},
+		{"number for string", `{"name": 5}`, http.StatusBadRequest,
+			`Invalid request body: field "name" must be a string`},
+		{"string for boolean", `{"active": "yes"}`, http.StatusBadRequest,
+			`Invalid request body: field "active" must be a boolean`},
+		{"object for array", `{"tags": {}}`, http.StatusBadRequest,
+			`Invalid request body: field "tags" must be an array`},
+		{"array for object", `[1]`, http.StatusBadRequest, "Invalid request body: must be an object"},
+		{"malformed", `{"name": "Tea",}`, http.StatusBadRequest, "Invalid request body: malformed JSON at byte 16"},
+		{"truncated", `{"name": "Tea"`, http.StatusBadRequest, "Invalid request body: unexpected end of JSON"},
+		{"trailing value", `{"name": "Tea"} {}`, http.StatusBadRequest,
+			"Invalid request body: request body must contain a single JSON value"},
+		{"over the body limit", `{"name": "` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge,
+			"request body must not be larger than 64 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/products", strings.NewReader(tt.body))
+			r.Body = http.MaxBytesReader(w, r.Body, 64)
+
+			var v input
+			ok := decodeJSON(w, r, &v)
+			if tt.wantCode == 0 {
+				if !ok || v.Name != "Tea" || v.Price != 5000 || v.Stock.String() != "1.5" {
+					t.Fatalf("decoded %+v, ok %v (%s)", v, ok, w.Body.String())
+				}
+				return
+			}
+			if ok || w.Code != tt.wantCode {
+				t.Fatalf("ok %v, status %d; want %d", ok, w.Code, tt.wantCode)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.wantMessage {
+				t.Errorf("message %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
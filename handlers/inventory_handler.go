@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"cashier-api/middleware"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+)
+
+type InventoryHandler struct {
+	service *services.InventoryService
+}
+
+func NewInventoryHandler(service *services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{service: service}
+}
+
+// GetLowStock - GET /api/inventory/low-stock, of the store of the request
+func (h *InventoryHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.service.ForStore(middleware.StoreID(r.Context())).GetLowStock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+)
+
+type PriceHandler struct {
+	service *services.PriceService
+}
+
+func NewPriceHandler(service *services.PriceService) *PriceHandler {
+	return &PriceHandler{service: service}
+}
+
+// GetHistory - GET /api/products/{id}/price-history
+func (h *PriceHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	changes, err := h.service.GetHistory(productID)
+	if err != nil {
+		http.Error(w, err.Error(), priceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// GetScheduled - GET /api/products/{id}/scheduled-prices
+func (h *PriceHandler) GetScheduled(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	scheduled, err := h.service.GetScheduled(productID)
+	if err != nil {
+		http.Error(w, err.Error(), priceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+// Schedule - POST /api/products/{id}/scheduled-prices
+func (h *PriceHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var input models.ScheduledPriceInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	scheduled, err := h.service.Schedule(productID, input, actor(r).Name)
+	if err != nil {
+		http.Error(w, err.Error(), priceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+// CancelScheduled - DELETE /api/products/{id}/scheduled-prices/{scheduleID}
+func (h *PriceHandler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "scheduleID", "Invalid scheduled price ID")
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelScheduled(productID, id); err != nil {
+		http.Error(w, err.Error(), priceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Scheduled price cancelled successfully",
+	})
+}
+
+func priceErrorStatus(err error) int {
+	switch {
+	case err.Error() == "product not found" || err == models.ErrScheduledPriceNotFound:
+		return http.StatusNotFound
+	case err == models.ErrScheduledPriceExists || err == models.ErrScheduledPriceApplied:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrInvalidPrice || err == models.ErrEffectiveFromPast:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
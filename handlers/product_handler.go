@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cashier-api/middleware"
 	"cashier-api/models"
 	"cashier-api/services"
 	"encoding/json"
@@ -17,6 +18,11 @@ func NewProductHandler(service *services.ProductService) *ProductHandler {
 	return &ProductHandler{service: service}
 }
 
+// serviceFor - Product service scoped to the store of the request
+func (h *ProductHandler) serviceFor(r *http.Request) *services.ProductService {
+	return h.service.ForStore(middleware.StoreID(r.Context()))
+}
+
 func (h *ProductHandler) HandleProducts(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -34,6 +40,8 @@ func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Reques
 		h.GetByID(w, r)
 	case http.MethodPut:
 		h.Update(w, r)
+	case http.MethodPatch:
+		h.Patch(w, r)
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
@@ -42,7 +50,12 @@ func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	products, err := h.service.GetAll()
+	filter, ok := productFilter(w, r)
+	if !ok {
+		return
+	}
+
+	products, err := h.serviceFor(r).GetAll(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -54,12 +67,11 @@ func (h *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &product) {
 		return
 	}
 
-	if err := h.service.Create(&product); err != nil {
+	if err := h.serviceFor(r).Create(&product, actor(r)); err != nil {
 		status := http.StatusBadRequest
 		if err == models.ErrCategoryNotFound {
 			status = http.StatusNotFound
@@ -81,7 +93,12 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.service.GetByID(id)
+	include, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.serviceFor(r).GetByID(id, include)
 	if err != nil {
 		status := http.StatusNotFound
 		if err == models.ErrInvalidID {
@@ -91,6 +108,13 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tag := etag(product.Version)
+	w.Header().Set("ETag", tag)
+	if notModified(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
@@ -103,22 +127,97 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := requireIfMatch(w, r, h.storedVersion(r, id))
+	if !ok {
+		return
+	}
+
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &product) {
 		return
 	}
 
 	product.ID = id
-	if err := h.service.Update(&product); err != nil {
+	product.Version = version
+	if err := h.serviceFor(r).Update(&product, actor(r)); err != nil {
 		status := http.StatusBadRequest
 		if err == models.ErrInvalidID || err == models.ErrCategoryNotFound {
 			status = http.StatusNotFound
+		} else if err == models.ErrVersionMismatch {
+			status = http.StatusPreconditionFailed
+		} else if err == models.ErrUnitChangeWithStock {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// Patch - PATCH /api/products/{id} with a JSON Merge Patch body
+func (h *ProductHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := requireIfMatch(w, r, h.storedVersion(r, id))
+	if !ok {
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.serviceFor(r).Patch(id, version, patch, actor(r))
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == models.ErrCategoryNotFound || err.Error() == "product not found" {
+			status = http.StatusNotFound
+		} else if err == models.ErrVersionMismatch {
+			status = http.StatusPreconditionFailed
+		} else if err == models.ErrUnitChangeWithStock {
+			status = http.StatusConflict
 		}
 		http.Error(w, err.Error(), status)
 		return
 	}
 
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// Restore - POST /api/products/{id}/restore
+func (h *ProductHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.serviceFor(r).Restore(id, actor(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "product not found" {
+			status = http.StatusNotFound
+		} else if err == models.ErrNotDeleted {
+			status = http.StatusConflict
+		} else if err == models.ErrCategoryArchived {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
@@ -131,12 +230,19 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
+	version, ok := requireIfMatch(w, r, h.storedVersion(r, id))
+	if !ok {
+		return
+	}
+
+	if err := h.serviceFor(r).Delete(id, version, actor(r)); err != nil {
 		status := http.StatusInternalServerError
 		if err == models.ErrInvalidID {
 			status = http.StatusBadRequest
 		} else if err.Error() == "product not found" {
 			status = http.StatusNotFound
+		} else if err == models.ErrVersionMismatch {
+			status = http.StatusPreconditionFailed
 		}
 		http.Error(w, err.Error(), status)
 		return
@@ -147,3 +253,42 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		"message": "Product deleted successfully",
 	})
 }
+
+// Batch - POST /api/products/batch
+func (h *ProductHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := h.serviceFor(r).Batch(req, actor(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == models.ErrInvalidBatchMode || err == models.ErrEmptyBatch || err == models.ErrBatchTooLarge {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// An atomic batch with a failed operation committed nothing
+	status := http.StatusOK
+	if !result.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// storedVersion - Current version of a product, for If-Match lists
+func (h *ProductHandler) storedVersion(r *http.Request, id int) func() (int, error) {
+	return func() (int, error) {
+		product, err := h.serviceFor(r).GetByID(id, false)
+		if err != nil {
+			return 0, err
+		}
+		return product.Version, nil
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// multipartOverhead - Room for multipart headers and boundaries on top of
+// the image size limit
+const multipartOverhead = 64 << 10
+
+type ProductImageHandler struct {
+	service *services.ProductImageService
+}
+
+func NewProductImageHandler(service *services.ProductImageService) *ProductImageHandler {
+	return &ProductImageHandler{service: service}
+}
+
+// Upload - POST /api/products/{id}/images (multipart/form-data, field "image")
+func (h *ProductImageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.service.MaxBytes()+multipartOverhead)
+	if err := r.ParseMultipartForm(h.service.MaxBytes()); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, models.ErrImageTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, models.ErrImageRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	image, err := h.service.Upload(r.Context(), productID, file)
+	if err != nil {
+		http.Error(w, err.Error(), imageErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(image)
+}
+
+// Delete - DELETE /api/products/{id}/images/{imageID}
+func (h *ProductImageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "imageID", "Invalid image ID")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), productID, id); err != nil {
+		http.Error(w, err.Error(), imageErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Image deleted successfully",
+	})
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case err.Error() == "product not found" || err == models.ErrImageNotFound:
+		return http.StatusNotFound
+	case err == models.ErrImageTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case err == models.ErrImageType:
+		return http.StatusUnsupportedMediaType
+	case err == models.ErrInvalidID || err == models.ErrImageRequired || err == models.ErrImageCorrupt ||
+		err == models.ErrImageDimensions:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
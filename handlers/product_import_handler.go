@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"cashier-api/models"
+	"cashier-api/spreadsheet"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// maxImportMemory - Multipart parts above this size are buffered on disk
+const maxImportMemory = 32 << 20
+
+// Import - POST /api/products/import
+//
+// Accepts a CSV or XLSX file either as multipart/form-data (field "file") or
+// as the raw request body. ?dry_run=true only validates and reports errors.
+func (h *ProductHandler) Import(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := queryBool(w, r, "dry_run")
+	if !ok {
+		return
+	}
+
+	body, format, err := readImportFile(r)
+	if err != nil {
+		if !bodyTooLarge(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	defer body.Close()
+
+	rows, err := spreadsheet.Read(body, format)
+	if err != nil {
+		if bodyTooLarge(w, err) {
+			return
+		}
+		http.Error(w, "Invalid "+format+" file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceFor(r).Import(rows, dryRun, actor(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidImportFile) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	} else if result.Created > 0 {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// Export - GET /api/products/export?format=csv|xlsx
+func (h *ProductHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = spreadsheet.FormatCSV
+	}
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		http.Error(w, spreadsheet.ErrUnsupportedFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, ok := productFilter(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.serviceFor(r).Export(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Render first so that a failure can still be reported as a 500
+	var buf bytes.Buffer
+	if err := spreadsheet.Write(&buf, format, rows); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := "products-" + time.Now().Format("20060102") + "." + format
+	w.Header().Set("Content-Type", spreadsheet.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	buf.WriteTo(w)
+}
+
+// readImportFile - Get the uploaded file and its format. ?format= overrides
+// detection from the file name or Content-Type.
+func readImportFile(r *http.Request) (io.ReadCloser, string, error) {
+	format := r.URL.Query().Get("format")
+	body := r.Body
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxImportMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, "", err
+			}
+			return nil, "", errors.New("Invalid multipart form")
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("Missing file field")
+		}
+		body = file
+		if format == "" {
+			format = spreadsheet.FormatFromFilename(header.Filename)
+		}
+		if format == "" {
+			format = spreadsheet.FormatFromContentType(header.Header.Get("Content-Type"))
+		}
+	} else if format == "" {
+		format = spreadsheet.FormatFromContentType(mediaType)
+	}
+
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		body.Close()
+		return nil, "", spreadsheet.ErrUnsupportedFormat
+	}
+	return body, format, nil
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"encoding/json"
+	"net/http"
+)
+
+// SetPackagingUnits - PUT /api/products/{id}/units
+func (h *ProductHandler) SetPackagingUnits(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var input models.PackagingUnitsInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	product, err := h.serviceFor(r).SetPackagingUnits(id, input.Units, actor(r))
+	if err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// AdjustStock - POST /api/products/{id}/stock
+func (h *ProductHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var adjustment models.StockAdjustment
+	if !decodeJSON(w, r, &adjustment) {
+		return
+	}
+
+	product, err := h.serviceFor(r).AdjustStock(id, adjustment, actor(r))
+	if err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func stockErrorStatus(err error) int {
+	switch {
+	case err.Error() == "product not found":
+		return http.StatusNotFound
+	case err == models.ErrInsufficientStock:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrInvalidPackagingUnit || err == models.ErrFractionalQuantity ||
+		err == models.ErrZeroAdjustment || err == models.ErrUnknownUnit || err == models.ErrIncompatibleUnit ||
+		err == models.ErrQuantityPrecision || err == models.ErrQuantityOverflow:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"cashier-api/middleware"
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+)
+
+type StocktakeHandler struct {
+	service *services.StocktakeService
+}
+
+func NewStocktakeHandler(service *services.StocktakeService) *StocktakeHandler {
+	return &StocktakeHandler{service: service}
+}
+
+// GetAll - GET /api/stocktakes
+func (h *StocktakeHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	stocktakes, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocktakes)
+}
+
+// Create - POST /api/stocktakes; store_id defaults to the X-Store-ID store
+func (h *StocktakeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.StocktakeInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if storeID := middleware.StoreID(r.Context()); input.StoreID == nil && storeID != 0 {
+		input.StoreID = &storeID
+	}
+	if !requireStore(w, r, stocktakeStore(input.StoreID)) {
+		return
+	}
+
+	stocktake, err := h.service.Create(input)
+	if err != nil {
+		http.Error(w, err.Error(), stocktakeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stocktake)
+}
+
+// GetByID - GET /api/stocktakes/{id}
+func (h *StocktakeHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid stock take ID")
+	if !ok {
+		return
+	}
+
+	stocktake, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), stocktakeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocktake)
+}
+
+// RecordCounts - POST /api/stocktakes/{id}/counts
+func (h *StocktakeHandler) RecordCounts(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid stock take ID")
+	if !ok {
+		return
+	}
+
+	var input models.StocktakeCountsInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if !h.requireStocktakeStore(w, r, id) {
+		return
+	}
+
+	stocktake, err := h.service.RecordCounts(id, input.Counts)
+	if err != nil {
+		http.Error(w, err.Error(), stocktakeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocktake)
+}
+
+// Finalize - POST /api/stocktakes/{id}/finalize
+func (h *StocktakeHandler) Finalize(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid stock take ID")
+	if !ok {
+		return
+	}
+
+	if !h.requireStocktakeStore(w, r, id) {
+		return
+	}
+
+	report, err := h.service.Finalize(id)
+	if err != nil {
+		http.Error(w, err.Error(), stocktakeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Report - GET /api/stocktakes/{id}/report
+func (h *StocktakeHandler) Report(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid stock take ID")
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Report(id)
+	if err != nil {
+		http.Error(w, err.Error(), stocktakeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// requireStocktakeStore - requireStore for the store of a stock take
+func (h *StocktakeHandler) requireStocktakeStore(w http.ResponseWriter, r *http.Request, id int) bool {
+	if middleware.APIKey(r.Context()) == nil {
+		return true
+	}
+	stocktake, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), stocktakeErrorStatus(err))
+		return false
+	}
+	return requireStore(w, r, stocktakeStore(stocktake.StoreID))
+}
+
+// stocktakeStore - Store of a stock take, 0 for the central stock
+func stocktakeStore(storeID *int) int {
+	if storeID == nil {
+		return 0
+	}
+	return *storeID
+}
+
+func stocktakeErrorStatus(err error) int {
+	switch {
+	case err == models.ErrStocktakeNotFound || err == models.ErrStoreNotFound ||
+		err.Error() == "product not found" || err.Error() == "category not found":
+		return http.StatusNotFound
+	case err == models.ErrStocktakeNotOpen || err == models.ErrStocktakeOpenExists:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrInvalidCategoryID || err == models.ErrInvalidStoreID ||
+		err == models.ErrStocktakeProduct ||
+		err == models.ErrInvalidCountMode || err == models.ErrNegativeCount || err == models.ErrEmptyCounts ||
+		err == models.ErrUnknownUnit || err == models.ErrIncompatibleUnit || err == models.ErrFractionalQuantity ||
+		err == models.ErrQuantityPrecision || err == models.ErrQuantityOverflow:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+)
+
+type StoreHandler struct {
+	service *services.StoreService
+}
+
+func NewStoreHandler(service *services.StoreService) *StoreHandler {
+	return &StoreHandler{service: service}
+}
+
+// GetAll - GET /api/stores
+func (h *StoreHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	stores, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stores)
+}
+
+// Create - POST /api/stores
+func (h *StoreHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var store models.Store
+	if !decodeJSON(w, r, &store) {
+		return
+	}
+	if !requireStore(w, r) {
+		return
+	}
+
+	if err := h.service.Create(&store); err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(store)
+}
+
+// GetByID - GET /api/stores/{id}
+func (h *StoreHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid store ID")
+	if !ok {
+		return
+	}
+
+	store, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store)
+}
+
+// Update - PUT /api/stores/{id}
+func (h *StoreHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid store ID")
+	if !ok {
+		return
+	}
+
+	var store models.Store
+	if !decodeJSON(w, r, &store) {
+		return
+	}
+	if !requireStore(w, r, id) {
+		return
+	}
+
+	store.ID = id
+	if err := h.service.Update(&store); err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store)
+}
+
+func storeErrorStatus(err error) int {
+	switch {
+	case err == models.ErrStoreNotFound:
+		return http.StatusNotFound
+	case err == models.ErrStoreCodeExists:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrStoreCodeRequired || err == models.ErrNameRequired:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"cashier-api/middleware"
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+)
+
+type TransferHandler struct {
+	service *services.TransferService
+}
+
+func NewTransferHandler(service *services.TransferService) *TransferHandler {
+	return &TransferHandler{service: service}
+}
+
+// GetAll - GET /api/transfers?status=shipped&store_id=2 (store_id defaults
+// to the X-Store-ID store)
+func (h *TransferHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	filter := models.TransferFilter{Status: r.URL.Query().Get("status")}
+	var ok bool
+	if filter.StoreID, ok = queryPositiveInt(w, r, "store_id"); !ok {
+		return
+	}
+	if filter.StoreID == 0 {
+		filter.StoreID = middleware.StoreID(r.Context())
+	}
+
+	transfers, err := h.service.GetAll(filter)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// Create - POST /api/transfers
+func (h *TransferHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.TransferInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if !requireStore(w, r, input.SourceStoreID, input.DestinationStoreID) {
+		return
+	}
+
+	transfer, err := h.service.Create(input, actor(r).Name)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// GetByID - GET /api/transfers/{id}
+func (h *TransferHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Update - PUT /api/transfers/{id} (drafts only)
+func (h *TransferHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	var input models.TransferInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if !requireStore(w, r, input.SourceStoreID, input.DestinationStoreID) ||
+		!h.requireTransferStore(w, r, id, bothStores) {
+		return
+	}
+
+	transfer, err := h.service.Update(id, input)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Delete - DELETE /api/transfers/{id} (drafts only)
+func (h *TransferHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	if !h.requireTransferStore(w, r, id, bothStores) {
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Transfer deleted successfully",
+	})
+}
+
+// Ship - POST /api/transfers/{id}/ship
+func (h *TransferHandler) Ship(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	if !h.requireTransferStore(w, r, id, sourceStore) {
+		return
+	}
+
+	transfer, err := h.service.Ship(id, actor(r).Name)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Receive - POST /api/transfers/{id}/receive; an empty body means
+// everything arrived
+func (h *TransferHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid transfer ID")
+	if !ok {
+		return
+	}
+
+	var input models.TransferReceiveInput
+	if r.ContentLength != 0 {
+		if !decodeJSON(w, r, &input) {
+			return
+		}
+	}
+
+	if !h.requireTransferStore(w, r, id, destinationStore) {
+		return
+	}
+
+	transfer, err := h.service.Receive(id, input, actor(r).Name)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// requireTransferStore - requireStore for the stores of a transfer that may
+// act on it: the source ships, the destination receives, either edits
+func (h *TransferHandler) requireTransferStore(w http.ResponseWriter, r *http.Request, id int,
+	stores func(*models.Transfer) []int) bool {
+	if middleware.APIKey(r.Context()) == nil {
+		return true
+	}
+	transfer, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return false
+	}
+	return requireStore(w, r, stores(transfer)...)
+}
+
+func sourceStore(t *models.Transfer) []int      { return []int{t.SourceStoreID} }
+func destinationStore(t *models.Transfer) []int { return []int{t.DestinationStoreID} }
+func bothStores(t *models.Transfer) []int       { return []int{t.SourceStoreID, t.DestinationStoreID} }
+
+func transferErrorStatus(err error) int {
+	switch {
+	case err == models.ErrTransferNotFound || err == models.ErrStoreNotFound || err.Error() == "product not found":
+		return http.StatusNotFound
+	case err == models.ErrTransferNotDraft || err == models.ErrTransferNotShipped || err == models.ErrInsufficientStock:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrInvalidStoreID || err == models.ErrTransferSameStore ||
+		err == models.ErrTransferEmpty || err == models.ErrTransferQuantity || err == models.ErrTransferDuplicateLine ||
+		err == models.ErrTransferProduct || err == models.ErrInvalidTransferStatus || err == models.ErrNegativeReceived ||
+		err == models.ErrReceivedExceedsShipped || err == models.ErrUnknownUnit || err == models.ErrIncompatibleUnit ||
+		err == models.ErrFractionalQuantity || err == models.ErrQuantityPrecision || err == models.ErrQuantityOverflow:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
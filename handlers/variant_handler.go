@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"net/http"
+)
+
+type VariantHandler struct {
+	service *services.VariantService
+}
+
+func NewVariantHandler(service *services.VariantService) *VariantHandler {
+	return &VariantHandler{service: service}
+}
+
+// GetAll - GET /api/products/{id}/variants
+func (h *VariantHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	variants, err := h.service.GetAll(productID)
+	if err != nil {
+		http.Error(w, err.Error(), variantErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(variants)
+}
+
+// Create - POST /api/products/{id}/variants
+func (h *VariantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var variant models.Variant
+	if !decodeJSON(w, r, &variant) {
+		return
+	}
+
+	variant.ID = 0
+	variant.ProductID = productID
+	if err := h.service.Create(&variant, actor(r)); err != nil {
+		http.Error(w, err.Error(), variantErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// Update - PUT /api/products/{id}/variants/{variantID}
+func (h *VariantHandler) Update(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "variantID", "Invalid variant ID")
+	if !ok {
+		return
+	}
+
+	var variant models.Variant
+	if !decodeJSON(w, r, &variant) {
+		return
+	}
+
+	variant.ID = id
+	variant.ProductID = productID
+	if err := h.service.Update(&variant, actor(r)); err != nil {
+		http.Error(w, err.Error(), variantErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(variant)
+}
+
+// Delete - DELETE /api/products/{id}/variants/{variantID}
+func (h *VariantHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r, "variantID", "Invalid variant ID")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(productID, id, actor(r)); err != nil {
+		http.Error(w, err.Error(), variantErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Variant deleted successfully",
+	})
+}
+
+// SetOptionTypes - PUT /api/products/{id}/options
+func (h *VariantHandler) SetOptionTypes(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var input models.OptionTypesInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	product, err := h.service.SetOptionTypes(productID, input.OptionTypes, actor(r))
+	if err != nil {
+		http.Error(w, err.Error(), variantErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func variantErrorStatus(err error) int {
+	switch {
+	case err.Error() == "product not found" || err == models.ErrVariantNotFound:
+		return http.StatusNotFound
+	case err == models.ErrDuplicateSKU || err == models.ErrDuplicateVariant || err == models.ErrVariantsExist:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrSKURequired || err == models.ErrInvalidOptions ||
+		err == models.ErrInvalidOptionTypes || err == models.ErrInvalidVariantPrice || err == models.ErrInvalidStock ||
+		err == models.ErrFractionalQuantity:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
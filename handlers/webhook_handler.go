@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"cashier-api/models"
+	"cashier-api/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// GetAll - GET /api/webhooks
+func (h *WebhookHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// Create - POST /api/webhooks; the response is the only one showing the secret
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.WebhookSubscriptionInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	subscription, err := h.service.Create(input)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// GetByID - GET /api/webhooks/{id}
+func (h *WebhookHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// Update - PUT /api/webhooks/{id}
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	var input models.WebhookSubscriptionInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	subscription, err := h.service.Update(id, input)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// Delete - DELETE /api/webhooks/{id}
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Webhook subscription deleted successfully",
+	})
+}
+
+// GetDeliveries - GET /api/webhooks/{id}/deliveries?status=pending
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	h.listDeliveries(w, r, models.DeliveryFilter{SubscriptionID: id, Status: r.URL.Query().Get("status")})
+}
+
+// GetDeadLetters - GET /api/webhooks/dead-letters (deliveries given up on)
+func (h *WebhookHandler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	h.listDeliveries(w, r, models.DeliveryFilter{Status: models.DeliveryDead})
+}
+
+func (h *WebhookHandler) listDeliveries(w http.ResponseWriter, r *http.Request, filter models.DeliveryFilter) {
+	var ok bool
+	if filter.Limit, ok = queryPositiveInt(w, r, "limit"); !ok {
+		return
+	}
+
+	deliveries, err := h.service.GetDeliveries(filter)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RetryDelivery - POST /api/webhooks/deliveries/{deliveryID}/retry
+func (h *WebhookHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "deliveryID", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.RetryDelivery(int64(id))
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case err == models.ErrWebhookNotFound || err == models.ErrDeliveryNotFound:
+		return http.StatusNotFound
+	case err == models.ErrDeliveryNotDead:
+		return http.StatusConflict
+	case err == models.ErrInvalidID || err == models.ErrWebhookURL || err == models.ErrWebhookPrivateURL || err == models.ErrWebhookEvents ||
+		errors.Is(err, models.ErrUnknownWebhookEvent) || err == models.ErrWebhookSecretTooShort ||
+		err == models.ErrInvalidDeliveryStatus:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
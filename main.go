@@ -1,141 +1,175 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
-	"cashier-api/database"
-	"cashier-api/handlers"
-	"cashier-api/repositories"
-	"cashier-api/services"
+	"cashier-api/config"
+	"cashier-api/middleware"
 
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 )
 
-type Config struct {
-	Port   string `mapstructure:"PORT"`
-	DBConn string `mapstructure:"DB_CONN"`
+// commands - Subcommands of the binary; without one it serves
+var commands = map[string]func(args []string) int{
+	"serve":   serveCommand,
+	"migrate": migrateCommand,
+	"seed":    seedCommand,
+	"user":    userCommand,
+	"export":  exportCommand,
+	"import":  importCommand,
+	"config":  configCommand,
+	"help":    helpCommand,
 }
 
+const usage = `usage: cashier-api [command] [flags]
+
+Commands:
+  serve                      run the HTTP server (default)
+  migrate up|down|status     apply, revert or list schema migrations
+  seed [--file FILE]         load the sample catalog or a JSON/YAML fixture
+  user create --name NAME    create an API key, admin scope by default
+  export [--output FILE]     write the product catalog as CSV or XLSX
+  import FILE                create products from a CSV or XLSX file
+  config print               show the effective configuration
+
+Every command accepts the configuration flags (--db-conn, --config, ...);
+run cashier-api COMMAND --help for the full list.`
+
 func main() {
-	// Load configuration with Viper
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-
-	if _, err := os.Stat(".env"); err == nil {
-		viper.SetConfigFile(".env")
-		if err := viper.ReadInConfig(); err != nil {
-			log.Printf("Warning: Error reading .env file: %v", err)
-		}
+	args := os.Args[1:]
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	config := Config{
-		Port:   viper.GetString("PORT"),
-		DBConn: viper.GetString("DB_CONN"),
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", name, usage)
+		os.Exit(2)
 	}
+	os.Exit(command(args))
+}
 
-	if config.Port == "" {
-		config.Port = "8080"
+// helpCommand - cashier-api help: the list of commands
+func helpCommand(args []string) int {
+	fmt.Println(usage)
+	return 0
+}
+
+// serveCommand - cashier-api [serve] [flags]
+func serveCommand(args []string) int {
+	cfg, _, err := setup(config.Flags("cashier-api serve"), args, 0)
+	if err != nil {
+		return fail(err)
 	}
 
-	if config.DBConn == "" {
-		log.Fatal("DB_CONN environment variable is required")
+	a, err := newApp(cfg)
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer a.db.Close()
 
-	// Initialize database connection
-	db, err := database.InitDB(config.DBConn)
+	if err := serve(a); err != nil {
+		log.Fatal(err)
+	}
+	return 0
+}
+
+// loadConfig - Parse args into flags, which hold the configuration flags
+// and those of the command, and read the configuration from them, the
+// environment, .env and the configuration file. At most maxArgs positional
+// arguments are accepted. -h prints the flags and exits.
+func loadConfig(flags *pflag.FlagSet, args []string, maxArgs int) (*config.Config, error) {
+	if err := flags.Parse(args); err == pflag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		return nil, err
+	}
+	if flags.NArg() > maxArgs {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(maxArgs))
+	}
+	return config.Load(flags)
+}
+
+// setup - loadConfig, then validate the configuration and set up logging.
+// Returns the positional arguments.
+func setup(flags *pflag.FlagSet, args []string, maxArgs int) (*config.Config, []string, error) {
+	cfg, err := loadConfig(flags, args, maxArgs)
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return nil, nil, err
 	}
-	defer db.Close()
-
-	// Dependency Injection Setup
-	// Category layer first (products need categories)
-	categoryRepo := repositories.NewCategoryRepository(db)
-	categoryService := services.NewCategoryService(categoryRepo)
-	categoryHandler := handlers.NewCategoryHandler(categoryService)
-
-	// Product layer (depends on category repo for validation)
-	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo, categoryRepo)
-	productHandler := handlers.NewProductHandler(productService)
-
-	// Setup routes
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	setupLogging(cfg)
+	return cfg, flags.Args(), nil
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "OK",
-			"message": "Cashier API Running with Complete Layered Architecture",
-			"version": "2.1.0",
-		})
-	})
-
-	// Product routes
-	http.HandleFunc("/api/products", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet, http.MethodPost:
-			productHandler.HandleProducts(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-
-	http.HandleFunc("/api/products/", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet, http.MethodPut, http.MethodDelete:
-			productHandler.HandleProductByID(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-
-	// Category routes
-	http.HandleFunc("/api/categories", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet, http.MethodPost:
-			categoryHandler.HandleCategories(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-
-	http.HandleFunc("/api/categories/", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet, http.MethodPut, http.MethodDelete:
-			categoryHandler.HandleCategoryByID(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// fail - Report the error of a command; returns its exit code
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return 1
+}
+
+// configCommand - cashier-api config print [flags]: the effective
+// configuration with secrets redacted, followed by validation errors
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: cashier-api config print [flags]")
+		return 2
+	}
+
+	cfg, err := loadConfig(config.Flags("cashier-api config print"), args[1:], 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	cfg.Print(os.Stdout)
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "\nInvalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// setupLogging - Route the log package through slog with LOG_LEVEL and
+// LOG_FORMAT; log.Printf lines are logged at info level
+func setupLogging(cfg *config.Config) {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "json" {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, options)))
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, options)))
+	}
+}
+
+// rateLimitKey - Requests with an API key count against the key, the others
+// against the client IP
+func rateLimitKey(r *http.Request) string {
+	if key := middleware.APIKey(r.Context()); key != nil {
+		return "key:" + strconv.Itoa(key.ID)
+	}
+	return middleware.ClientIP(r.Context())
+}
+
+// splitList - Comma separated config value without empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
 		}
-	})
-
-	// Start server
-	addr := "0.0.0.0:" + config.Port
-	fmt.Println("🚀 Cashier API Server running on http://" + addr)
-	fmt.Println("📊 Available endpoints:")
-	fmt.Println("  GET    /health")
-	fmt.Println("  Products (with category relationships):")
-	fmt.Println("    GET    /api/products")
-	fmt.Println("    POST   /api/products")
-	fmt.Println("    GET    /api/products/{id}")
-	fmt.Println("    PUT    /api/products/{id}")
-	fmt.Println("    DELETE /api/products/{id}")
-	fmt.Println("  Categories:")
-	fmt.Println("    GET    /api/categories")
-	fmt.Println("    POST   /api/categories")
-	fmt.Println("    GET    /api/categories/{id}")
-	fmt.Println("    PUT    /api/categories/{id}")
-	fmt.Println("    DELETE /api/categories/{id}")
-
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.Fatal("Failed to start server:", err)
 	}
+	return list
 }
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"cashier-api/models"
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InventorySource - Provides aggregated catalog figures (implemented by ProductRepository)
+type InventorySource interface {
+	GetInventorySummary(lowStockThreshold int) (*models.InventorySummary, error)
+}
+
+type inventoryCollector struct {
+	source            InventorySource
+	lowStockThreshold int
+
+	products     *prometheus.Desc
+	lowStock     *prometheus.Desc
+	totalStock   *prometheus.Desc
+	catalogValue *prometheus.Desc
+	scrapeErrors prometheus.Counter
+}
+
+func newInventoryCollector(source InventorySource, lowStockThreshold int) *inventoryCollector {
+	return &inventoryCollector{
+		source:            source,
+		lowStockThreshold: lowStockThreshold,
+		products: prometheus.NewDesc(namespace+"_products",
+			"Number of products in the catalog.", nil, nil),
+		lowStock: prometheus.NewDesc(namespace+"_products_low_stock",
+			"Number of products at or below the reorder level in the central stock or a store.", nil, nil),
+		totalStock: prometheus.NewDesc(namespace+"_stock_units",
+			"Stock of all products by unit of measure, central and store stock.", []string{"unit"}, nil),
+		catalogValue: prometheus.NewDesc(namespace+"_catalog_value",
+			"Total catalog value (price x stock) over the central and store stock.", nil, nil),
+		scrapeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "inventory_scrape_errors_total",
+			Help:      "Number of failed inventory metric queries.",
+		}),
+	}
+}
+
+func (c *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.products
+	ch <- c.lowStock
+	ch <- c.totalStock
+	ch <- c.catalogValue
+	c.scrapeErrors.Describe(ch)
+}
+
+func (c *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	summary, err := c.source.GetInventorySummary(c.lowStockThreshold)
+	if err != nil {
+		log.Printf("metrics: failed to collect inventory summary: %v", err)
+		c.scrapeErrors.Inc()
+		c.scrapeErrors.Collect(ch)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(summary.TotalProducts))
+	ch <- prometheus.MustNewConstMetric(c.lowStock, prometheus.GaugeValue, float64(summary.LowStockProducts))
+	for unit, stock := range summary.StockByUnit {
+		ch <- prometheus.MustNewConstMetric(c.totalStock, prometheus.GaugeValue, stock.Float64(), unit)
+	}
+	ch <- prometheus.MustNewConstMetric(c.catalogValue, prometheus.GaugeValue, float64(summary.CatalogValue))
+	c.scrapeErrors.Collect(ch)
+}
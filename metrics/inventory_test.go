@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"cashier-api/models"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeInventory struct {
+	summary *models.InventorySummary
+	err     error
+}
+
+func (f fakeInventory) GetInventorySummary(int) (*models.InventorySummary, error) {
+	return f.summary, f.err
+}
+
+func TestInventoryMetrics(t *testing.T) {
+	m := New()
+	m.RegisterInventory(fakeInventory{summary: &models.InventorySummary{
+		TotalProducts:    6,
+		LowStockProducts: 2,
+		StockByUnit:      map[string]models.Quantity{"pcs": models.NewQuantity(245), "kg": models.NewQuantity(12)},
+		CatalogValue:     1234500,
+	}}, 5)
+
+	expected := `
+# HELP cashier_products Number of products in the catalog.
+# TYPE cashier_products gauge
+cashier_products 6
+# HELP cashier_products_low_stock Number of products at or below the reorder level in the central stock or a store.
+# TYPE cashier_products_low_stock gauge
+cashier_products_low_stock 2
+# HELP cashier_stock_units Stock of all products by unit of measure, central and store stock.
+# TYPE cashier_stock_units gauge
+cashier_stock_units{unit="kg"} 12
+cashier_stock_units{unit="pcs"} 245
+# HELP cashier_catalog_value Total catalog value (price x stock) over the central and store stock.
+# TYPE cashier_catalog_value gauge
+cashier_catalog_value 1.2345e+06
+`
+	err := testutil.GatherAndCompare(m.Gatherer(), strings.NewReader(expected),
+		"cashier_products", "cashier_products_low_stock", "cashier_stock_units", "cashier_catalog_value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Gauges must not use the counter suffix
+	problems, err := testutil.GatherAndLint(m.Gatherer(), "cashier_products", "cashier_stock_units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		t.Errorf("%s: %s", p.Metric, p.Text)
+	}
+}
+
+func TestInventoryMetricsScrapeError(t *testing.T) {
+	m := New()
+	m.RegisterInventory(fakeInventory{err: errors.New("database down")}, 5)
+
+	if n, err := testutil.GatherAndCount(m.Gatherer(), "cashier_products"); err != nil || n != 0 {
+		t.Fatalf("cashier_products series = %d, %v; want 0", n, err)
+	}
+	if n, err := testutil.GatherAndCount(m.Gatherer(), "cashier_inventory_scrape_errors_total"); err != nil || n != 1 {
+		t.Fatalf("scrape error series = %d, %v; want 1", n, err)
+	}
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cashier"
+
+// Metrics - Holds the Prometheus registry and the HTTP collectors
+type Metrics struct {
+	registry        *prometheus.Registry
+	RequestsTotal   *prometheus.CounterVec
+	RequestDuration *prometheus.HistogramVec
+	CacheRequests   *prometheus.CounterVec
+}
+
+// New - Create a registry with Go runtime, process and HTTP collectors
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		registry: registry,
+		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		CacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "requests_total",
+			Help:      "Catalog cache lookups by cache and result (hit, miss or error).",
+		}, []string{"cache", "result"}),
+	}
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.RequestsTotal,
+		m.RequestDuration,
+		m.CacheRequests,
+	)
+
+	return m
+}
+
+// ObserveCache - Count a cache lookup; passed to cache.Config.Observe
+func (m *Metrics) ObserveCache(cache, result string) {
+	m.CacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// RegisterDB - Expose sql.DBStats of the connection pool
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// RegisterInventory - Expose business metrics computed at scrape time
+func (m *Metrics) RegisterInventory(source InventorySource, lowStockThreshold int) {
+	m.registry.MustRegister(newInventoryCollector(source, lowStockThreshold))
+}
+
+// RegisterEventStream - Expose the number of connected GET /api/events clients
+func (m *Metrics) RegisterEventStream(clients func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "stream_clients",
+		Help:      "Number of clients connected to the event stream.",
+	}, func() float64 { return float64(clients()) }))
+}
+
+// Register - Add extra collectors (e.g. from other packages) to the registry
+func (m *Metrics) Register(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Gatherer - Registry used for in-process scrapes
+func (m *Metrics) Gatherer() prometheus.Gatherer {
+	return m.registry
+}
+
+// Handler - HTTP handler for GET /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cashier-api/config"
+	"cashier-api/events"
+	"cashier-api/metrics"
+	"cashier-api/models"
+	"cashier-api/repositories"
+	"cashier-api/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestMetricsEndpoint - Requests through the middleware chain are counted
+// by route pattern (product details are served by the /api/products/
+// subtree), and the collectors registered on start are scraped
+func TestMetricsEndpoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(
+		sqlmock.NewRows([]string{"count", "low_stock", "value"}).AddRow(2, 1, 87500))
+	mock.ExpectQuery("SELECT unit, SUM").WillReturnRows(
+		sqlmock.NewRows([]string{"unit", "sum"}).AddRow("pcs", "25").AddRow("kg", "2.5"))
+
+	products := &fakeProducts{products: map[int]*models.ProductDetail{
+		1: {ID: 1, Name: "Indomie Godog", Price: 3500, Stock: models.NewQuantity(10), Unit: "pcs", CategoryID: 1},
+		2: {ID: 2, Name: "Beras", Price: 14000, Stock: models.NewQuantity(2), Unit: "kg", CategoryID: 1},
+	}}
+	images := services.NewProductImageService(fakeImages{}, products, nil, 1<<20)
+	a := &app{
+		cfg: &config.Config{
+			MetricsEnabled:      true,
+			AuthAnonymousScopes: "products:read",
+			BodyLimit:           1 << 20,
+			LowStockThreshold:   5,
+		},
+		db:             db,
+		metrics:        metrics.New(),
+		broker:         events.NewBroker(1),
+		productRepo:    repositories.NewProductRepository(db, nil),
+		productService: services.NewProductService(products, &fakeCategories{}, fakeVariants{}, images, nil),
+	}
+	registerMetrics(a)
+	handler := newHandler(a, newRouter(a))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+	for _, target := range []string{"/api/products/1", "/api/products/2", "/api/products/99"} {
+		get(target)
+	}
+
+	w := get("/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	scrape := string(body)
+
+	for _, want := range []string{
+		`cashier_http_requests_total{method="GET",route="/api/products/",status="200"} 2`,
+		`cashier_http_requests_total{method="GET",route="/api/products/",status="404"} 1`,
+		`cashier_http_request_duration_seconds_count{method="GET",route="/api/products/",status="200"} 2`,
+		`cashier_http_request_duration_seconds_bucket{method="GET",route="/api/products/",status="200",le="+Inf"} 2`,
+		`go_sql_max_open_connections{db_name="postgres"}`,
+		`go_sql_open_connections{db_name="postgres"}`,
+		`cashier_stock_units{unit="kg"} 2.5`,
+		`cashier_stock_units{unit="pcs"} 25`,
+		`cashier_products_low_stock 1`,
+		`cashier_events_stream_clients 0`,
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("scrape has no %s", want)
+		}
+	}
+	if strings.Contains(scrape, `route="/api/products/1"`) {
+		t.Error("route label is the request path, not the pattern")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"cashier-api/models"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader - Alternative to "Authorization: Bearer <key>"
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyKey struct{}
+
+// KeyAuthenticator - Implemented by services.APIKeyService
+type KeyAuthenticator interface {
+	Authenticate(secret string) (*models.APIKey, error)
+}
+
+// AuthConfig - Settings of Auth
+type AuthConfig struct {
+	Keys KeyAuthenticator
+	// AdminToken - Bootstrap secret acting as a key with the admin scope
+	// (creates the first keys); empty disables it
+	AdminToken string
+	// AnonymousScopes - Scopes of /api/ requests without a key (e.g. tills
+	// on the shop network); empty rejects them. keyOnlyPrefixes and admin
+	// routes always need a key.
+	AnonymousScopes []string
+	// FailureRate / FailureBurst - Token bucket per client IP that every
+	// 401 takes from; once it is empty the IP gets 429 before a key is
+	// looked up, so keys cannot be guessed at the request rate. A rate of 0
+	// leaves failures unlimited.
+	FailureRate  float64
+	FailureBurst int
+}
+
+// keyOnlyPrefixes - Routes that need a key whatever the anonymous scopes:
+// webhook secrets and the audit log are not for the shop floor
+var keyOnlyPrefixes = []string{"/api/webhooks", "/api/audit"}
+
+// scopeResources - Resource of /api/ path prefixes, products for the rest;
+// the scope is the resource plus :read for GET/HEAD or :write otherwise
+var scopeResources = []struct {
+	prefix   string
+	resource string
+}{
+	{"/api/inventory/low-stock", "reports"},
+	{"/api/audit", "reports"},
+	{"/api/stores", "inventory"},
+	{"/api/transfers", "inventory"},
+	{"/api/stocktakes", "inventory"},
+	{"/api/webhooks", "webhooks"},
+}
+
+// Auth - Authenticate /api/ requests carrying an API key and check that the
+// key has the scope of the route: 401 for an unknown or revoked key (or a
+// missing one where the anonymous scopes do not cover the route), 403 for a
+// missing scope, 429 for a client IP with too many 401s. The key is put in
+// the request context.
+func Auth(cfg AuthConfig) Middleware {
+	// Failed attempts are counted in the write bucket of the client IP
+	failures := newRateLimiter(RateLimitConfig{WriteRate: cfg.FailureRate, WriteBurst: cfg.FailureBurst})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			scope := RequiredScope(r)
+
+			ip := ClientIP(r.Context())
+			if wait := failures.wait(ip, true, time.Now()); wait > 0 {
+				tooManyRequests(w, wait)
+				return
+			}
+			reject := func(err error) {
+				failures.take(ip, true, time.Now())
+				unauthorized(w, err)
+			}
+
+			secret := credential(r)
+			if secret == "" {
+				switch {
+				case scope == models.ScopeAdmin:
+					reject(models.ErrAdminKeyRequired)
+				case cfg.allowsAnonymous(r.URL.Path, scope):
+					next.ServeHTTP(w, r)
+				default:
+					reject(models.ErrAPIKeyRequired)
+				}
+				return
+			}
+
+			var key *models.APIKey
+			if cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(cfg.AdminToken)) == 1 {
+				key = &models.APIKey{Name: "admin token", Scopes: []string{models.ScopeAdmin}}
+			} else {
+				var err error
+				if key, err = cfg.Keys.Authenticate(secret); err == models.ErrInvalidAPIKey {
+					reject(err)
+					return
+				} else if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if !key.HasScope(scope) {
+				http.Error(w, models.ErrAPIKeyForbidden.Error()+" "+scope, http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), apiKeyKey{}, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// allowsAnonymous - Whether a request without a key may use scope on path
+func (cfg AuthConfig) allowsAnonymous(path, scope string) bool {
+	for _, prefix := range keyOnlyPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return slices.Contains(cfg.AnonymousScopes, scope)
+}
+
+// APIKey - Key the request was authenticated with, nil without one
+func APIKey(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyKey{}).(*models.APIKey)
+	return key
+}
+
+// RequiredScope - Scope an API key needs for an /api/ request
+func RequiredScope(r *http.Request) string {
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/api/admin/") {
+		return models.ScopeAdmin
+	}
+
+	access := ":write"
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		access = ":read"
+	}
+	// Stock take reports are reports, the rest of a stock take is inventory
+	if strings.HasPrefix(path, "/api/stocktakes/") && strings.HasSuffix(path, "/report") {
+		return "reports" + access
+	}
+	for _, s := range scopeResources {
+		if strings.HasPrefix(path, s.prefix) {
+			return s.resource + access
+		}
+	}
+	return "products" + access
+}
+
+// credential - Key of the Authorization bearer token or X-API-Key header
+func credential(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="cashier-api"`)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cashier-api/models"
+)
+
+type fakeKeys map[string]*models.APIKey
+
+func (f fakeKeys) Authenticate(secret string) (*models.APIKey, error) {
+	if key, ok := f[secret]; ok {
+		return key, nil
+	}
+	return nil, models.ErrInvalidAPIKey
+}
+
+func TestAuth(t *testing.T) {
+	keys := fakeKeys{
+		"ck_reader": {Name: "reader", Scopes: []string{models.ScopeProductsRead}},
+		"ck_hooks":  {Name: "hooks", Scopes: []string{models.ScopeWebhooksRead}},
+		"ck_admin":  {Name: "admin", Scopes: []string{models.ScopeAdmin}},
+		// ck_revoked is not there: a revoked key no longer authenticates
+	}
+
+	tests := []struct {
+		name      string
+		anonymous []string
+		method    string
+		target    string
+		key       string
+		wantCode  int
+	}{
+		{"no key", nil, http.MethodGet, "/api/products", "", http.StatusUnauthorized},
+		{"no key, anonymous scope", []string{models.ScopeProductsRead}, http.MethodGet, "/api/products", "", http.StatusOK},
+		{"no key, other anonymous scope", []string{models.ScopeProductsRead}, http.MethodPost, "/api/products", "", http.StatusUnauthorized},
+		{"no key, webhooks", []string{models.ScopeWebhooksRead}, http.MethodGet, "/api/webhooks", "", http.StatusUnauthorized},
+		{"no key, audit", []string{models.ScopeReportsRead}, http.MethodGet, "/api/audit", "", http.StatusUnauthorized},
+		{"no key, low stock report", []string{models.ScopeReportsRead}, http.MethodGet, "/api/inventory/low-stock", "", http.StatusOK},
+		{"no key, admin", []string{models.ScopeProductsRead}, http.MethodGet, "/api/admin/api-keys", "", http.StatusUnauthorized},
+		{"no key, outside the API", nil, http.MethodGet, "/health", "", http.StatusOK},
+		{"scope", nil, http.MethodGet, "/api/products", "ck_reader", http.StatusOK},
+		{"wrong scope", nil, http.MethodPost, "/api/products", "ck_reader", http.StatusForbidden},
+		{"wrong resource", nil, http.MethodGet, "/api/webhooks", "ck_reader", http.StatusForbidden},
+		{"webhooks scope", nil, http.MethodGet, "/api/webhooks", "ck_hooks", http.StatusOK},
+		{"admin", nil, http.MethodDelete, "/api/admin/api-keys/1", "ck_admin", http.StatusOK},
+		{"revoked key", []string{models.ScopeProductsRead}, http.MethodGet, "/api/products", "ck_revoked", http.StatusUnauthorized},
+		{"admin token", nil, http.MethodGet, "/api/audit", "bootstrap-secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var key *models.APIKey
+			handler := Auth(AuthConfig{Keys: keys, AdminToken: "bootstrap-secret", AnonymousScopes: tt.anonymous})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { key = APIKey(r.Context()) }))
+
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.key != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if w.Code == http.StatusOK && (key != nil) != (tt.key != "") {
+				t.Errorf("key in context = %v, want one only when sent", key)
+			}
+		})
+	}
+}
+
+// countingKeys - fakeKeys that counts the lookups
+type countingKeys struct {
+	fakeKeys
+	lookups int
+}
+
+func (c *countingKeys) Authenticate(secret string) (*models.APIKey, error) {
+	c.lookups++
+	return c.fakeKeys.Authenticate(secret)
+}
+
+func TestAuthThrottlesFailures(t *testing.T) {
+	keys := &countingKeys{fakeKeys: fakeKeys{
+		"ck_reader": {Name: "reader", Scopes: []string{models.ScopeProductsRead}},
+	}}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		RequestInfo(0),
+		Auth(AuthConfig{Keys: keys, FailureRate: 0.01, FailureBurst: 3}),
+	)
+	send := func(remoteAddr, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		r.RemoteAddr = remoteAddr
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Missing and wrong keys count alike
+	for i, key := range []string{"", "ck_guess1", "ck_guess2"} {
+		if w := send("192.0.2.1:1000", key); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want %d", i+1, w.Code, http.StatusUnauthorized)
+		}
+	}
+	lookups := keys.lookups
+
+	w := send("192.0.2.1:1000", "ck_guess3")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d after the burst, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+	if keys.lookups != lookups {
+		t.Error("key looked up for a throttled client")
+	}
+
+	// Other clients are not affected
+	if w := send("192.0.2.2:1000", "ck_reader"); w.Code != http.StatusOK {
+		t.Errorf("other client: status %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsExposedHeaders - Response headers browser code may read: ETag for
+// If-Match, the request ID for support, Retry-After of 429 and the file
+// name of exports
+var corsExposedHeaders = []string{"ETag", RequestIDHeader, "Retry-After", "Content-Disposition"}
+
+// CORSConfig - Cross-origin access for browser frontends on other origins
+type CORSConfig struct {
+	AllowedOrigins   []string // "*" allows any origin; empty disables CORS
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool          // cookies and Authorization from the browser, not with "*"
+	MaxAge           time.Duration // how long browsers cache a preflight answer
+}
+
+// CORS - Add CORS headers to /api/ responses for allowed origins and answer
+// their preflight (OPTIONS) requests with 204. Preflights from other origins
+// get 403; their other requests go through without CORS headers, so the
+// browser withholds the response.
+func CORS(cfg CORSConfig) Middleware {
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(corsExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || len(cfg.AllowedOrigins) == 0 || !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if !anyOrigin && !slices.Contains(cfg.AllowedOrigins, origin) {
+				if preflight {
+					http.Error(w, "origin not allowed", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				h.Set("Access-Control-Allow-Methods", methods)
+				if headers != "" {
+					h.Set("Access-Control-Allow-Headers", headers)
+				}
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", maxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Set("Access-Control-Expose-Headers", exposed)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
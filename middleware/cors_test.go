@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var corsTestConfig = CORSConfig{
+	AllowedOrigins: []string{"https://admin.example.com"},
+	AllowedMethods: []string{"GET", "POST"},
+	AllowedHeaders: []string{"Content-Type", "Authorization"},
+	MaxAge:         10 * time.Minute,
+}
+
+// corsRequest - Send a request through CORS and report whether it reached
+// the handler
+func corsRequest(cfg CORSConfig, method, origin string, preflight bool) (*httptest.ResponseRecorder, bool) {
+	reached := false
+	handler := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+
+	r := httptest.NewRequest(method, "/api/products", nil)
+	r.Header.Set("Origin", origin)
+	if preflight {
+		r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w, reached
+}
+
+func TestCORSPreflightAllowed(t *testing.T) {
+	w, reached := corsRequest(corsTestConfig, http.MethodOptions, "https://admin.example.com", true)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if reached {
+		t.Error("preflight reached the handler")
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://admin.example.com",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "600",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	w, reached := corsRequest(corsTestConfig, http.MethodOptions, "https://evil.example.com", true)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if reached {
+		t.Error("rejected preflight reached the handler")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q on a rejected preflight", got)
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		wantOrigin string
+	}{
+		{"allowed origin", "https://admin.example.com", "https://admin.example.com"},
+		{"rejected origin", "https://evil.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reached := corsRequest(corsTestConfig, http.MethodGet, tt.origin, false)
+
+			if !reached {
+				t.Fatal("request did not reach the handler")
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantOrigin == "" {
+				for header := range w.Header() {
+					if strings.HasPrefix(header, "Access-Control-") {
+						t.Errorf("CORS header %s on a request from a rejected origin", header)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := corsTestConfig
+	cfg.AllowedOrigins = []string{"*"}
+
+	w, _ := corsRequest(cfg, http.MethodGet, "https://shop.example.com", false)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+}
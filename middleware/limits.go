@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// BodyLimit - Cap request bodies with http.MaxBytesReader. routes overrides
+// defaultLimit by mux pattern (e.g. "POST /api/products/import"); a limit of
+// 0 leaves the route to enforce its own. Reading past the limit fails with
+// *http.MaxBytesError, which handlers answer with 413.
+func BodyLimit(mux *http.ServeMux, defaultLimit int64, routes map[string]int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := defaultLimit
+			if _, route := mux.Handler(r); route != "" {
+				if routeLimit, ok := routes[route]; ok {
+					limit = routeLimit
+				}
+			}
+
+			if limit > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > limit {
+					http.Error(w, fmt.Sprintf("request body must not be larger than %d bytes", limit),
+						http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	// Handlers answer a read past the limit with 413, like decodeJSON
+	read := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "read past the limit", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(body)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/products", read)
+	mux.Handle("POST /api/products/import", read)
+	mux.Handle("POST /api/products/{id}/images", read)
+	handler := BodyLimit(mux, 16, map[string]int64{
+		"POST /api/products/import":      64,
+		"POST /api/products/{id}/images": 0,
+	})(mux)
+
+	tests := []struct {
+		name     string
+		target   string
+		size     int
+		chunked  bool
+		wantCode int
+		wantBody string // prefix
+	}{
+		{"within the default", "/api/products", 16, false, http.StatusOK, ""},
+		{"over the default", "/api/products", 17, false, http.StatusRequestEntityTooLarge,
+			"request body must not be larger than 16 bytes"},
+		{"chunked within the default", "/api/products", 16, true, http.StatusOK, ""},
+		{"chunked over the default", "/api/products", 17, true, http.StatusRequestEntityTooLarge, "read past the limit"},
+		{"route override", "/api/products/import", 64, false, http.StatusOK, ""},
+		{"over the route override", "/api/products/import", 65, false, http.StatusRequestEntityTooLarge,
+			"request body must not be larger than 64 bytes"},
+		{"chunked over the route override", "/api/products/import", 65, true, http.StatusRequestEntityTooLarge,
+			"read past the limit"},
+		{"route enforcing its own limit", "/api/products/1/images", 1 << 10, true, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.Repeat("x", tt.size)
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(body))
+			if tt.chunked {
+				// No Content-Length: only MaxBytesReader catches it
+				r.ContentLength = -1
+				r.Body = io.NopCloser(strings.NewReader(body))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && w.Body.String() != body {
+				t.Errorf("handler read %d bytes, want %d", w.Body.Len(), tt.size)
+			}
+			if !strings.HasPrefix(w.Body.String(), tt.wantBody) {
+				t.Errorf("body %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"cashier-api/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics - Record request count and latency per route and status.
+// The route label is the mux pattern so that /api/products/1 and
+// /api/products/2 share one series.
+func Metrics(m *metrics.Metrics, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			next.ServeHTTP(rec, r)
+
+			status := strconv.Itoa(rec.status)
+
+			m.RequestsTotal.WithLabelValues(r.Method, route, status).Inc()
+			m.RequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import "net/http"
+
+// Middleware - Wraps an http.Handler with extra behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain - Apply middlewares so that the first one is the outermost
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// statusRecorder - Captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap - Lets http.ResponseController reach the underlying writer (e.g. for Flush)
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
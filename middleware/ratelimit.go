@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig - Token buckets per client. Reads (GET, HEAD) and writes
+// have separate buckets; a rate of 0 leaves that class unlimited.
+type RateLimitConfig struct {
+	ReadRate   float64 // tokens per second
+	ReadBurst  int     // bucket size
+	WriteRate  float64
+	WriteBurst int
+
+	// Key - Client a request counts against; defaults to the client IP
+	Key func(r *http.Request) string
+}
+
+// clientIdleTimeout - Buckets of clients idle this long are full again and
+// dropped
+const clientIdleTimeout = 10 * time.Minute
+
+// RateLimit - Throttle /api/ requests per client. A request without a token
+// is answered with 429 and Retry-After, the seconds until the next token.
+func RateLimit(cfg RateLimitConfig) Middleware {
+	limiter := newRateLimiter(cfg)
+	if limiter.cfg.Key == nil {
+		limiter.cfg.Key = func(r *http.Request) string { return ClientIP(r.Context()) }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+			if wait := limiter.take(limiter.cfg.Key(r), write, time.Now()); wait > 0 {
+				tooManyRequests(w, wait)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tooManyRequests - 429 with Retry-After in whole seconds
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+type rateLimiter struct {
+	cfg RateLimitConfig
+
+	mu        sync.Mutex
+	clients   map[string]*rateClient
+	lastSweep time.Time
+}
+
+type rateClient struct {
+	read     tokenBucket
+	write    tokenBucket
+	lastSeen time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, clients: map[string]*rateClient{}}
+}
+
+// take - Take a token of the client's read or write bucket. Returns 0 when
+// one was available, otherwise how long until the next one.
+func (l *rateLimiter) take(key string, write bool, now time.Time) time.Duration {
+	return l.use(key, write, now, (*tokenBucket).take)
+}
+
+// wait - How long until the client's read or write bucket has a token, 0
+// when it has one now; the token is left in the bucket
+func (l *rateLimiter) wait(key string, write bool, now time.Time) time.Duration {
+	return l.use(key, write, now, (*tokenBucket).wait)
+}
+
+// use - Apply fn to the client's read or write bucket
+func (l *rateLimiter) use(key string, write bool, now time.Time,
+	fn func(b *tokenBucket, rate float64, burst int, now time.Time) time.Duration) time.Duration {
+	rate, burst := l.cfg.ReadRate, l.cfg.ReadBurst
+	if write {
+		rate, burst = l.cfg.WriteRate, l.cfg.WriteBurst
+	}
+	if rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > clientIdleTimeout {
+		for k, client := range l.clients {
+			if now.Sub(client.lastSeen) > clientIdleTimeout {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &rateClient{}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	bucket := &client.read
+	if write {
+		bucket = &client.write
+	}
+	return fn(bucket, rate, burst, now)
+}
+
+// tokenBucket - Holds up to burst tokens, refilled at rate per second
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time // zero = never used, i.e. full
+}
+
+func (b *tokenBucket) take(rate float64, burst int, now time.Time) time.Duration {
+	wait := b.wait(rate, burst, now)
+	if wait == 0 {
+		b.tokens--
+	}
+	return wait
+}
+
+// wait - Refill the bucket and return how long until it holds a token
+func (b *tokenBucket) wait(rate float64, burst int, now time.Time) time.Duration {
+	if burst < 1 {
+		burst = 1
+	}
+	if b.updated.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name     string
+		after    time.Duration // since start
+		wantWait time.Duration
+	}{
+		{"full bucket", 0, 0},
+		{"burst", 0, 0},
+		{"burst", 0, 0},
+		{"empty", 0, time.Second},
+		{"half refilled", 500 * time.Millisecond, 500 * time.Millisecond},
+		{"refilled", time.Second, 0},
+		{"empty again", time.Second, time.Second},
+		{"idle refills up to the burst", time.Hour, 0},
+		{"burst after idle", time.Hour, 0},
+		{"burst after idle", time.Hour, 0},
+		{"empty after idle", time.Hour, time.Second},
+	}
+
+	var b tokenBucket
+	for i, step := range steps {
+		if wait := b.take(1, 3, start.Add(step.after)); wait != step.wantWait {
+			t.Errorf("take %d (%s): wait %v, want %v", i+1, step.name, wait, step.wantWait)
+		}
+	}
+}
+
+func TestTokenBucketWaitKeepsToken(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var b tokenBucket
+	for i := 0; i < 3; i++ {
+		if wait := b.wait(1, 1, now); wait != 0 {
+			t.Fatalf("wait %d = %v, want the token left in the bucket", i+1, wait)
+		}
+	}
+	if wait := b.wait(1, 0, now); wait != 0 {
+		t.Errorf("burst 0 = %v, want a bucket of one", wait)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		RequestInfo(0),
+		RateLimit(RateLimitConfig{ReadRate: 0.5, ReadBurst: 2, WriteRate: 0.25, WriteBurst: 1}),
+	)
+	send := func(method, target, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		remoteAddr     string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{"read", http.MethodGet, "/api/products", "192.0.2.1:1000", http.StatusOK, ""},
+		{"read burst", http.MethodHead, "/api/products", "192.0.2.1:1000", http.StatusOK, ""},
+		{"read throttled", http.MethodGet, "/api/products", "192.0.2.1:1000", http.StatusTooManyRequests, "2"},
+		{"write has its own bucket", http.MethodPost, "/api/products", "192.0.2.1:1000", http.StatusOK, ""},
+		{"write throttled", http.MethodDelete, "/api/products/1", "192.0.2.1:1000", http.StatusTooManyRequests, "4"},
+		{"other client", http.MethodGet, "/api/products", "192.0.2.2:1000", http.StatusOK, ""},
+		{"outside the API", http.MethodGet, "/health", "192.0.2.1:1000", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		w := send(tt.method, tt.target, tt.remoteAddr)
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: status %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+			t.Errorf("%s: Retry-After %q, want %q", tt.name, got, tt.wantRetryAfter)
+		}
+		if w.Code == http.StatusTooManyRequests {
+			if body := strings.TrimSpace(w.Body.String()); body != "Too Many Requests" {
+				t.Errorf("%s: body %q", tt.name, body)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+				t.Errorf("%s: Content-Type %q, want text/plain", tt.name, ct)
+			}
+		}
+	}
+}
+
+func TestRateLimitKeyAndUnlimited(t *testing.T) {
+	// Writes unlimited; reads counted per API key header, not per IP
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		RequestInfo(0),
+		RateLimit(RateLimitConfig{ReadRate: 1, ReadBurst: 1,
+			Key: func(r *http.Request) string { return r.Header.Get("X-Test-Key") }}),
+	)
+	send := func(method, key, remoteAddr string) int {
+		r := httptest.NewRequest(method, "/api/products", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Test-Key", key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		if code := send(http.MethodPost, "a", "192.0.2.1:1000"); code != http.StatusOK {
+			t.Fatalf("write %d: status %d, want unlimited", i+1, code)
+		}
+	}
+	send(http.MethodGet, "a", "192.0.2.1:1000")
+	if code := send(http.MethodGet, "a", "192.0.2.2:1000"); code != http.StatusTooManyRequests {
+		t.Errorf("same key from another IP: status %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := send(http.MethodGet, "b", "192.0.2.1:1000"); code != http.StatusOK {
+		t.Errorf("other key from the same IP: status %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRateLimiterDropsIdleClients(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(RateLimitConfig{ReadRate: 1, ReadBurst: 1})
+	l.take("idle", false, now)
+	l.take("active", false, now.Add(clientIdleTimeout))
+
+	l.take("active", false, now.Add(clientIdleTimeout+time.Minute))
+	if _, ok := l.clients["idle"]; ok {
+		t.Error("idle client kept")
+	}
+	if _, ok := l.clients["active"]; !ok {
+		t.Error("active client dropped")
+	}
+}
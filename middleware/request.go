@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader - Request and response header carrying the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - Longer incoming IDs are replaced by a generated one
+const maxRequestIDLength = 64
+
+type requestIDKey struct{}
+type clientIPKey struct{}
+
+// RequestInfo - Give every request an ID and resolve the client IP, both
+// recorded in the audit log. An incoming X-Request-ID is kept (so it can be
+// correlated with a proxy's logs), otherwise one is generated; either way it
+// is echoed in the response. The client IP is the peer address, or with
+// trustedHops proxies in front of the server the X-Forwarded-For address the
+// outermost one saw (trustedHops from the right; 0 ignores the header).
+// Entries further left are sent by the client and can be forged.
+func RequestInfo(trustedHops int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := strings.TrimSpace(r.Header.Get(RequestIDHeader))
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = context.WithValue(ctx, clientIPKey{}, clientIP(r, trustedHops))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestID - ID of the request, empty outside RequestInfo
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ClientIP - Address of the client, empty outside RequestInfo
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+func clientIP(r *http.Request, trustedHops int) string {
+	if trustedHops > 0 {
+		// Each proxy appends the address it received the request from;
+		// several headers count as one list
+		var forwarded []string
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			forwarded = append(forwarded, strings.Split(value, ",")...)
+		}
+		if len(forwarded) > 0 {
+			entry := forwarded[max(len(forwarded)-trustedHops, 0)]
+			if ip := net.ParseIP(strings.TrimSpace(entry)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// validRequestID - Non-empty, not too long and printable ASCII only
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestInfoClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		hops      int
+		forwarded []string
+		want      string
+	}{
+		{"proxy not trusted", 0, []string{"203.0.113.7"}, "192.0.2.1"},
+		{"no header", 1, nil, "192.0.2.1"},
+		{"one proxy", 1, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"forged entry ignored", 1, []string{"10.0.0.1, 203.0.113.7"}, "203.0.113.7"},
+		{"two proxies", 2, []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, "203.0.113.7"},
+		{"several headers", 2, []string{"10.0.0.1, 203.0.113.7", "198.51.100.2"}, "203.0.113.7"},
+		{"fewer entries than hops", 3, []string{"203.0.113.7, 198.51.100.2"}, "203.0.113.7"},
+		{"invalid entry", 1, []string{"10.0.0.1, unknown"}, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RequestInfo(tt.hops)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+			req.RemoteAddr = "192.0.2.1:4321"
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"cashier-api/models"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StoreHeader - Request header selecting the store (branch) a till works for
+const StoreHeader = "X-Store-ID"
+
+type storeKey struct{}
+
+// StoreChecker - Implemented by repositories.StoreRepository
+type StoreChecker interface {
+	Exists(id int) (bool, error)
+}
+
+// StoreContext - Put the store of the X-Store-ID header in the request
+// context. Requests without the header work on the central stock and default
+// prices; an invalid ID is rejected with 400 and an unknown store with 404.
+// An API key bound to a store selects it without the header, and a header or
+// store_id query parameter naming another store is rejected with 403. Runs
+// after Auth.
+func StoreContext(stores StoreChecker) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bound := 0
+			if key := APIKey(r.Context()); key != nil && key.StoreID != nil {
+				bound = *key.StoreID
+			}
+			if bound != 0 {
+				query := strings.TrimSpace(r.URL.Query().Get("store_id"))
+				if query != "" && query != strconv.Itoa(bound) {
+					http.Error(w, models.ErrStoreForbidden.Error(), http.StatusForbidden)
+					return
+				}
+			}
+
+			header := strings.TrimSpace(r.Header.Get(StoreHeader))
+			if header == "" {
+				if bound != 0 {
+					r = r.WithContext(context.WithValue(r.Context(), storeKey{}, bound))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			storeID, err := strconv.Atoi(header)
+			if err != nil || storeID <= 0 {
+				http.Error(w, models.ErrInvalidStoreID.Error(), http.StatusBadRequest)
+				return
+			}
+			if bound != 0 && storeID != bound {
+				http.Error(w, models.ErrStoreForbidden.Error(), http.StatusForbidden)
+				return
+			}
+
+			exists, err := stores.Exists(storeID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !exists {
+				http.Error(w, models.ErrStoreNotFound.Error(), http.StatusNotFound)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), storeKey{}, storeID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// StoreID - Store of the request, 0 when none was selected
+func StoreID(ctx context.Context) int {
+	storeID, _ := ctx.Value(storeKey{}).(int)
+	return storeID
+}
+
+// StoreAllowed - Whether the API key of the request may act for one of
+// storeIDs; always true without a key bound to a store
+func StoreAllowed(ctx context.Context, storeIDs ...int) bool {
+	key := APIKey(ctx)
+	if key == nil || key.StoreID == nil {
+		return true
+	}
+	return slices.Contains(storeIDs, *key.StoreID)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cashier-api/models"
+)
+
+type fakeStores map[int]bool
+
+func (f fakeStores) Exists(id int) (bool, error) { return f[id], nil }
+
+func TestStoreContextBoundKey(t *testing.T) {
+	two := 2
+	bound := &models.APIKey{Name: "till 2", Scopes: []string{models.ScopeProductsRead}, StoreID: &two}
+	unbound := &models.APIKey{Name: "sync", Scopes: []string{models.ScopeProductsRead}}
+
+	tests := []struct {
+		name      string
+		key       *models.APIKey
+		target    string
+		header    string
+		wantCode  int
+		wantStore int
+	}{
+		{"no key, no header", nil, "/api/products", "", http.StatusOK, 0},
+		{"no key, header", nil, "/api/products", "3", http.StatusOK, 3},
+		{"unknown store", nil, "/api/products", "9", http.StatusNotFound, 0},
+		{"unbound key, header", unbound, "/api/products", "3", http.StatusOK, 3},
+		{"bound key selects its store", bound, "/api/products", "", http.StatusOK, 2},
+		{"bound key, same header", bound, "/api/products", "2", http.StatusOK, 2},
+		{"bound key, other header", bound, "/api/products", "3", http.StatusForbidden, 0},
+		{"bound key, other query", bound, "/api/transfers?store_id=3", "", http.StatusForbidden, 0},
+		{"bound key, same query", bound, "/api/transfers?store_id=2", "", http.StatusOK, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotStore int
+			handler := StoreContext(fakeStores{2: true, 3: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotStore = StoreID(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set(StoreHeader, tt.header)
+			}
+			if tt.key != nil {
+				r = r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, tt.key))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode || gotStore != tt.wantStore {
+				t.Errorf("got %d with store %d, want %d with store %d", w.Code, gotStore, tt.wantCode, tt.wantStore)
+			}
+		})
+	}
+}
+
+func TestStoreAllowed(t *testing.T) {
+	two := 2
+	ctx := context.WithValue(context.Background(), apiKeyKey{}, &models.APIKey{StoreID: &two})
+
+	if !StoreAllowed(context.Background(), 5) {
+		t.Error("request without a key was refused")
+	}
+	if !StoreAllowed(ctx, 1, 2) {
+		t.Error("bound key refused for its own store")
+	}
+	if StoreAllowed(ctx, 1, 3) || StoreAllowed(ctx, 0) || StoreAllowed(ctx) {
+		t.Error("bound key allowed for other stores")
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// API key scopes: <resource>:read allows GET, <resource>:write every other
+// method. admin allows everything, including managing keys.
+const (
+	// Products, categories, variants, prices, images and the event stream
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	// Stores, stock transfers and stock takes
+	ScopeInventoryRead  = "inventory:read"
+	ScopeInventoryWrite = "inventory:write"
+	// Low stock, stock take reports and the audit log
+	ScopeReportsRead = "reports:read"
+	// Webhook subscriptions and deliveries
+	ScopeWebhooksRead  = "webhooks:read"
+	ScopeWebhooksWrite = "webhooks:write"
+
+	ScopeAdmin = "admin"
+)
+
+// APIKeyScopes - Every scope a key can be given
+var APIKeyScopes = []string{
+	ScopeProductsRead, ScopeProductsWrite, ScopeInventoryRead, ScopeInventoryWrite, ScopeReportsRead,
+	ScopeWebhooksRead, ScopeWebhooksWrite, ScopeAdmin,
+}
+
+// APIKey - Credential of a machine client (e.g. a shop sync job or BI tool).
+// Only a hash of the key is stored; Key is set once, in the response that
+// creates it.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // start of the key, to tell keys apart
+	Scopes     []string   `json:"scopes"`
+	StoreID    *int       `json:"store_id"` // bound to this store, nil = any store
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// HasScope - Whether the key may do what scope allows
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+// APIKeyInput - Body of POST /api/admin/api-keys
+type APIKeyInput struct {
+	Name    string   `json:"name"`
+	Scopes  []string `json:"scopes"`
+	StoreID *int     `json:"store_id"`
+}
+
+// API key errors
+var (
+	ErrAPIKeyNotFound   = errors.New("api key not found")
+	ErrAPIKeyName       = errors.New("name is required")
+	ErrAPIKeyScopes     = errors.New("scopes must list at least one scope")
+	ErrUnknownScope     = errors.New("unknown scope")
+	ErrAPIKeyRequired   = errors.New("API key required")
+	ErrInvalidAPIKey    = errors.New("invalid or revoked API key")
+	ErrAPIKeyForbidden  = errors.New("API key lacks scope")
+	ErrAdminKeyRequired = errors.New("admin API key or ADMIN_TOKEN required")
+	ErrStoreForbidden   = errors.New("API key is bound to another store")
+)
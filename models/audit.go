@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Audited entities
+const (
+	AuditEntityProduct  = "product"
+	AuditEntityCategory = "category"
+)
+
+// Audit actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete" // soft delete (archive)
+	AuditRestore = "restore"
+)
+
+// Actor - Who makes a change and from where, recorded in the audit log
+type Actor struct {
+	Name      string // API key and/or X-Actor header, empty without either
+	RequestID string // X-Request-ID of the request
+	IP        string // client address
+}
+
+// AuditEntry - One create, update or delete of a catalog entity. Before and
+// After only hold the fields that changed.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Action    string          `json:"action"`
+	Actor     *string         `json:"actor"`
+	RequestID *string         `json:"request_id"`
+	IP        *string         `json:"ip"`
+	StoreID   *int            `json:"store_id,omitempty"` // X-Store-ID of product writes
+	Before    json.RawMessage `json:"before"`             // null on create
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Number of audit entries returned when no limit is given, and at most
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditFilter - Query options for the audit log
+type AuditFilter struct {
+	Entity   string // empty = all
+	EntityID int    // 0 = all; requires Entity
+	Actor    string // empty = all
+	Limit    int    // newest entries returned
+}
+
+// Audit errors
+var (
+	ErrInvalidAuditEntity = errors.New("entity must be product or category")
+	ErrAuditIDWithoutType = errors.New("id requires entity")
+)
@@ -0,0 +1,60 @@
+package models
+
+import "errors"
+
+// Batch operation kinds
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// Batch execution modes
+const (
+	BatchAtomic     = "atomic"      // any failure rolls back every operation
+	BatchBestEffort = "best_effort" // failed operations are skipped, the rest is committed
+)
+
+// MaxBatchOperations - Upper bound of operations in one batch request
+const MaxBatchOperations = 1000
+
+// BatchOperation - One create/update/delete in POST /api/products/batch
+type BatchOperation struct {
+	Op      string   `json:"op"`
+	ID      int      `json:"id,omitempty"`      // update, delete
+	Version int      `json:"version,omitempty"` // update, delete (optional version check)
+	Product *Product `json:"product,omitempty"` // create, update
+}
+
+// BatchRequest - Body of POST /api/products/batch
+type BatchRequest struct {
+	Mode       string           `json:"mode"` // atomic (default) or best_effort
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperationResult - Outcome of one operation, in request order
+type BatchOperationResult struct {
+	Index   int      `json:"index"`
+	Op      string   `json:"op"`
+	ID      int      `json:"id,omitempty"`
+	OK      bool     `json:"ok"`
+	Product *Product `json:"product,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// BatchResult - Response of POST /api/products/batch
+type BatchResult struct {
+	Mode      string                 `json:"mode"`
+	Committed bool                   `json:"committed"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+var (
+	ErrInvalidBatchMode = errors.New("mode must be atomic or best_effort")
+	ErrEmptyBatch       = errors.New("operations must not be empty")
+	ErrBatchTooLarge    = errors.New("too many operations in one batch")
+	ErrInvalidBatchOp   = errors.New("op must be create, update or delete")
+	ErrProductRequired  = errors.New("product is required")
+)
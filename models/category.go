@@ -1,7 +1,29 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
 type Category struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	ParentID    *int       `json:"parent_id"` // nil for top-level categories
+	Version     int        `json:"version"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CategoryNode - Category with its subcategories, for GET /api/categories/tree
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
 }
+
+// Category hierarchy errors
+var (
+	ErrParentNotFound      = errors.New("parent category not found")
+	ErrCategoryCycle       = errors.New("category cannot be moved under itself or one of its subcategories")
+	ErrCategoryHasChildren = errors.New("cannot delete category that has subcategories")
+	ErrParentArchived      = errors.New("cannot restore category while its parent is deleted")
+)
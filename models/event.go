@@ -0,0 +1,19 @@
+package models
+
+import "errors"
+
+// StreamEvent - Data of an event pushed on GET /api/events
+type StreamEvent struct {
+	ProductID  int            `json:"product_id"`
+	CategoryID int            `json:"category_id"`
+	StoreID    *int           `json:"store_id"`          // store of the write, nil = central
+	Product    *ProductDetail `json:"product,omitempty"` // product.created, updated and restored, without variants and images
+	Stock      *Quantity      `json:"stock,omitempty"`   // stock.changed: stock after the change
+	Source     string         `json:"source,omitempty"`  // stock.changed
+}
+
+// Event stream errors
+var (
+	ErrInvalidLastEventID = errors.New("invalid Last-Event-ID")
+	ErrStreamUnsupported  = errors.New("streaming is not supported")
+)
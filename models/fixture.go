@@ -0,0 +1,60 @@
+package models
+
+import "errors"
+
+// Fixture - Catalog loaded by the seed command from a JSON or YAML file.
+// Categories and products refer to categories by name; entries that already
+// exist (categories and products by name, stores by code) are skipped.
+type Fixture struct {
+	Categories []FixtureCategory `json:"categories"`
+	Stores     []FixtureStore    `json:"stores"`
+	Products   []FixtureProduct  `json:"products"`
+}
+
+type FixtureCategory struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parent      string `json:"parent"` // name of a category listed before it or existing; empty = top level
+}
+
+type FixtureStore struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+type FixtureProduct struct {
+	Name            string           `json:"name"`
+	Price           int              `json:"price"`
+	Stock           Quantity         `json:"stock"`
+	Unit            string           `json:"unit"`
+	Category        string           `json:"category"`
+	ReorderLevel    *Quantity        `json:"reorder_level"`
+	ReorderQuantity Quantity         `json:"reorder_quantity"`
+	PackagingUnits  []PackagingUnit  `json:"packaging_units"`
+	OptionTypes     []string         `json:"option_types"`
+	Variants        []FixtureVariant `json:"variants"`
+}
+
+type FixtureVariant struct {
+	SKU     string            `json:"sku"`
+	Barcode *string           `json:"barcode"`
+	Options map[string]string `json:"options"`
+	Price   *int              `json:"price"`
+	Stock   Quantity          `json:"stock"`
+}
+
+// SeedResult - What the seed command created and skipped
+type SeedResult struct {
+	Categories int `json:"categories"`
+	Stores     int `json:"stores"`
+	Products   int `json:"products"`
+	Variants   int `json:"variants"`
+	Skipped    int `json:"skipped"` // categories, stores and products that already existed complete
+}
+
+// Seed errors
+var (
+	ErrInvalidFixture  = errors.New("invalid fixture file")
+	ErrFixtureCategory = errors.New("unknown category")
+)
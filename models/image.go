@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ProductImage - Uploaded photo of a product with a generated thumbnail
+type ProductImage struct {
+	ID           int       `json:"id"`
+	ProductID    int       `json:"product_id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Key / ThumbnailKey - Storage keys, URLs are derived from them
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
+
+// Image errors
+var (
+	ErrImageRequired   = errors.New("image file is required")
+	ErrImageTooLarge   = errors.New("image is too large")
+	ErrImageType       = errors.New("image must be a JPEG, PNG, GIF or WebP file")
+	ErrImageDimensions = errors.New("image dimensions are too large")
+	ErrImageNotFound   = errors.New("image not found")
+	ErrImageCorrupt    = errors.New("image file is corrupt or cannot be decoded")
+)
@@ -0,0 +1,20 @@
+package models
+
+import "errors"
+
+// ImportRowError - Validation errors of one data row (row 1 is the header)
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// ImportResult - Outcome of a bulk product import
+type ImportResult struct {
+	DryRun    bool             `json:"dry_run"`
+	TotalRows int              `json:"total_rows"`
+	ValidRows int              `json:"valid_rows"`
+	Created   int              `json:"created"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+var ErrInvalidImportFile = errors.New("invalid import file")
@@ -0,0 +1,35 @@
+package models
+
+// InventorySummary - Aggregated catalog figures exposed as business metrics
+type InventorySummary struct {
+	TotalProducts    int                 `json:"total_products"`
+	LowStockProducts int                 `json:"low_stock_products"`
+	StockByUnit      map[string]Quantity `json:"stock_by_unit"` // unit of measure -> stock of the products in it
+	CatalogValue     int64               `json:"catalog_value"`
+}
+
+// LowStockItem - Product at or below its reorder level with a reorder suggestion
+type LowStockItem struct {
+	ProductID       int      `json:"product_id"`
+	Name            string   `json:"name"`
+	CategoryID      int      `json:"category_id"`
+	CategoryName    string   `json:"category_name"`
+	Unit            string   `json:"unit"`
+	Stock           Quantity `json:"stock"`
+	ReorderLevel    Quantity `json:"reorder_level"` // effective level (product or default)
+	ReorderQuantity Quantity `json:"reorder_quantity"`
+
+	// SuggestedQuantity - Reorder quantity, or at least enough to get back to the level
+	SuggestedQuantity Quantity `json:"suggested_quantity"`
+
+	// StoreID / StoreName - Store whose stock is low, nil for the central stock
+	StoreID   *int    `json:"store_id"`
+	StoreName *string `json:"store_name"`
+}
+
+// LowStockGroup - Low-stock items of one category, for GET /api/inventory/low-stock
+type LowStockGroup struct {
+	CategoryID   int            `json:"category_id"`
+	CategoryName string         `json:"category_name"`
+	Items        []LowStockItem `json:"items"`
+}
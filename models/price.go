@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Price change sources
+const (
+	PriceSourceManual    = "manual"    // product update, patch or batch
+	PriceSourceScheduled = "scheduled" // applied by the price scheduler
+)
+
+// PriceChange - One recorded change of a product price
+type PriceChange struct {
+	ID               int       `json:"id"`
+	ProductID        int       `json:"product_id"`
+	OldPrice         int       `json:"old_price"`
+	NewPrice         int       `json:"new_price"`
+	ChangedBy        *string   `json:"changed_by"` // X-Actor of the request, nil when not sent
+	Source           string    `json:"source"`
+	ScheduledPriceID *int      `json:"scheduled_price_id,omitempty"`
+	StoreID          *int      `json:"store_id,omitempty"` // Only set for store price overrides
+	ChangedAt        time.Time `json:"changed_at"`
+}
+
+// ScheduledPrice - Future price applied automatically at EffectiveFrom
+type ScheduledPrice struct {
+	ID            int        `json:"id"`
+	ProductID     int        `json:"product_id"`
+	Price         int        `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	CreatedBy     *string    `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	AppliedAt     *time.Time `json:"applied_at,omitempty"`
+
+	// FailedAt / LastError - Set when the scheduler could not apply the
+	// price; it is not retried, cancel it and schedule it again
+	FailedAt  *time.Time `json:"failed_at,omitempty"`
+	LastError *string    `json:"last_error,omitempty"`
+}
+
+// ScheduledPriceInput - Request body of POST /api/products/{id}/scheduled-prices
+type ScheduledPriceInput struct {
+	Price         int       `json:"price"`
+	EffectiveFrom time.Time `json:"effective_from"` // RFC 3339, e.g. 2026-11-01T00:00:00+07:00
+}
+
+// Price errors
+var (
+	ErrEffectiveFromPast      = errors.New("effective_from must be in the future")
+	ErrScheduledPriceNotFound = errors.New("scheduled price not found")
+	ErrScheduledPriceExists   = errors.New("a price is already scheduled for this product at that time")
+	ErrScheduledPriceApplied  = errors.New("scheduled price has already been applied")
+)
@@ -1,32 +1,62 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // Product - Basic product structure for create/update
 type Product struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Price      int    `json:"price"`
-	Stock      int    `json:"stock"`
-	CategoryID int    `json:"category_id"`
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Price      int      `json:"price"` // per unit
+	Stock      Quantity `json:"stock"`
+	Unit       string   `json:"unit"` // pcs, kg, g, l or m; empty = pcs
+	CategoryID int      `json:"category_id"`
+	Version    int      `json:"version"`
+
+	// ReorderLevel - Low-stock threshold; nil = LOW_STOCK_THRESHOLD
+	ReorderLevel    *Quantity `json:"reorder_level"`
+	ReorderQuantity Quantity  `json:"reorder_quantity"` // suggested order size, 0 = refill to the level
 }
 
 // ProductList - For GET /api/products response (NO category)
 type ProductList struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Price int    `json:"price"`
-	Stock int    `json:"stock"`
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Price     int        `json:"price"`
+	Stock     Quantity   `json:"stock"`
+	Unit      string     `json:"unit"`
+	StoreID   *int       `json:"store_id,omitempty"`   // Only set when stock and price are of a store
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // Only set for archived products
 }
 
 // ProductDetail - For GET /api/products/{id} response (WITH category_name)
 type ProductDetail struct {
-	ID           int    `json:"id"`
-	Name         string `json:"name"`
-	Price        int    `json:"price"`
-	Stock        int    `json:"stock"`
-	CategoryID   int    `json:"category_id"`
-	CategoryName string `json:"category_name"` // Only in detail
+	ID              int             `json:"id"`
+	Name            string          `json:"name"`
+	Price           int             `json:"price"`
+	Stock           Quantity        `json:"stock"`
+	Unit            string          `json:"unit"`
+	CategoryID      int             `json:"category_id"`
+	CategoryName    string          `json:"category_name"` // Only in detail
+	ReorderLevel    *Quantity       `json:"reorder_level"`
+	ReorderQuantity Quantity        `json:"reorder_quantity"`
+	OptionTypes     []string        `json:"option_types"` // e.g. ["Size", "Color"]
+	PackagingUnits  []PackagingUnit `json:"packaging_units"`
+	Images          []ProductImage  `json:"images"`
+	Variants        []Variant       `json:"variants"`
+	StoreID         *int            `json:"store_id,omitempty"`      // Only set when stock and price are of a store
+	DefaultPrice    *int            `json:"default_price,omitempty"` // Price without the store override
+	Version         int             `json:"version"`
+	DeletedAt       *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// ProductFilter - Query options for the product list
+type ProductFilter struct {
+	IncludeDeleted bool
+	CategoryID     int    // 0 = all; otherwise the category and all its descendants
+	Barcode        string // only products having a variant with this barcode or SKU
 }
 
 // Validation errors
@@ -37,4 +67,9 @@ var (
 	ErrInvalidStock      = errors.New("stock cannot be negative")
 	ErrInvalidCategoryID = errors.New("invalid category ID")
 	ErrCategoryNotFound  = errors.New("category not found")
+	ErrInvalidPatch      = errors.New("invalid merge patch document")
+	ErrVersionMismatch   = errors.New("resource has been modified by another request")
+	ErrNotDeleted        = errors.New("resource is not deleted")
+	ErrCategoryArchived  = errors.New("cannot restore product while its category is deleted")
+	ErrInvalidReorder    = errors.New("reorder level and reorder quantity cannot be negative")
 )
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxQuantityPrecision - Decimal places stored by the NUMERIC stock columns
+const MaxQuantityPrecision = 6
+
+// quantityPrecision / quantityScale - Decimal places kept by Quantity, set
+// once at startup with SetQuantityPrecision
+var (
+	quantityPrecision       = 3
+	quantityScale     int64 = 1000
+)
+
+// Quantity - Fixed-point decimal amount (stock, counted or moved quantity).
+// The value is stored as an integer number of 1/10^precision units, so
+// quantities add and compare exactly. In JSON it is a plain number (2.5).
+type Quantity int64
+
+// Quantity errors
+var (
+	ErrInvalidQuantity   = errors.New("quantity must be a decimal number")
+	ErrQuantityPrecision = errors.New("quantity has more decimal places than allowed")
+	ErrQuantityOverflow  = errors.New("quantity is too large")
+)
+
+// SetQuantityPrecision - Configure the number of decimal places (0-6). Must be
+// called before any quantity is parsed.
+func SetQuantityPrecision(precision int) error {
+	if precision < 0 || precision > MaxQuantityPrecision {
+		return fmt.Errorf("quantity precision must be between 0 and %d", MaxQuantityPrecision)
+	}
+	quantityPrecision = precision
+	quantityScale = 1
+	for i := 0; i < precision; i++ {
+		quantityScale *= 10
+	}
+	return nil
+}
+
+// QuantityPrecision - Configured number of decimal places
+func QuantityPrecision() int {
+	return quantityPrecision
+}
+
+// NewQuantity - Quantity of whole units
+func NewQuantity(units int64) Quantity {
+	return Quantity(units * quantityScale)
+}
+
+// ParseQuantity - Parse a decimal string such as "12", "-0.5" or "1.250"
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return 0, ErrInvalidQuantity
+	}
+	return quantityFromRat(r)
+}
+
+// quantityFromRat - Exact conversion; fails when r needs more decimal places
+func quantityFromRat(r *big.Rat) (Quantity, error) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt64(quantityScale))
+	if !scaled.IsInt() {
+		return 0, ErrQuantityPrecision
+	}
+	n := scaled.Num()
+	if !n.IsInt64() {
+		return 0, ErrQuantityOverflow
+	}
+	return Quantity(n.Int64()), nil
+}
+
+func (q Quantity) rat() *big.Rat {
+	return big.NewRat(int64(q), quantityScale)
+}
+
+// IsWhole - Whether the quantity has no fractional part
+func (q Quantity) IsWhole() bool {
+	return int64(q)%quantityScale == 0
+}
+
+// Mul - q * factor, failing if the exact result cannot be represented
+func (q Quantity) Mul(factor Quantity) (Quantity, error) {
+	return quantityFromRat(new(big.Rat).Mul(q.rat(), factor.rat()))
+}
+
+// MulRatio - q * num / den, failing if the exact result cannot be represented
+func (q Quantity) MulRatio(num, den int64) (Quantity, error) {
+	return quantityFromRat(new(big.Rat).Mul(q.rat(), big.NewRat(num, den)))
+}
+
+// Cost - q * unitPrice rounded half away from zero to a whole currency unit
+func (q Quantity) Cost(unitPrice int) int64 {
+	n := new(big.Int).Mul(big.NewInt(int64(q)), big.NewInt(int64(unitPrice)))
+	half := big.NewInt(quantityScale / 2)
+	if n.Sign() < 0 {
+		n.Sub(n, half)
+	} else {
+		n.Add(n, half)
+	}
+	return n.Quo(n, big.NewInt(quantityScale)).Int64()
+}
+
+// Float64 - Approximate value, for metrics and spreadsheets
+func (q Quantity) Float64() float64 {
+	return float64(q) / float64(quantityScale)
+}
+
+// String - Decimal representation without trailing zeros ("2.5", "10")
+func (q Quantity) String() string {
+	s := q.rat().FloatString(quantityPrecision)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON - Accepts a JSON number or a numeric string
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// Scan - Read a NUMERIC column
+func (q *Quantity) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*q = 0
+		return nil
+	case int64:
+		*q = NewQuantity(v)
+		return nil
+	case float64:
+		parsed, err := ParseQuantity(strconv.FormatFloat(v, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+		*q = parsed
+		return nil
+	case []byte:
+		return q.scanString(string(v))
+	case string:
+		return q.scanString(v)
+	}
+	return fmt.Errorf("cannot scan %T into Quantity", src)
+}
+
+// scanString - Database values may carry more decimals than configured, as
+// long as the extra digits are zeros
+func (q *Quantity) scanString(s string) error {
+	parsed, err := ParseQuantity(s)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Quantity: %w", s, err)
+	}
+	*q = parsed
+	return nil
+}
+
+// Value - Store as a decimal string so NUMERIC columns keep the exact value
+func (q Quantity) Value() (driver.Value, error) {
+	return q.String(), nil
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// withPrecision - Run with the given number of decimal places, restoring the
+// default afterwards
+func withPrecision(t *testing.T, precision int) {
+	t.Helper()
+	previous := QuantityPrecision()
+	if err := SetQuantityPrecision(precision); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetQuantityPrecision(previous) })
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		precision int
+		in        string
+		want      Quantity
+		wantErr   error
+	}{
+		{3, "12", 12000, nil},
+		{3, " 2.5 ", 2500, nil},
+		{3, "1.250", 1250, nil},
+		{3, "0.001", 1, nil},
+		{3, "-0.5", -500, nil},
+		{3, "-12", -12000, nil},
+		{3, "0.0005", 0, ErrQuantityPrecision},
+		{3, "1.2340", 1234, nil},
+		{0, "3", 3, nil},
+		{0, "0.5", 0, ErrQuantityPrecision},
+		{6, "0.000001", 1, nil},
+		{6, "0.0000001", 0, ErrQuantityPrecision},
+		{3, "", 0, ErrInvalidQuantity},
+		{3, "abc", 0, ErrInvalidQuantity},
+		{3, "1,5", 0, ErrInvalidQuantity},
+		{3, "1/2", 0, ErrInvalidQuantity},
+		{3, "1e3", 0, ErrInvalidQuantity},
+		{3, "99999999999999999999", 0, ErrQuantityOverflow},
+	}
+
+	for _, tt := range tests {
+		withPrecision(t, tt.precision)
+		got, err := ParseQuantity(tt.in)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("ParseQuantity(%q) at precision %d = %d, %v; want %d, %v",
+				tt.in, tt.precision, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetQuantityPrecision(t *testing.T) {
+	withPrecision(t, 3)
+	for _, precision := range []int{-1, MaxQuantityPrecision + 1} {
+		if err := SetQuantityPrecision(precision); err == nil {
+			t.Errorf("precision %d accepted", precision)
+		}
+	}
+	if QuantityPrecision() != 3 {
+		t.Errorf("rejected precision changed it to %d", QuantityPrecision())
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	withPrecision(t, 3)
+	tests := []struct {
+		q    Quantity
+		want string
+	}{
+		{NewQuantity(10), "10"},
+		{2500, "2.5"},
+		{1, "0.001"},
+		{-750, "-0.75"},
+		{0, "0"},
+	}
+	for _, tt := range tests {
+		if got := tt.q.String(); got != tt.want {
+			t.Errorf("Quantity(%d) = %q, want %q", int64(tt.q), got, tt.want)
+		}
+	}
+}
+
+func TestQuantityCost(t *testing.T) {
+	withPrecision(t, 3)
+	tests := []struct {
+		q     string
+		price int
+		want  int64
+	}{
+		{"3", 3500, 10500},
+		{"2.5", 14000, 35000},
+		{"0.333", 1000, 333},
+		{"0.001", 500, 1},   // 0.5 rounds up
+		{"0.001", 499, 0},   // 0.499 rounds down
+		{"-0.001", 500, -1}, // half away from zero
+		{"-2.5", 3, -8},
+		{"0", 3500, 0},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuantity(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.Cost(tt.price); got != tt.want {
+			t.Errorf("%s x %d = %d, want %d", tt.q, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityMulRatio(t *testing.T) {
+	withPrecision(t, 3)
+	tests := []struct {
+		q        string
+		num, den int64
+		want     string
+		wantErr  error
+	}{
+		{"1.5", 1000, 1, "1500", nil}, // kg to g
+		{"750", 1, 1000, "0.75", nil}, // g to kg
+		{"1", 1, 1000, "0.001", nil},
+		{"1", 1, 10000, "", ErrQuantityPrecision},
+		{"10", 1, 3, "", ErrQuantityPrecision},
+		{"-2", 24, 1, "-48", nil},
+		{"9000000000000", 1000000, 1, "", ErrQuantityOverflow},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuantity(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.MulRatio(tt.num, tt.den)
+		if err != tt.wantErr || (err == nil && got.String() != tt.want) {
+			t.Errorf("%s x %d/%d = %s, %v; want %s, %v", tt.q, tt.num, tt.den, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	withPrecision(t, 3)
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{`2.5`, 2500, false},
+		{`"2.5"`, 2500, false},
+		{`null`, 0, false},
+		{`0.0001`, 0, true},
+		{`"two"`, 0, true},
+	}
+	for _, tt := range tests {
+		var q Quantity
+		err := q.UnmarshalJSON([]byte(tt.in))
+		if (err != nil) != tt.wantErr || q != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %d, %v", tt.in, q, err)
+		}
+	}
+	if data, _ := Quantity(2500).MarshalJSON(); string(data) != "2.5" {
+		t.Errorf("MarshalJSON = %s, want 2.5", data)
+	}
+}
+
+// TestQuantityNumeric - Values written to and read back from NUMERIC(18,6)
+// columns, which return up to six decimals as text
+func TestQuantityNumeric(t *testing.T) {
+	withPrecision(t, 3)
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Quantity
+		wantErr bool
+	}{
+		{"numeric text", []byte("2.500000"), 2500, false},
+		{"numeric string", "-0.750000", -750, false},
+		{"whole", []byte("12"), 12000, false},
+		{"integer", int64(12), 12000, false},
+		{"float", 2.5, 2500, false},
+		{"NULL", nil, 0, false},
+		{"more decimals than configured", []byte("0.000500"), 0, true},
+		{"not a number", []byte("abc"), 0, true},
+		{"unsupported type", true, 0, true},
+	}
+	for _, tt := range tests {
+		var q Quantity
+		err := q.Scan(tt.src)
+		if (err != nil) != tt.wantErr || q != tt.want {
+			t.Errorf("%s: Scan(%v) = %d, %v", tt.name, tt.src, q, err)
+		}
+	}
+
+	for _, in := range []string{"0", "0.001", "2.5", "-0.75", "999999999999.999"} {
+		q, err := ParseQuantity(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := q.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored := numeric(value.(string))
+		var back Quantity
+		if err := back.Scan([]byte(stored)); err != nil || back != q {
+			t.Errorf("%s: stored as %q, read back %s, %v", in, stored, back, err)
+		}
+	}
+}
+
+// numeric - Text of a NUMERIC(18,6) column holding the decimal s, which
+// Postgres pads to the column scale
+func numeric(s string) string {
+	whole, fraction, _ := strings.Cut(s, ".")
+	return whole + "." + fraction + strings.Repeat("0", 6-len(fraction))
+}
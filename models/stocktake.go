@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Stock take statuses
+const (
+	StocktakeOpen      = "open"
+	StocktakeFinalized = "finalized"
+)
+
+// Count modes of POST /api/stocktakes/{id}/counts
+const (
+	CountModeAdd = "add" // add to what other devices counted (default)
+	CountModeSet = "set" // replace the counted quantity (recount)
+)
+
+// Stocktake - Physical inventory count session. Expected quantities are a
+// snapshot of the product stock (of the store when StoreID is set) when the
+// session was opened.
+type Stocktake struct {
+	ID           int        `json:"id"`
+	Note         string     `json:"note"`
+	CategoryID   *int       `json:"category_id"` // nil = whole catalog, else the category subtree
+	StoreID      *int       `json:"store_id"`    // nil = the central stock
+	Status       string     `json:"status"`
+	ItemCount    int        `json:"item_count"`
+	CountedItems int        `json:"counted_items"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FinalizedAt  *time.Time `json:"finalized_at"`
+
+	Items []StocktakeItem `json:"items,omitempty"` // Only in detail
+}
+
+// StocktakeItem - Expected and counted quantity of one product
+type StocktakeItem struct {
+	ProductID    int        `json:"product_id"`
+	ProductName  string     `json:"product_name"`
+	CategoryID   int        `json:"category_id"`
+	CategoryName string     `json:"category_name"`
+	Unit         string     `json:"unit"`
+	Price        int        `json:"price"` // price when the session was opened
+	Expected     Quantity   `json:"expected"`
+	Counted      *Quantity  `json:"counted"` // nil = not counted yet
+	CountedAt    *time.Time `json:"counted_at"`
+
+	// Variance / VarianceValue - counted - expected, nil while not counted
+	Variance      *Quantity `json:"variance"`
+	VarianceValue *int64    `json:"variance_value"`
+
+	// Applied - Stock change made by finalizing, nil before. Clamped is set
+	// when the stock would have gone below zero, so less was taken than the
+	// variance.
+	Applied *Quantity `json:"applied"`
+	Clamped bool      `json:"clamped"`
+}
+
+// StocktakeInput - Body of POST /api/stocktakes
+type StocktakeInput struct {
+	Note       string `json:"note"`
+	CategoryID *int   `json:"category_id"`
+	StoreID    *int   `json:"store_id"`
+}
+
+// StocktakeCount - One counted quantity; Unit may be any unit the product
+// converts from (see StockAdjustment)
+type StocktakeCount struct {
+	ProductID int      `json:"product_id"`
+	Quantity  Quantity `json:"quantity"`
+	Unit      string   `json:"unit"`
+	Mode      string   `json:"mode"` // add (default) or set
+}
+
+// StocktakeCountsInput - Body of POST /api/stocktakes/{id}/counts
+type StocktakeCountsInput struct {
+	Counts []StocktakeCount `json:"counts"`
+}
+
+// VarianceSummary - Variance totals of a category or of the whole stock take
+type VarianceSummary struct {
+	Items         int   `json:"items"`
+	CountedItems  int   `json:"counted_items"`
+	ShortageValue int64 `json:"shortage_value"` // value of missing stock (<= 0)
+	SurplusValue  int64 `json:"surplus_value"`  // value of extra stock (>= 0)
+	NetValue      int64 `json:"net_value"`
+	ClampedItems  int   `json:"clamped_items"` // items finalized with less than the variance
+}
+
+// CategoryVariance - Variance of the items of one category
+type CategoryVariance struct {
+	CategoryID   int    `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	VarianceSummary
+	Items []StocktakeItem `json:"lines"`
+}
+
+// VarianceReport - Result of finalizing (or previewing) a stock take.
+// Uncounted items are left unchanged.
+type VarianceReport struct {
+	Stocktake  Stocktake          `json:"stocktake"`
+	Totals     VarianceSummary    `json:"totals"`
+	Categories []CategoryVariance `json:"categories"`
+	Clamped    []StocktakeItem    `json:"clamped"` // items whose stock stopped at zero
+}
+
+// Stock take errors
+var (
+	ErrStocktakeNotFound   = errors.New("stock take not found")
+	ErrStocktakeNotOpen    = errors.New("stock take is already finalized")
+	ErrStocktakeOpenExists = errors.New("another stock take is still open for this store")
+	ErrStocktakeProduct    = errors.New("product is not part of this stock take")
+	ErrInvalidCountMode    = errors.New("mode must be add or set")
+	ErrNegativeCount       = errors.New("counted quantity cannot be negative")
+	ErrEmptyCounts         = errors.New("counts must not be empty")
+)
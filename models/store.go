@@ -0,0 +1,23 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Store - Branch / outlet with its own stock and optional price overrides
+type Store struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"` // short unique code, e.g. "JKT-01"
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store errors
+var (
+	ErrStoreNotFound     = errors.New("store not found")
+	ErrStoreCodeRequired = errors.New("store code is required")
+	ErrStoreCodeExists   = errors.New("store code already exists")
+	ErrInvalidStoreID    = errors.New("invalid store ID")
+)
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Transfer statuses
+const (
+	TransferDraft    = "draft"    // lines can still be changed, no stock moved
+	TransferShipped  = "shipped"  // taken from the source store, in transit
+	TransferReceived = "received" // added to the destination store
+)
+
+// Transfer - Goods moved from one store to another
+type Transfer struct {
+	ID                   int        `json:"id"`
+	SourceStoreID        int        `json:"source_store_id"`
+	SourceStoreName      string     `json:"source_store_name"`
+	DestinationStoreID   int        `json:"destination_store_id"`
+	DestinationStoreName string     `json:"destination_store_name"`
+	Status               string     `json:"status"`
+	Note                 string     `json:"note"`
+	CreatedBy            *string    `json:"created_by"`
+	ShippedBy            *string    `json:"shipped_by"`
+	ReceivedBy           *string    `json:"received_by"`
+	CreatedAt            time.Time  `json:"created_at"`
+	ShippedAt            *time.Time `json:"shipped_at"`
+	ReceivedAt           *time.Time `json:"received_at"`
+
+	Lines []TransferLine `json:"lines,omitempty"` // Only in detail
+}
+
+// TransferLine - Quantity of one product on a transfer, in the product unit
+type TransferLine struct {
+	ProductID   int       `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Unit        string    `json:"unit"`
+	Quantity    Quantity  `json:"quantity"` // shipped quantity
+	Received    *Quantity `json:"received"` // nil until received
+
+	// Discrepancy - received - quantity; negative = missing, positive = more
+	// than shipped, nil until received
+	Discrepancy *Quantity `json:"discrepancy"`
+}
+
+// TransferLineInput - Quantity of a product in any unit it converts from
+// (see StockAdjustment)
+type TransferLineInput struct {
+	ProductID int      `json:"product_id"`
+	Quantity  Quantity `json:"quantity"`
+	Unit      string   `json:"unit"`
+}
+
+// TransferInput - Body of POST /api/transfers and PUT /api/transfers/{id}
+type TransferInput struct {
+	SourceStoreID      int                 `json:"source_store_id"`
+	DestinationStoreID int                 `json:"destination_store_id"`
+	Note               string              `json:"note"`
+	Lines              []TransferLineInput `json:"lines"`
+}
+
+// TransferReceiveInput - Body of POST /api/transfers/{id}/receive. Lines
+// give the quantities that actually arrived; products not listed arrived
+// complete.
+type TransferReceiveInput struct {
+	Lines []TransferLineInput `json:"lines"`
+}
+
+// TransferFilter - Query options for the transfer list
+type TransferFilter struct {
+	Status  string // empty = all
+	StoreID int    // 0 = all; otherwise transfers from or to the store
+}
+
+// Transfer errors
+var (
+	ErrTransferNotFound       = errors.New("transfer not found")
+	ErrTransferSameStore      = errors.New("source and destination store must differ")
+	ErrTransferEmpty          = errors.New("transfer must have at least one line")
+	ErrTransferQuantity       = errors.New("transfer quantity must be greater than 0")
+	ErrTransferDuplicateLine  = errors.New("product appears more than once on the transfer")
+	ErrTransferProduct        = errors.New("product is not part of this transfer")
+	ErrTransferNotDraft       = errors.New("only draft transfers can be changed, shipped or deleted")
+	ErrTransferNotShipped     = errors.New("only shipped transfers can be received")
+	ErrInvalidTransferStatus  = errors.New("status must be draft, shipped or received")
+	ErrNegativeReceived       = errors.New("received quantity cannot be negative")
+	ErrReceivedExceedsShipped = errors.New("received quantity cannot exceed the shipped quantity")
+)
@@ -0,0 +1,100 @@
+package models
+
+import "errors"
+
+// Units of measure. Stock and prices of a product are kept in its base unit
+// (price per piece, per kg, per liter, per meter).
+const (
+	UnitPieces   = "pcs"
+	UnitKilogram = "kg"
+	UnitGram     = "g"
+	UnitLiter    = "l"
+	UnitMeter    = "m"
+)
+
+// DefaultUnit - Unit of products created without one
+const DefaultUnit = UnitPieces
+
+// unitInfo - Dimension of a unit and its size in the smallest unit of that
+// dimension, used to convert between standard units (1 kg = 1000 g)
+type unitInfo struct {
+	dimension string
+	size      int64
+	countable bool
+}
+
+var units = map[string]unitInfo{
+	UnitPieces:   {dimension: "count", size: 1, countable: true},
+	UnitKilogram: {dimension: "mass", size: 1000},
+	UnitGram:     {dimension: "mass", size: 1},
+	UnitLiter:    {dimension: "volume", size: 1},
+	UnitMeter:    {dimension: "length", size: 1},
+}
+
+// Units - All supported units of measure
+func Units() []string {
+	return []string{UnitPieces, UnitKilogram, UnitGram, UnitLiter, UnitMeter}
+}
+
+// IsValidUnit - Whether unit is a supported unit of measure
+func IsValidUnit(unit string) bool {
+	_, ok := units[unit]
+	return ok
+}
+
+// IsCountUnit - Count-based units only allow whole quantities
+func IsCountUnit(unit string) bool {
+	return units[unit].countable
+}
+
+// ConvertUnit - Convert q from one standard unit to another of the same
+// dimension (e.g. 1500 g -> 1.5 kg)
+func ConvertUnit(q Quantity, from, to string) (Quantity, error) {
+	fromInfo, ok := units[from]
+	if !ok {
+		return 0, ErrInvalidUnit
+	}
+	toInfo, ok := units[to]
+	if !ok {
+		return 0, ErrInvalidUnit
+	}
+	if fromInfo.dimension != toInfo.dimension {
+		return 0, ErrIncompatibleUnit
+	}
+	if from == to {
+		return q, nil
+	}
+	return q.MulRatio(fromInfo.size, toInfo.size)
+}
+
+// PackagingUnit - Product specific unit made of a fixed number of base units,
+// e.g. a box of 24 pcs or a sack of 25 kg
+type PackagingUnit struct {
+	Name   string   `json:"name"`
+	Factor Quantity `json:"factor"` // base units per packaging unit
+}
+
+// PackagingUnitsInput - Body of PUT /api/products/{id}/units
+type PackagingUnitsInput struct {
+	Units []PackagingUnit `json:"units"`
+}
+
+// StockAdjustment - Body of POST /api/products/{id}/stock. Quantity is added
+// to the stock (negative to remove) after converting it from Unit, which may
+// be the product unit, a compatible standard unit or a packaging unit name.
+type StockAdjustment struct {
+	Quantity Quantity `json:"quantity"`
+	Unit     string   `json:"unit"` // empty = product unit
+}
+
+// Unit errors
+var (
+	ErrInvalidUnit          = errors.New("unit must be one of pcs, kg, g, l, m")
+	ErrUnknownUnit          = errors.New("unit must be a standard unit or a packaging unit of the product")
+	ErrIncompatibleUnit     = errors.New("unit cannot be converted to the product unit")
+	ErrFractionalQuantity   = errors.New("quantity must be a whole number for count-based units")
+	ErrInvalidPackagingUnit = errors.New("packaging units need a unique name that is not a standard unit and a factor greater than 0")
+	ErrZeroAdjustment       = errors.New("quantity must not be zero")
+	ErrInsufficientStock    = errors.New("not enough stock")
+	ErrUnitChangeWithStock  = errors.New("unit cannot be changed while the product has stock; adjust it to 0 first")
+)
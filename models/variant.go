@@ -0,0 +1,35 @@
+package models
+
+import "errors"
+
+// Variant - Sellable version of a product (e.g. T-Shirt size M, red) with its
+// own SKU, stock and optional price override
+type Variant struct {
+	ID        int               `json:"id"`
+	ProductID int               `json:"product_id"`
+	SKU       string            `json:"sku"`
+	Barcode   *string           `json:"barcode"`
+	Options   map[string]string `json:"options"` // option type -> value, e.g. {"Size": "M"}
+	Price     *int              `json:"price"`   // nil = use the product price
+	Stock     Quantity          `json:"stock"`   // in the product unit
+
+	// EffectivePrice - Price override or the parent product price (read only)
+	EffectivePrice int `json:"effective_price"`
+}
+
+// OptionTypesInput - Body of PUT /api/products/{id}/options
+type OptionTypesInput struct {
+	OptionTypes []string `json:"option_types"`
+}
+
+// Variant validation errors
+var (
+	ErrSKURequired         = errors.New("sku is required")
+	ErrDuplicateSKU        = errors.New("sku or barcode already in use")
+	ErrDuplicateVariant    = errors.New("a variant with the same options already exists")
+	ErrInvalidOptions      = errors.New("options must have a non-empty value for every option type of the product")
+	ErrInvalidOptionTypes  = errors.New("option types must be unique and non-empty")
+	ErrVariantsExist       = errors.New("cannot change option types while the product has variants")
+	ErrVariantNotFound     = errors.New("variant not found")
+	ErrInvalidVariantPrice = errors.New("variant price must be greater than 0")
+)
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Webhook event types
+const (
+	EventProductCreated  = "product.created"
+	EventProductUpdated  = "product.updated"
+	EventProductDeleted  = "product.deleted"
+	EventProductRestored = "product.restored"
+
+	EventCategoryCreated  = "category.created"
+	EventCategoryUpdated  = "category.updated"
+	EventCategoryDeleted  = "category.deleted"
+	EventCategoryRestored = "category.restored"
+
+	EventStockChanged = "stock.changed"
+)
+
+// WebhookEvents - Every event type a subscription can ask for
+var WebhookEvents = []string{
+	EventProductCreated, EventProductUpdated, EventProductDeleted, EventProductRestored,
+	EventCategoryCreated, EventCategoryUpdated, EventCategoryDeleted, EventCategoryRestored,
+	EventStockChanged,
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"   // waiting for its first or next attempt
+	DeliveryDelivered = "delivered" // the receiver answered 2xx
+	DeliveryDead      = "dead"      // gave up after the last attempt (dead letter)
+)
+
+// Sources of stock.changed events
+const (
+	StockSourceProduct   = "product"   // product create, update, batch, import or stock adjustment
+	StockSourceTransfer  = "transfer"  // shipped from or received into a store
+	StockSourceStocktake = "stocktake" // stock take finalized
+)
+
+// WebhookSubscription - Receiver of catalog and stock events. The secret
+// signs every delivery and is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Secret      string    `json:"secret,omitempty"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookSubscriptionInput - Body of POST /api/webhooks and PUT /api/webhooks/{id}
+type WebhookSubscriptionInput struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret"` // generated on create when empty, kept on update when empty
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"` // default true
+}
+
+// WebhookEvent - Body POSTed to the subscription URL
+type WebhookEvent struct {
+	ID        string          `json:"id"` // same for every subscription receiving the event
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CatalogEvent - Data of product.* and category.* events
+type CatalogEvent struct {
+	ID      int             `json:"id"`
+	StoreID *int            `json:"store_id,omitempty"` // X-Store-ID of product writes
+	Actor   *string         `json:"actor"`
+	Before  json.RawMessage `json:"before"`  // changed fields, null on create
+	After   json.RawMessage `json:"after"`   // changed fields
+	Current json.RawMessage `json:"current"` // the whole entity after the change
+}
+
+// StockEvent - Data of stock.changed events
+type StockEvent struct {
+	ProductID int      `json:"product_id"`
+	StoreID   *int     `json:"store_id"` // nil = central stock
+	Stock     Quantity `json:"stock"`    // stock after the change, in the product unit
+	Source    string   `json:"source"`
+}
+
+// WebhookDelivery - One event queued for one subscription (the outbox row)
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int             `json:"subscription_id"`
+	URL            string          `json:"url"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	LastStatus     *int            `json:"last_status"` // HTTP status of the last attempt, nil without a response
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+
+	Secret string `json:"-"` // Only set for claimed deliveries
+}
+
+// Number of deliveries listed when no limit is given, and at most
+const (
+	DefaultDeliveryLimit = 100
+	MaxDeliveryLimit     = 1000
+)
+
+// DeliveryFilter - Query options for delivery lists
+type DeliveryFilter struct {
+	SubscriptionID int    // 0 = all
+	Status         string // empty = all
+	Limit          int    // newest deliveries returned
+}
+
+// Webhook errors
+var (
+	ErrWebhookNotFound       = errors.New("webhook subscription not found")
+	ErrWebhookURL            = errors.New("url must be an absolute http or https URL")
+	ErrWebhookPrivateURL     = errors.New("url must not point to a loopback, private or link-local address")
+	ErrWebhookEvents         = errors.New("events must list at least one event type")
+	ErrUnknownWebhookEvent   = errors.New("unknown event type")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrDeliveryNotDead       = errors.New("only dead deliveries can be retried")
+	ErrInvalidDeliveryStatus = errors.New("status must be pending, delivered or dead")
+	ErrWebhookSecretTooShort = errors.New("secret must be at least 16 characters")
+)
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+
+	"cashier-api/config"
+	"cashier-api/docs"
+	"cashier-api/metrics"
+	"cashier-api/models"
+	"cashier-api/services"
+	"cashier-api/storage"
+)
+
+// fakeProducts - In-memory services.ProductRepository for the product
+// routes; the methods the tests do not reach panic
+type fakeProducts struct {
+	services.ProductRepository
+	products map[int]*models.ProductDetail
+}
+
+func (f *fakeProducts) ForStore(storeID int) services.ProductRepository { return f }
+func (f *fakeProducts) InvalidateCache()                                {}
+
+func (f *fakeProducts) GetAll(filter models.ProductFilter) ([]models.ProductList, error) {
+	var ids []int
+	for id := range f.products {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var list []models.ProductList
+	for _, id := range ids {
+		p := f.products[id]
+		list = append(list, models.ProductList{ID: p.ID, Name: p.Name, Price: p.Price, Stock: p.Stock, Unit: p.Unit})
+	}
+	return list, nil
+}
+
+func (f *fakeProducts) GetByID(id int, includeDeleted bool) (*models.ProductDetail, error) {
+	p, ok := f.products[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	detail := *p
+	return &detail, nil
+}
+
+func (f *fakeProducts) CheckCategoryExists(categoryID int) (bool, error) {
+	return categoryID == 1, nil
+}
+
+func (f *fakeProducts) Create(product *models.Product, actor models.Actor) error {
+	product.ID = len(f.products) + 1
+	product.Version = 1
+	f.products[product.ID] = &models.ProductDetail{
+		ID: product.ID, Name: product.Name, Price: product.Price, Stock: product.Stock, Unit: product.Unit,
+		CategoryID: product.CategoryID, CategoryName: "Food", Version: product.Version,
+		OptionTypes: []string{}, PackagingUnits: []models.PackagingUnit{},
+	}
+	return nil
+}
+
+func (f *fakeProducts) Update(product *models.Product, actor models.Actor) error {
+	p, ok := f.products[product.ID]
+	if !ok {
+		return errors.New("product not found")
+	}
+	if product.Version != 0 && product.Version != p.Version {
+		return models.ErrVersionMismatch
+	}
+	p.Name, p.Price, p.Stock, p.Unit, p.CategoryID = product.Name, product.Price, product.Stock, product.Unit, product.CategoryID
+	p.Version++
+	product.Version = p.Version
+	return nil
+}
+
+func (f *fakeProducts) Delete(id int, version int, actor models.Actor) error {
+	if _, ok := f.products[id]; !ok {
+		return errors.New("product not found")
+	}
+	delete(f.products, id)
+	return nil
+}
+
+// fakeCategories - In-memory services.CategoryRepository
+type fakeCategories struct {
+	services.CategoryRepository
+	categories map[int]*models.Category
+}
+
+func (f *fakeCategories) InvalidateCache() {}
+
+func (f *fakeCategories) GetAll(includeDeleted bool) ([]models.Category, error) {
+	var list []models.Category
+	for id := 1; id <= len(f.categories); id++ {
+		if c, ok := f.categories[id]; ok {
+			list = append(list, *c)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeCategories) GetByID(id int, includeDeleted bool) (*models.Category, error) {
+	c, ok := f.categories[id]
+	if !ok {
+		return nil, errors.New("category not found")
+	}
+	category := *c
+	return &category, nil
+}
+
+func (f *fakeCategories) Create(category *models.Category, actor models.Actor) error {
+	category.ID = len(f.categories) + 1
+	category.Version = 1
+	stored := *category
+	f.categories[category.ID] = &stored
+	return nil
+}
+
+type fakeVariants struct{ services.VariantRepository }
+
+func (fakeVariants) GetByProductID(productID int) ([]models.Variant, error) {
+	return []models.Variant{}, nil
+}
+
+type fakeImages struct {
+	services.ProductImageRepository
+}
+
+func (fakeImages) GetByProductID(productID int) ([]models.ProductImage, error) {
+	return []models.ProductImage{}, nil
+}
+
+// specRouter - Routes of openapi.json, after validating the document
+func specRouter(t *testing.T) routers.Router {
+	t.Helper()
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(docs.OpenAPISpec)
+	if err != nil {
+		t.Fatal("invalid openapi.json: ", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		t.Fatal("invalid openapi.json: ", err)
+	}
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+// TestResponsesMatchSpec - Product and category handlers answer with the
+// status codes, content types and bodies openapi.json declares, errors
+// (text/plain) included
+func TestResponsesMatchSpec(t *testing.T) {
+	spec := specRouter(t)
+
+	products := &fakeProducts{products: map[int]*models.ProductDetail{
+		1: {
+			ID: 1, Name: "Indomie Godog", Price: 3500, Stock: 10, Unit: "pcs", CategoryID: 1, CategoryName: "Food",
+			Version: 2, OptionTypes: []string{}, PackagingUnits: []models.PackagingUnit{},
+		},
+	}}
+	categories := &fakeCategories{categories: map[int]*models.Category{
+		1: {ID: 1, Name: "Food", Description: "Food and snacks", Version: 1},
+	}}
+	store, err := storage.NewLocal(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := services.NewProductImageService(fakeImages{}, products, store, 1<<20)
+	mux := newRouter(&app{
+		cfg:             &config.Config{},
+		metrics:         metrics.New(),
+		store:           store,
+		categoryService: services.NewCategoryService(categories),
+		productService:  services.NewProductService(products, categories, fakeVariants{}, images, nil),
+		imageService:    images,
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{"list products", http.MethodGet, "/api/products", "", nil, http.StatusOK},
+		{"invalid category filter", http.MethodGet, "/api/products?category_id=x", "", nil, http.StatusBadRequest},
+		{"product detail", http.MethodGet, "/api/products/1", "", nil, http.StatusOK},
+		{"product not found", http.MethodGet, "/api/products/99", "", nil, http.StatusNotFound},
+		{"invalid product ID", http.MethodGet, "/api/products/abc", "", nil, http.StatusBadRequest},
+		{"create product", http.MethodPost, "/api/products",
+			`{"name": "Teh Botol", "price": 4000, "stock": 24, "category_id": 1}`, nil, http.StatusCreated},
+		{"create product without name", http.MethodPost, "/api/products",
+			`{"price": 4000, "stock": 24, "category_id": 1}`, nil, http.StatusBadRequest},
+		{"create product in unknown category", http.MethodPost, "/api/products",
+			`{"name": "Teh Botol", "price": 4000, "stock": 24, "category_id": 9}`, nil, http.StatusNotFound},
+		{"create product with unknown field", http.MethodPost, "/api/products",
+			`{"name": "Teh Botol", "price": 4000, "category_id": 1, "colour": "red"}`, nil, http.StatusBadRequest},
+		{"update product", http.MethodPut, "/api/products/1",
+			`{"name": "Indomie Goreng", "price": 3600, "stock": 10, "category_id": 1}`,
+			map[string]string{"If-Match": `"2"`}, http.StatusOK},
+		{"update with stale If-Match", http.MethodPut, "/api/products/1",
+			`{"name": "Indomie Goreng", "price": 3600, "stock": 10, "category_id": 1}`,
+			map[string]string{"If-Match": `"1"`}, http.StatusPreconditionFailed},
+		{"patch product", http.MethodPatch, "/api/products/1", `{"price": 3700}`,
+			map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"3"`}, http.StatusOK},
+		{"patch with invalid document", http.MethodPatch, "/api/products/1", `[1]`,
+			map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"4"`}, http.StatusBadRequest},
+		{"delete unknown product", http.MethodDelete, "/api/products/99", "",
+			map[string]string{"If-Match": `"1"`}, http.StatusNotFound},
+		{"update without If-Match", http.MethodPut, "/api/products/1",
+			`{"name": "Indomie Goreng", "price": 3600, "stock": 10, "category_id": 1}`, nil, http.StatusPreconditionRequired},
+		{"list categories", http.MethodGet, "/api/categories", "", nil, http.StatusOK},
+		{"category", http.MethodGet, "/api/categories/1", "", nil, http.StatusOK},
+		{"category not found", http.MethodGet, "/api/categories/99", "", nil, http.StatusNotFound},
+		{"create category", http.MethodPost, "/api/categories",
+			`{"name": "Drinks", "description": "Cold drinks"}`, nil, http.StatusCreated},
+		{"create category without name", http.MethodPost, "/api/categories", `{"description": "x"}`,
+			nil, http.StatusBadRequest},
+		{"create category under unknown parent", http.MethodPost, "/api/categories",
+			`{"name": "Tea", "parent_id": 99}`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.target, body)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			validateResponse(t, spec, tt.method, tt.target, w)
+		})
+	}
+}
+
+// validateResponse - Check a recorded response against the operation of
+// the request in openapi.json
+func validateResponse(t *testing.T, spec routers.Router, method, target string, w *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	route, pathParams, err := spec.FindRoute(req)
+	if err != nil {
+		t.Fatalf("%s %s not in openapi.json: %v", method, target, err)
+	}
+
+	options := &openapi3filter.Options{IncludeResponseStatus: true, MultiError: true}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		},
+		Status:  w.Code,
+		Header:  w.Header(),
+		Body:    io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+		Options: options,
+	}
+	if err := openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+		t.Errorf("response does not match openapi.json: %v\n%s", err, w.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// apiKeyColumns - Columns scanned by scanAPIKey (without the hash)
+var apiKeyColumns = "id, name, prefix, scopes, store_id, created_at, last_used_at, revoked_at"
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.StoreID, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// GetAll - Every key, revoked ones included
+func (r *APIKeyRepository) GetAll() ([]models.APIKey, error) {
+	rows, err := r.db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) GetByID(id int) (*models.APIKey, error) {
+	row := r.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1", id)
+	k, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return k, nil
+}
+
+// GetByHash - Key with the given hash, revoked or not
+func (r *APIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	row := r.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash)
+	k, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return k, nil
+}
+
+// Create - Save a key under the hash of its secret
+func (r *APIKeyRepository) Create(key *models.APIKey, hash string) error {
+	query := `
+        INSERT INTO api_keys (name, prefix, key_hash, scopes, store_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `
+	return r.db.QueryRow(query, key.Name, key.Prefix, hash, pq.Array(key.Scopes), key.StoreID).Scan(&key.ID,
+		&key.CreatedAt)
+}
+
+// Revoke - Stop accepting a key. Revoking it again keeps the first time.
+func (r *APIKeyRepository) Revoke(id int) error {
+	result, err := r.db.Exec(
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed - Record that a key was used now
+func (r *APIKeyRepository) TouchLastUsed(id int) error {
+	_, err := r.db.Exec("UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"bytes"
+	"cashier-api/models"
+	"database/sql"
+	"encoding/json"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// GetAll - Audit entries matching the filter, newest first
+func (r *AuditRepository) GetAll(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := `
+        SELECT id, entity, entity_id, action, actor, request_id, ip, store_id, before, after, created_at
+        FROM audit_log
+        WHERE ($1 = '' OR entity = $1)
+          AND ($2 = 0 OR entity_id = $2)
+          AND ($3 = '' OR actor = $3 OR starts_with(actor, $3 || '/'))
+        ORDER BY created_at DESC, id DESC
+        LIMIT $4
+    `
+	rows, err := r.db.Query(query, filter.Entity, filter.EntityID, filter.Actor, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &e.Actor, &e.RequestID, &e.IP, &e.StoreID,
+			&before, &after, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// productSnapshot - Audited fields of a product as seen in a store (0 = the
+// central stock and default price). Locks the product row.
+func productSnapshot(q querier, id int, storeID int) ([]byte, error) {
+	query := `
+        SELECT (to_jsonb(p) - 'version' - 'updated_at' - 'low_stock_alerted_at')
+               || jsonb_build_object('packaging_units', COALESCE(
+                      (SELECT jsonb_object_agg(u.name, u.factor) FROM product_units u WHERE u.product_id = p.id),
+                      '{}'))
+               || jsonb_build_object('variants', COALESCE(
+                      (SELECT jsonb_object_agg(v.sku, jsonb_build_object(
+                          'barcode', v.barcode, 'options', v.options, 'price', v.price, 'stock', v.stock))
+                       FROM product_variants v WHERE v.product_id = p.id),
+                      '{}'))
+               || CASE WHEN $2 = 0 THEN '{}'::jsonb
+                       ELSE jsonb_build_object('store_stock', si.stock, 'store_price', si.price) END
+        FROM products p
+        ` + storeInventoryJoin("$2") + `
+        WHERE p.id = $1
+        FOR UPDATE OF p
+    `
+	var snapshot []byte
+	err := q.QueryRow(query, id, storeID).Scan(&snapshot)
+	return snapshot, err
+}
+
+// categorySnapshot - Audited fields of a category. Locks the category row.
+func categorySnapshot(q querier, id int) ([]byte, error) {
+	query := "SELECT to_jsonb(c) - 'version' - 'updated_at' FROM categories c WHERE c.id = $1 FOR UPDATE"
+	var snapshot []byte
+	err := q.QueryRow(query, id).Scan(&snapshot)
+	return snapshot, err
+}
+
+// auditProduct - Record a product write made by actor. before is the
+// snapshot taken before the write (nil on create).
+func auditProduct(q querier, action string, id int, storeID int, before []byte, actor models.Actor) error {
+	after, err := productSnapshot(q, id, storeID)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.AuditEntry{Entity: models.AuditEntityProduct, EntityID: id, Action: action}
+	if storeID != 0 {
+		entry.StoreID = &storeID
+	}
+	if err := recordAudit(q, entry, before, after, actor); err != nil {
+		return err
+	}
+
+	stockField := "stock"
+	if storeID != 0 {
+		stockField = "store_stock"
+	}
+	var changed map[string]json.RawMessage
+	if entry.After != nil {
+		if err := json.Unmarshal(entry.After, &changed); err != nil {
+			return err
+		}
+	}
+	if _, ok := changed[stockField]; !ok {
+		return nil
+	}
+	return publishStockChanged(q, id, storeID, models.StockSourceProduct)
+}
+
+// auditCategory - Record a category write made by actor. before is the
+// snapshot taken before the write (nil on create).
+func auditCategory(q querier, action string, id int, before []byte, actor models.Actor) error {
+	after, err := categorySnapshot(q, id)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.AuditEntry{Entity: models.AuditEntityCategory, EntityID: id, Action: action}
+	return recordAudit(q, entry, before, after, actor)
+}
+
+// recordAudit - Append entry to the audit log with the fields that differ
+// between the two snapshots and queue its webhook event. Updates that
+// changed nothing are not recorded.
+func recordAudit(q querier, entry *models.AuditEntry, before, after []byte, actor models.Actor) error {
+	var err error
+	entry.Before, entry.After, err = auditDiff(before, after)
+	if err != nil || entry.After == nil {
+		return err
+	}
+	entry.Actor = nullString(actor.Name)
+	entry.RequestID = nullString(actor.RequestID)
+	entry.IP = nullString(actor.IP)
+
+	var beforeParam interface{}
+	if entry.Before != nil {
+		beforeParam = string(entry.Before)
+	}
+
+	query := `
+        INSERT INTO audit_log (entity, entity_id, action, actor, request_id, ip, store_id, before, after)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, created_at
+    `
+	err = q.QueryRow(query, entry.Entity, entry.EntityID, entry.Action, entry.Actor, entry.RequestID, entry.IP,
+		entry.StoreID, beforeParam, string(entry.After)).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return publishCatalogEvent(q, entry, after)
+}
+
+// auditDiff - Reduce two JSON object snapshots to the fields that changed.
+// Without a before snapshot every field of after is kept. Both results are
+// nil when nothing changed.
+func auditDiff(before, after []byte) (json.RawMessage, json.RawMessage, error) {
+	if before == nil {
+		return nil, after, nil
+	}
+
+	var old, cur map[string]json.RawMessage
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(after, &cur); err != nil {
+		return nil, nil, err
+	}
+
+	oldChanged := map[string]json.RawMessage{}
+	curChanged := map[string]json.RawMessage{}
+	for field, value := range cur {
+		if previous, ok := old[field]; !ok || !bytes.Equal(previous, value) {
+			oldChanged[field] = orNull(previous)
+			curChanged[field] = value
+		}
+	}
+	for field, previous := range old {
+		if _, ok := cur[field]; !ok {
+			oldChanged[field] = previous
+			curChanged[field] = orNull(nil)
+		}
+	}
+	if len(curChanged) == 0 {
+		return nil, nil, nil
+	}
+
+	oldJSON, err := json.Marshal(oldChanged)
+	if err != nil {
+		return nil, nil, err
+	}
+	curJSON, err := json.Marshal(curChanged)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldJSON, curJSON, nil
+}
+
+// orNull - value, or JSON null for a field missing from a snapshot
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
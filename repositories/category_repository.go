@@ -1,22 +1,57 @@
 package repositories
 
 import (
+	"cashier-api/cache"
 	"cashier-api/models"
 	"database/sql"
 	"errors"
+	"fmt"
 )
 
+// categoryMoveLock - pg_advisory_xact_lock key serializing parent changes,
+// so that two concurrent moves cannot together create a cycle
+const categoryMoveLock = 7301
+
+// categorySubtreeQuery - Recursive CTE selecting the IDs of the category
+// given by the placeholder param (e.g. "$1") and all of its descendants.
+// UNION drops rows already seen, so the query ends even on a cyclic tree.
+func categorySubtreeQuery(param string) string {
+	return `
+        WITH RECURSIVE subtree AS (
+            SELECT id FROM categories WHERE id = ` + param + `
+            UNION
+            SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+        )
+        SELECT id FROM subtree`
+}
+
 type CategoryRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *cache.Cache // category lists; nil = disabled
+}
+
+func NewCategoryRepository(db *sql.DB, catalogCache *cache.Cache) *CategoryRepository {
+	return &CategoryRepository{db: db, cache: catalogCache}
 }
 
-func NewCategoryRepository(db *sql.DB) *CategoryRepository {
-	return &CategoryRepository{db: db}
+// InvalidateCache - Drop the cached category lists, and the product lists,
+// whose category filter covers the subtree of a category
+func (r *CategoryRepository) InvalidateCache() {
+	r.cache.Invalidate(cacheCategories)
+	r.cache.Invalidate(cacheProducts)
 }
 
-func (r *CategoryRepository) GetAll() ([]models.Category, error) {
-	query := "SELECT id, name, description FROM categories ORDER BY id"
-	rows, err := r.db.Query(query)
+// GetAll - Archived categories are only returned when includeDeleted is set
+func (r *CategoryRepository) GetAll(includeDeleted bool) ([]models.Category, error) {
+	key := fmt.Sprintf("deleted=%t", includeDeleted)
+	return cache.Fetch(r.cache, cacheCategories, key, func() ([]models.Category, error) {
+		return r.getAll(includeDeleted)
+	})
+}
+
+func (r *CategoryRepository) getAll(includeDeleted bool) ([]models.Category, error) {
+	query := "SELECT id, name, description, parent_id, version, deleted_at FROM categories WHERE ($1 OR deleted_at IS NULL) ORDER BY id"
+	rows, err := r.db.Query(query, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -25,7 +60,7 @@ func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
 	for rows.Next() {
 		var c models.Category
-		if err := rows.Scan(&c.ID, &c.Name, &c.Description); err != nil {
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.ParentID, &c.Version, &c.DeletedAt); err != nil {
 			return nil, err
 		}
 		categories = append(categories, c)
@@ -38,12 +73,12 @@ func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	return categories, nil
 }
 
-func (r *CategoryRepository) GetByID(id int) (*models.Category, error) {
-	query := "SELECT id, name, description FROM categories WHERE id = $1"
-	row := r.db.QueryRow(query, id)
+func (r *CategoryRepository) GetByID(id int, includeDeleted bool) (*models.Category, error) {
+	query := "SELECT id, name, description, parent_id, version, deleted_at FROM categories WHERE id = $1 AND ($2 OR deleted_at IS NULL)"
+	row := r.db.QueryRow(query, id, includeDeleted)
 
 	var c models.Category
-	err := row.Scan(&c.ID, &c.Name, &c.Description)
+	err := row.Scan(&c.ID, &c.Name, &c.Description, &c.ParentID, &c.Version, &c.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("category not found")
@@ -54,57 +89,272 @@ func (r *CategoryRepository) GetByID(id int) (*models.Category, error) {
 	return &c, nil
 }
 
-func (r *CategoryRepository) Create(category *models.Category) error {
-	query := "INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id"
-	return r.db.QueryRow(query, category.Name, category.Description).Scan(&category.ID)
+func (r *CategoryRepository) Create(category *models.Category, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if category.ParentID != nil {
+			if live, err := lockLiveCategory(tx, *category.ParentID); err != nil {
+				return err
+			} else if !live {
+				return models.ErrParentNotFound
+			}
+		}
+
+		query := "INSERT INTO categories (name, description, parent_id) VALUES ($1, $2, $3) RETURNING id, version"
+		err := tx.QueryRow(query, category.Name, category.Description, category.ParentID).Scan(&category.ID, &category.Version)
+		if err != nil {
+			return err
+		}
+		return auditCategory(tx, models.AuditCreate, category.ID, nil, actor)
+	})
+}
+
+// Update - category.Version set means the stored version must still match.
+// A new parent is checked for cycles inside the transaction.
+func (r *CategoryRepository) Update(category *models.Category, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := categorySnapshot(tx, category.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("category not found")
+			}
+			return err
+		}
+
+		if category.ParentID != nil {
+			if err := lockCategoryMove(tx, category.ID, *category.ParentID); err != nil {
+				return err
+			}
+		}
+
+		query := `
+            UPDATE categories
+            SET name = $1, description = $2, parent_id = $3,
+                version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $4 AND ($5 = 0 OR version = $5) AND deleted_at IS NULL
+            RETURNING version
+        `
+		err = tx.QueryRow(query, category.Name, category.Description, category.ParentID,
+			category.ID, category.Version).Scan(&category.Version)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return categoryMissingOrModified(tx, category.ID)
+			}
+			return err
+		}
+
+		return auditCategory(tx, models.AuditUpdate, category.ID, before, actor)
+	})
 }
 
-func (r *CategoryRepository) Update(category *models.Category) error {
-	query := "UPDATE categories SET name = $1, description = $2 WHERE id = $3"
-	result, err := r.db.Exec(query, category.Name, category.Description, category.ID)
+// Delete - Soft delete (archive) category. Version 0 skips the optimistic
+// concurrency check.
+func (r *CategoryRepository) Delete(id int, version int, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		// Lock the row first: products and subcategories written meanwhile
+		// share-lock it (lockLiveCategory) and wait, so the checks below
+		// stay true until the archive is committed
+		var locked int
+		lock := "SELECT id FROM categories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"
+		if err := tx.QueryRow(lock, id).Scan(&locked); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("category not found")
+			}
+			return err
+		}
+
+		before, err := categorySnapshot(tx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("category not found")
+			}
+			return err
+		}
+
+		// Check if category has any non-archived subcategories
+		var childCount int
+		childQuery := "SELECT COUNT(*) FROM categories WHERE parent_id = $1 AND deleted_at IS NULL"
+		err = tx.QueryRow(childQuery, id).Scan(&childCount)
+		if err != nil {
+			return err
+		}
+
+		if childCount > 0 {
+			return models.ErrCategoryHasChildren
+		}
+
+		// Check if the category or any descendant is used by non-archived products
+		var productCount int
+		checkQuery := `
+            SELECT COUNT(*) FROM products
+            WHERE deleted_at IS NULL AND category_id IN (` + categorySubtreeQuery("$1") + `)
+        `
+		err = tx.QueryRow(checkQuery, id).Scan(&productCount)
+		if err != nil {
+			return err
+		}
+
+		if productCount > 0 {
+			return errors.New("cannot delete category that has products")
+		}
+
+		query := `
+            UPDATE categories
+            SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+        `
+		result, err := tx.Exec(query, id, version)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return categoryMissingOrModified(tx, id)
+		}
+
+		return auditCategory(tx, models.AuditDelete, id, before, actor)
+	})
+}
+
+// Restore - Bring back an archived category. Its parent must not be archived.
+func (r *CategoryRepository) Restore(id int, actor models.Actor) (int, error) {
+	var version int
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		before, err := categorySnapshot(tx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("category not found")
+			}
+			return err
+		}
+
+		var parentArchived bool
+		parentQuery := `
+            SELECT EXISTS (
+                SELECT 1 FROM categories c
+                JOIN categories parent ON c.parent_id = parent.id
+                WHERE c.id = $1 AND parent.deleted_at IS NOT NULL
+            )
+        `
+		if err := tx.QueryRow(parentQuery, id).Scan(&parentArchived); err != nil {
+			return err
+		}
+		if parentArchived {
+			return models.ErrParentArchived
+		}
+
+		query := `
+            UPDATE categories
+            SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND deleted_at IS NOT NULL
+            RETURNING version
+        `
+		if err := tx.QueryRow(query, id).Scan(&version); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrNotDeleted
+			}
+			return err
+		}
+		return auditCategory(tx, models.AuditRestore, id, before, actor)
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return version, nil
+}
 
-	rowsAffected, err := result.RowsAffected()
+// IsDescendant - Reports whether id is ancestorID itself or lies below it
+func (r *CategoryRepository) IsDescendant(id int, ancestorID int) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM (" + categorySubtreeQuery("$1") + ") subtree WHERE id = $2)"
+	var descendant bool
+	err := r.db.QueryRow(query, ancestorID, id).Scan(&descendant)
 	if err != nil {
-		return err
+		return false, err
 	}
+	return descendant, nil
+}
 
-	if rowsAffected == 0 {
-		return errors.New("category not found")
+// GetSubtreeIDs - IDs of the category and all of its descendants
+func (r *CategoryRepository) GetSubtreeIDs(id int) ([]int, error) {
+	rows, err := r.db.Query(categorySubtreeQuery("$1"), id)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	var ids []int
+	for rows.Next() {
+		var categoryID int
+		if err := rows.Scan(&categoryID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, categoryID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
 }
 
-func (r *CategoryRepository) Delete(id int) error {
-	// Check if category is being used by any products
-	var productCount int
-	checkQuery := "SELECT COUNT(*) FROM products WHERE category_id = $1"
-	err := r.db.QueryRow(checkQuery, id).Scan(&productCount)
-	if err != nil {
+// lockCategoryMove - Prepare putting category id below parentID: wait for
+// other moves, lock the moved row and the parent, then check that the parent
+// is not the category itself or one of its descendants
+func lockCategoryMove(tx *sql.Tx, id int, parentID int) error {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", categoryMoveLock); err != nil {
 		return err
 	}
 
-	if productCount > 0 {
-		return errors.New("cannot delete category that has products")
+	var locked int
+	if err := tx.QueryRow("SELECT id FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&locked); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("category not found")
+		}
+		return err
 	}
-
-	query := "DELETE FROM categories WHERE id = $1"
-	result, err := r.db.Exec(query, id)
-	if err != nil {
+	if live, err := lockLiveCategory(tx, parentID); err != nil {
 		return err
+	} else if !live {
+		return models.ErrParentNotFound
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	var cycle bool
+	query := "SELECT EXISTS (SELECT 1 FROM (" + categorySubtreeQuery("$1") + ") subtree WHERE id = $2)"
+	if err := tx.QueryRow(query, id, parentID).Scan(&cycle); err != nil {
 		return err
 	}
+	if cycle {
+		return models.ErrCategoryCycle
+	}
+	return nil
+}
 
-	if rowsAffected == 0 {
-		return errors.New("category not found")
+// lockLiveCategory - Share-lock a category that is not archived until the
+// transaction ends, so that it cannot be archived while a row referencing it
+// is being written. Reports false when it is missing or archived.
+func lockLiveCategory(q querier, id int) (bool, error) {
+	var locked int
+	err := q.QueryRow("SELECT id FROM categories WHERE id = $1 AND deleted_at IS NULL FOR SHARE", id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
 	}
+	return err == nil, err
+}
 
-	return nil
+// categoryMissingOrModified - Explain why a versioned write touched no rows
+func categoryMissingOrModified(q querier, id int) error {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrVersionMismatch
+	}
+	return errors.New("category not found")
 }
@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type PriceRepository struct {
+	db *sql.DB
+}
+
+func NewPriceRepository(db *sql.DB) *PriceRepository {
+	return &PriceRepository{db: db}
+}
+
+// scheduledPriceColumns - Columns scanned by scanScheduledPrice
+var scheduledPriceColumns = "id, product_id, price, effective_from, created_by, created_at, applied_at, failed_at, last_error"
+
+func scanScheduledPrice(row rowScanner) (*models.ScheduledPrice, error) {
+	var s models.ScheduledPrice
+	err := row.Scan(&s.ID, &s.ProductID, &s.Price, &s.EffectiveFrom, &s.CreatedBy, &s.CreatedAt, &s.AppliedAt,
+		&s.FailedAt, &s.LastError)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetHistory - Price changes of a product, newest first
+func (r *PriceRepository) GetHistory(productID int) ([]models.PriceChange, error) {
+	query := `
+        SELECT id, product_id, old_price, new_price, changed_by, source, scheduled_price_id, store_id, changed_at
+        FROM product_price_history
+        WHERE product_id = $1
+        ORDER BY changed_at DESC, id DESC
+    `
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.PriceChange{}
+	for rows.Next() {
+		var c models.PriceChange
+		err := rows.Scan(&c.ID, &c.ProductID, &c.OldPrice, &c.NewPrice, &c.ChangedBy, &c.Source,
+			&c.ScheduledPriceID, &c.StoreID, &c.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// GetScheduled - Pending scheduled prices of a product, earliest first
+func (r *PriceRepository) GetScheduled(productID int) ([]models.ScheduledPrice, error) {
+	query := "SELECT " + scheduledPriceColumns + `
+        FROM product_scheduled_prices
+        WHERE product_id = $1 AND applied_at IS NULL
+        ORDER BY effective_from, id
+    `
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := []models.ScheduledPrice{}
+	for rows.Next() {
+		s, err := scanScheduledPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, *s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// CreateScheduled - Schedule a future price. A product can have only one
+// pending price per effective time.
+func (r *PriceRepository) CreateScheduled(scheduled *models.ScheduledPrice) error {
+	query := `
+        INSERT INTO product_scheduled_prices (product_id, price, effective_from, created_by)
+        VALUES ($1, $2, $3, $4)
+        RETURNING ` + scheduledPriceColumns
+	row := r.db.QueryRow(query, scheduled.ProductID, scheduled.Price, scheduled.EffectiveFrom, scheduled.CreatedBy)
+	created, err := scanScheduledPrice(row)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.ErrScheduledPriceExists
+		}
+		return err
+	}
+	*scheduled = *created
+	return nil
+}
+
+// DeleteScheduled - Cancel a pending scheduled price
+func (r *PriceRepository) DeleteScheduled(productID int, id int) error {
+	var applied bool
+	err := r.db.QueryRow(`
+        SELECT applied_at IS NOT NULL FROM product_scheduled_prices WHERE product_id = $1 AND id = $2
+    `, productID, id).Scan(&applied)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrScheduledPriceNotFound
+		}
+		return err
+	}
+	if applied {
+		return models.ErrScheduledPriceApplied
+	}
+
+	result, err := r.db.Exec(`
+        DELETE FROM product_scheduled_prices WHERE product_id = $1 AND id = $2 AND applied_at IS NULL
+    `, productID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		// Applied by the scheduler in the meantime
+		return models.ErrScheduledPriceApplied
+	}
+	return nil
+}
+
+// ApplyDue - Apply every scheduled price whose effective time has passed,
+// oldest first, and return the resulting price changes. Each price is applied
+// in its own transaction; SKIP LOCKED lets several instances run the
+// scheduler without applying a price twice. A price that cannot be applied
+// is marked failed and skipped from then on; the others are still applied
+// and the failures are returned together as the error.
+func (r *PriceRepository) ApplyDue() ([]models.PriceChange, error) {
+	var changes []models.PriceChange
+	var failures []error
+	for {
+		var scheduled *models.ScheduledPrice
+		var change *models.PriceChange
+		err := withTx(r.db, func(tx *sql.Tx) error {
+			row := tx.QueryRow(`
+                SELECT ` + scheduledPriceColumns + `
+                FROM product_scheduled_prices
+                WHERE applied_at IS NULL AND failed_at IS NULL AND effective_from <= CURRENT_TIMESTAMP
+                ORDER BY effective_from, id
+                LIMIT 1
+                FOR UPDATE SKIP LOCKED
+            `)
+			var err error
+			if scheduled, err = scanScheduledPrice(row); err != nil {
+				if err == sql.ErrNoRows {
+					return nil
+				}
+				return err
+			}
+
+			change, err = applyScheduledPrice(tx, scheduled)
+			return err
+		})
+		if err != nil {
+			if scheduled == nil {
+				failures = append(failures, err)
+				return changes, errors.Join(failures...)
+			}
+			if markErr := r.markScheduledFailed(scheduled.ID, err); markErr != nil {
+				failures = append(failures, markErr)
+				return changes, errors.Join(failures...)
+			}
+			failures = append(failures, fmt.Errorf("scheduled price %d of product %d: %w",
+				scheduled.ID, scheduled.ProductID, err))
+			continue
+		}
+		if scheduled == nil {
+			return changes, errors.Join(failures...)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+}
+
+// markScheduledFailed - Record why a scheduled price could not be applied,
+// so ApplyDue skips it
+func (r *PriceRepository) markScheduledFailed(id int, cause error) error {
+	query := `
+        UPDATE product_scheduled_prices
+        SET failed_at = CURRENT_TIMESTAMP, last_error = $1
+        WHERE id = $2 AND applied_at IS NULL
+    `
+	_, err := r.db.Exec(query, cause.Error(), id)
+	return err
+}
+
+// applyScheduledPrice - Set the product price and mark the schedule applied.
+// Archived products get the price too, so it is right when they are restored.
+// The audit log attributes the change to whoever scheduled the price.
+func applyScheduledPrice(tx *sql.Tx, scheduled *models.ScheduledPrice) (*models.PriceChange, error) {
+	var oldPrice int
+	err := tx.QueryRow("SELECT price FROM products WHERE id = $1 FOR UPDATE", scheduled.ProductID).Scan(&oldPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE product_scheduled_prices SET applied_at = CURRENT_TIMESTAMP WHERE id = $1",
+		scheduled.ID); err != nil {
+		return nil, err
+	}
+	if oldPrice == scheduled.Price {
+		return nil, nil
+	}
+	before, err := productSnapshot(tx, scheduled.ProductID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        UPDATE products
+        SET price = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+    `
+	if _, err := tx.Exec(query, scheduled.Price, scheduled.ProductID); err != nil {
+		return nil, err
+	}
+
+	change := &models.PriceChange{
+		ProductID:        scheduled.ProductID,
+		OldPrice:         oldPrice,
+		NewPrice:         scheduled.Price,
+		ChangedBy:        scheduled.CreatedBy,
+		Source:           models.PriceSourceScheduled,
+		ScheduledPriceID: &scheduled.ID,
+	}
+	if err := recordPriceChange(tx, change); err != nil {
+		return nil, err
+	}
+
+	var actor models.Actor
+	if scheduled.CreatedBy != nil {
+		actor.Name = *scheduled.CreatedBy
+	}
+	if err := auditProduct(tx, models.AuditUpdate, scheduled.ProductID, 0, before, actor); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// recordPriceChange - Append to the price history and set the ID and time of change
+func recordPriceChange(q querier, change *models.PriceChange) error {
+	query := `
+        INSERT INTO product_price_history (product_id, old_price, new_price, changed_by, source,
+                                           scheduled_price_id, store_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, changed_at
+    `
+	return q.QueryRow(query, change.ProductID, change.OldPrice, change.NewPrice, change.ChangedBy, change.Source,
+		change.ScheduledPriceID, change.StoreID).Scan(&change.ID, &change.ChangedAt)
+}
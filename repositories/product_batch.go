@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+	"fmt"
+)
+
+// ProductBatch - Product writes bound to a single database transaction
+type ProductBatch struct {
+	tx         *sql.Tx
+	actor      models.Actor
+	storeID    int
+	savepoints int
+}
+
+// RunBatch - Run fn in one transaction. Returning an error from fn rolls
+// back every write made through the batch. Writes are recorded in the price
+// history and audit log with actor.
+func (r *ProductRepository) RunBatch(actor models.Actor, fn func(b *ProductBatch) error) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		return fn(&ProductBatch{tx: tx, actor: actor, storeID: r.storeID})
+	})
+}
+
+func (b *ProductBatch) Create(product *models.Product) error {
+	return createProduct(b.tx, product, b.storeID, b.actor)
+}
+
+func (b *ProductBatch) Update(product *models.Product) error {
+	return updateProduct(b.tx, product, b.actor, b.storeID)
+}
+
+func (b *ProductBatch) Delete(id int, version int) error {
+	return deleteProduct(b.tx, id, version, b.storeID, b.actor)
+}
+
+func (b *ProductBatch) CheckCategoryExists(categoryID int) (bool, error) {
+	return checkCategoryExists(b.tx, categoryID)
+}
+
+// Savepoint - Run fn inside a savepoint so that its failure only undoes its
+// own writes and the rest of the transaction stays usable.
+func (b *ProductBatch) Savepoint(fn func() error) error {
+	b.savepoints++
+	name := fmt.Sprintf("batch_op_%d", b.savepoints)
+
+	if _, err := b.tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := b.tx.Exec("ROLLBACK TO SAVEPOINT " + name); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	_, err := b.tx.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+)
+
+type ProductImageRepository struct {
+	db *sql.DB
+}
+
+func NewProductImageRepository(db *sql.DB) *ProductImageRepository {
+	return &ProductImageRepository{db: db}
+}
+
+// GetByProductID - Images of a product in upload order (URLs not set)
+func (r *ProductImageRepository) GetByProductID(productID int) ([]models.ProductImage, error) {
+	query := `
+        SELECT id, product_id, storage_key, thumbnail_key, content_type, size, width, height, created_at
+        FROM product_images
+        WHERE product_id = $1
+        ORDER BY id
+    `
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []models.ProductImage{}
+	for rows.Next() {
+		var img models.ProductImage
+		err := rows.Scan(&img.ID, &img.ProductID, &img.Key, &img.ThumbnailKey, &img.ContentType,
+			&img.Size, &img.Width, &img.Height, &img.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Create - Record an uploaded image and bump the product version
+func (r *ProductImageRepository) Create(image *models.ProductImage) error {
+	query := `
+        INSERT INTO product_images (product_id, storage_key, thumbnail_key, content_type, size, width, height)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at
+    `
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, image.ProductID, image.Key, image.ThumbnailKey, image.ContentType,
+			image.Size, image.Width, image.Height).Scan(&image.ID, &image.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return touchProduct(tx, image.ProductID)
+	})
+}
+
+// Delete - Remove an image record and return it, so its files can be deleted
+func (r *ProductImageRepository) Delete(productID int, id int) (*models.ProductImage, error) {
+	query := `
+        DELETE FROM product_images
+        WHERE product_id = $1 AND id = $2
+        RETURNING id, product_id, storage_key, thumbnail_key, content_type, size, width, height, created_at
+    `
+	var img models.ProductImage
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, productID, id).Scan(&img.ID, &img.ProductID, &img.Key, &img.ThumbnailKey,
+			&img.ContentType, &img.Size, &img.Width, &img.Height, &img.CreatedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrImageNotFound
+			}
+			return err
+		}
+		return touchProduct(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
@@ -1,23 +1,114 @@
 package repositories
 
 import (
+	"cashier-api/cache"
 	"cashier-api/models"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// productFilterWhere - Conditions for models.ProductFilter on products alias p,
+// using the arguments returned by productFilterArgs
+var productFilterWhere = `
+        ($1 OR p.deleted_at IS NULL)
+        AND ($2 = 0 OR p.category_id IN (` + categorySubtreeQuery("$2") + `))
+        AND ($3 = '' OR p.id IN (
+            SELECT v.product_id FROM product_variants v WHERE v.barcode = $3 OR v.sku = $3
+        ))`
+
+// lowStockWhere - The stock expression (e.g. p.stock or si.stock) of product
+// p is at or below its reorder level; $1 is the default level for products
+// without one
+func lowStockWhere(stock string) string {
+	return stock + " <= COALESCE(p.reorder_level, $1)"
+}
+
+// lowStockColumns - Columns scanned by queryLowStock (products p JOIN
+// categories c) for the stock expression and the store id and name columns
+func lowStockColumns(stock string, store string) string {
+	return `
+        p.id, p.name, p.category_id, c.name, p.unit, ` + stock + `,
+        COALESCE(p.reorder_level, $1), p.reorder_quantity,
+        GREATEST(p.reorder_quantity, COALESCE(p.reorder_level, $1) - ` + stock + `), ` + store
+}
+
+// centralStore - lowStockColumns store columns of the central stock
+var centralStore = "NULL::INTEGER, NULL::TEXT"
+
+func productFilterArgs(filter models.ProductFilter) []interface{} {
+	return []interface{}{filter.IncludeDeleted, filter.CategoryID, filter.Barcode}
+}
+
+// storeInventoryJoin - Stock and price override of products p in the store
+// given by the placeholder param (store_inventory si). Store 0 never
+// matches, so the central stock and default price apply.
+func storeInventoryJoin(param string) string {
+	return "LEFT JOIN store_inventory si ON si.product_id = p.id AND si.store_id = " + param
+}
+
+// storeStock - Stock of products p in the store of storeInventoryJoin
+func storeStock(param string) string {
+	return "CASE WHEN " + param + " = 0 THEN p.stock ELSE COALESCE(si.stock, 0) END"
+}
+
+// storePrice - Price override of the store, or the default price
+var storePrice = "COALESCE(si.price, p.price)"
+
+// ProductRepository - storeID scopes stock and price to one store (0 = the
+// central stock and default prices)
 type ProductRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	storeID int
+	cache   *cache.Cache // product lists; nil = disabled
+}
+
+func NewProductRepository(db *sql.DB, catalogCache *cache.Cache) *ProductRepository {
+	return &ProductRepository{db: db, cache: catalogCache}
+}
+
+// ForStore - Repository reading and writing the stock and prices of a store
+func (r *ProductRepository) ForStore(storeID int) *ProductRepository {
+	return &ProductRepository{db: r.db, storeID: storeID, cache: r.cache}
 }
 
-func NewProductRepository(db *sql.DB) *ProductRepository {
-	return &ProductRepository{db: db}
+// InvalidateCache - Drop the cached product lists of every store; called
+// after writes that change a name, price, stock or barcode
+func (r *ProductRepository) InvalidateCache() {
+	r.cache.Invalidate(cacheProducts)
 }
 
-// GetAll - Get all products WITHOUT category info
-func (r *ProductRepository) GetAll() ([]models.ProductList, error) {
-	query := "SELECT id, name, price, stock FROM products ORDER BY id"
-	rows, err := r.db.Query(query)
+// store - Store in scope for JSON responses, nil for the central view
+func (r *ProductRepository) store() *int {
+	if r.storeID == 0 {
+		return nil
+	}
+	storeID := r.storeID
+	return &storeID
+}
+
+// GetAll - Get all products WITHOUT category info (archived ones only on request)
+func (r *ProductRepository) GetAll(filter models.ProductFilter) ([]models.ProductList, error) {
+	key := fmt.Sprintf("store=%d:deleted=%t:category=%d:barcode=%s",
+		r.storeID, filter.IncludeDeleted, filter.CategoryID, filter.Barcode)
+	return cache.Fetch(r.cache, cacheProducts, key, func() ([]models.ProductList, error) {
+		return r.getAll(filter)
+	})
+}
+
+func (r *ProductRepository) getAll(filter models.ProductFilter) ([]models.ProductList, error) {
+	query := `
+        SELECT p.id, p.name, ` + storePrice + `, ` + storeStock("$4") + `, p.unit, p.deleted_at
+        FROM products p
+        ` + storeInventoryJoin("$4") + `
+        WHERE ` + productFilterWhere + `
+        ORDER BY p.id
+    `
+	rows, err := r.db.Query(query, append(productFilterArgs(filter), r.storeID)...)
 	if err != nil {
 		return nil, err
 	}
@@ -26,9 +117,10 @@ func (r *ProductRepository) GetAll() ([]models.ProductList, error) {
 	var products []models.ProductList
 	for rows.Next() {
 		var p models.ProductList
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &p.Unit, &p.DeletedAt); err != nil {
 			return nil, err
 		}
+		p.StoreID = r.store()
 		products = append(products, p)
 	}
 
@@ -40,20 +132,25 @@ func (r *ProductRepository) GetAll() ([]models.ProductList, error) {
 }
 
 // GetByID - Get product by ID WITH category name (JOIN)
-func (r *ProductRepository) GetByID(id int) (*models.ProductDetail, error) {
+func (r *ProductRepository) GetByID(id int, includeDeleted bool) (*models.ProductDetail, error) {
 	query := `
-        SELECT p.id, p.name, p.price, p.stock, p.category_id, 
-               c.name as category_name
+        SELECT p.id, p.name, ` + storePrice + `, p.price, ` + storeStock("$3") + `, p.unit, p.category_id,
+               c.name as category_name, p.reorder_level, p.reorder_quantity,
+               p.option_types, p.version, p.deleted_at
         FROM products p
         LEFT JOIN categories c ON p.category_id = c.id
-        WHERE p.id = $1
+        ` + storeInventoryJoin("$3") + `
+        WHERE p.id = $1 AND ($2 OR p.deleted_at IS NULL)
     `
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRow(query, id, includeDeleted, r.storeID)
 
 	var product models.ProductDetail
+	var optionTypes []byte
+	var defaultPrice int
 
-	err := row.Scan(&product.ID, &product.Name, &product.Price, &product.Stock,
-		&product.CategoryID, &product.CategoryName)
+	err := row.Scan(&product.ID, &product.Name, &product.Price, &defaultPrice, &product.Stock, &product.Unit,
+		&product.CategoryID, &product.CategoryName, &product.ReorderLevel, &product.ReorderQuantity,
+		&optionTypes, &product.Version, &product.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("product not found")
@@ -61,19 +158,256 @@ func (r *ProductRepository) GetByID(id int) (*models.ProductDetail, error) {
 		return nil, err
 	}
 
+	if err := json.Unmarshal(optionTypes, &product.OptionTypes); err != nil {
+		return nil, err
+	}
+
+	if product.PackagingUnits, err = r.GetPackagingUnits(id); err != nil {
+		return nil, err
+	}
+
+	if product.StoreID = r.store(); product.StoreID != nil {
+		product.DefaultPrice = &defaultPrice
+	}
+
 	return &product, nil
 }
 
-// Create - Create new product
-func (r *ProductRepository) Create(product *models.Product) error {
-	query := "INSERT INTO products (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id"
-	return r.db.QueryRow(query, product.Name, product.Price, product.Stock, product.CategoryID).Scan(&product.ID)
+// GetStocks - Stock of the given products (archived ones included) by ID
+func (r *ProductRepository) GetStocks(ids []int) (map[int]models.Quantity, error) {
+	query := `
+        SELECT p.id, ` + storeStock("$2") + `
+        FROM products p
+        ` + storeInventoryJoin("$2") + `
+        WHERE p.id = ANY($1)
+    `
+	rows, err := r.db.Query(query, pq.Array(ids), r.storeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := map[int]models.Quantity{}
+	for rows.Next() {
+		var id int
+		var stock models.Quantity
+		if err := rows.Scan(&id, &stock); err != nil {
+			return nil, err
+		}
+		stocks[id] = stock
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
+// GetAllWithCategory - Get all products WITH category name, used for export
+func (r *ProductRepository) GetAllWithCategory(filter models.ProductFilter) ([]models.ProductDetail, error) {
+	query := `
+        SELECT p.id, p.name, ` + storePrice + `, ` + storeStock("$4") + `, p.unit, p.category_id,
+               c.name as category_name, p.version, p.deleted_at
+        FROM products p
+        LEFT JOIN categories c ON p.category_id = c.id
+        ` + storeInventoryJoin("$4") + `
+        WHERE ` + productFilterWhere + `
+        ORDER BY p.id
+    `
+	rows, err := r.db.Query(query, append(productFilterArgs(filter), r.storeID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.ProductDetail
+	for rows.Next() {
+		var p models.ProductDetail
+		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &p.Unit, &p.CategoryID,
+			&p.CategoryName, &p.Version, &p.DeletedAt); err != nil {
+			return nil, err
+		}
+		p.StoreID = r.store()
+		products = append(products, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// Create - Create new product. In a store the stock is the store stock.
+func (r *ProductRepository) Create(product *models.Product, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		return createProduct(tx, product, r.storeID, actor)
+	})
+}
+
+// CreateMany - Create all products in one transaction (all-or-nothing)
+func (r *ProductRepository) CreateMany(products []models.Product, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		for i := range products {
+			if err := createProduct(tx, &products[i], r.storeID, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func createProduct(q querier, product *models.Product, storeID int, actor models.Actor) error {
+	// Stock created in a store belongs to that store, the central stock starts at zero
+	centralStock := product.Stock
+	if storeID != 0 {
+		centralStock = 0
+	}
+
+	if live, err := lockLiveCategory(q, product.CategoryID); err != nil {
+		return err
+	} else if !live {
+		return models.ErrCategoryNotFound
+	}
+
+	query := `
+        INSERT INTO products (name, price, stock, unit, category_id, reorder_level, reorder_quantity)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, version
+    `
+	err := q.QueryRow(query, product.Name, product.Price, centralStock, product.Unit, product.CategoryID,
+		product.ReorderLevel, product.ReorderQuantity).Scan(&product.ID, &product.Version)
+	if err != nil {
+		return err
+	}
+	if storeID != 0 {
+		if err := setStoreInventory(q, storeID, product.ID, product.Stock, nil); err != nil {
+			return err
+		}
+	}
+	return auditProduct(q, models.AuditCreate, product.ID, storeID, nil, actor)
+}
+
+// Update - Update product. When product.Version is set the update only
+// succeeds if the stored version still matches (optimistic concurrency).
+// A price change is recorded in the price history with the actor. In a
+// store the stock and price are written to the store inventory.
+func (r *ProductRepository) Update(product *models.Product, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		return updateProduct(tx, product, actor, r.storeID)
+	})
 }
 
-// Update - Update product
-func (r *ProductRepository) Update(product *models.Product) error {
-	query := "UPDATE products SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5"
-	result, err := r.db.Exec(query, product.Name, product.Price, product.Stock, product.CategoryID, product.ID)
+func updateProduct(q querier, product *models.Product, actor models.Actor, storeID int) error {
+	// Lock the row so the recorded old price is the one being replaced
+	var centralPrice, oldPrice int
+	var centralStock models.Quantity
+	var oldUnit string
+	lock := `
+        SELECT p.price, p.stock, p.unit, ` + storePrice + `
+        FROM products p
+        ` + storeInventoryJoin("$2") + `
+        WHERE p.id = $1 AND p.deleted_at IS NULL
+        FOR UPDATE OF p
+    `
+	err := q.QueryRow(lock, product.ID, storeID).Scan(&centralPrice, &centralStock, &oldUnit, &oldPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("product not found")
+		}
+		return err
+	}
+	if product.Unit != oldUnit {
+		if err := checkNoStock(q, product.ID); err != nil {
+			return err
+		}
+	}
+	before, err := productSnapshot(q, product.ID, storeID)
+	if err != nil {
+		return err
+	}
+	if live, err := lockLiveCategory(q, product.CategoryID); err != nil {
+		return err
+	} else if !live {
+		return models.ErrCategoryNotFound
+	}
+
+	// In a store the central stock and default price stay as they are
+	price, stock := product.Price, product.Stock
+	if storeID != 0 {
+		price, stock = centralPrice, centralStock
+	}
+
+	query := `
+        UPDATE products
+        SET name = $1, price = $2, stock = $3, unit = $4, category_id = $5,
+            reorder_level = $6, reorder_quantity = $7,
+            version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $8 AND ($9 = 0 OR version = $9) AND deleted_at IS NULL
+        RETURNING version
+    `
+	err = q.QueryRow(query, product.Name, price, stock, product.Unit, product.CategoryID,
+		product.ReorderLevel, product.ReorderQuantity, product.ID, product.Version).Scan(&product.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return productMissingOrModified(q, product.ID)
+		}
+		return err
+	}
+
+	if storeID != 0 {
+		// A price equal to the default price removes the override
+		var override *int
+		if product.Price != centralPrice {
+			override = &product.Price
+		}
+		if err := setStoreInventory(q, storeID, product.ID, product.Stock, override); err != nil {
+			return err
+		}
+	}
+
+	if oldPrice != product.Price {
+		change := &models.PriceChange{
+			ProductID: product.ID,
+			OldPrice:  oldPrice,
+			NewPrice:  product.Price,
+			ChangedBy: nullString(actor.Name),
+			Source:    models.PriceSourceManual,
+		}
+		if storeID != 0 {
+			change.StoreID = &storeID
+		}
+		if err := recordPriceChange(q, change); err != nil {
+			return err
+		}
+	}
+	return auditProduct(q, models.AuditUpdate, product.ID, storeID, before, actor)
+}
+
+// Delete - Soft delete (archive) product. Version 0 skips the optimistic
+// concurrency check.
+func (r *ProductRepository) Delete(id int, version int, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		return deleteProduct(tx, id, version, r.storeID, actor)
+	})
+}
+
+func deleteProduct(q querier, id int, version int, storeID int, actor models.Actor) error {
+	before, err := productSnapshot(q, id, storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("product not found")
+		}
+		return err
+	}
+
+	query := `
+        UPDATE products
+        SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+    `
+	result, err := q.Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -84,16 +418,222 @@ func (r *ProductRepository) Update(product *models.Product) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("product not found")
+		return productMissingOrModified(q, id)
 	}
 
+	return auditProduct(q, models.AuditDelete, id, storeID, before, actor)
+}
+
+// Restore - Bring back an archived product. Its category must not be archived.
+func (r *ProductRepository) Restore(id int, actor models.Actor) (int, error) {
+	var version int
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		var deletedAt *time.Time
+		var categoryDeleted bool
+		checkQuery := `
+            SELECT p.deleted_at, c.deleted_at IS NOT NULL
+            FROM products p
+            JOIN categories c ON p.category_id = c.id
+            WHERE p.id = $1
+            FOR UPDATE OF p
+        `
+		err := tx.QueryRow(checkQuery, id).Scan(&deletedAt, &categoryDeleted)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("product not found")
+			}
+			return err
+		}
+		if deletedAt == nil {
+			return models.ErrNotDeleted
+		}
+		if categoryDeleted {
+			return models.ErrCategoryArchived
+		}
+
+		before, err := productSnapshot(tx, id, r.storeID)
+		if err != nil {
+			return err
+		}
+
+		query := `
+            UPDATE products
+            SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND deleted_at IS NOT NULL
+            RETURNING version
+        `
+		if err := tx.QueryRow(query, id).Scan(&version); err != nil {
+			return err
+		}
+		return auditProduct(tx, models.AuditRestore, id, r.storeID, before, actor)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// checkNoStock - Stock, store stock and variant stock are kept in the base
+// unit, so the unit of a product can only change while it holds none. The
+// product row must be locked by the caller.
+func checkNoStock(q querier, productID int) error {
+	var hasStock bool
+	query := `
+        SELECT p.stock <> 0
+            OR EXISTS (SELECT 1 FROM store_inventory si WHERE si.product_id = p.id AND si.stock <> 0)
+            OR EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.stock <> 0)
+        FROM products p
+        WHERE p.id = $1
+    `
+	if err := q.QueryRow(query, productID).Scan(&hasStock); err != nil {
+		return err
+	}
+	if hasStock {
+		return models.ErrUnitChangeWithStock
+	}
 	return nil
 }
 
-// Delete - Delete product
-func (r *ProductRepository) Delete(id int) error {
-	query := "DELETE FROM products WHERE id = $1"
-	result, err := r.db.Exec(query, id)
+// productMissingOrModified - Explain why a versioned write touched no rows
+func productMissingOrModified(q querier, id int) error {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrVersionMismatch
+	}
+	return errors.New("product not found")
+}
+
+// SetOptionTypes - Replace the option types (e.g. Size, Color) of a product
+func (r *ProductRepository) SetOptionTypes(id int, optionTypes []string, actor models.Actor) error {
+	encoded, err := json.Marshal(optionTypes)
+	if err != nil {
+		return err
+	}
+
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := productSnapshot(tx, id, r.storeID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("product not found")
+			}
+			return err
+		}
+
+		query := `
+            UPDATE products
+            SET option_types = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $2 AND deleted_at IS NULL
+        `
+		result, err := tx.Exec(query, encoded, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return errors.New("product not found")
+		}
+
+		return auditProduct(tx, models.AuditUpdate, id, r.storeID, before, actor)
+	})
+}
+
+// GetPackagingUnits - Packaging units of a product, smallest first
+func (r *ProductRepository) GetPackagingUnits(productID int) ([]models.PackagingUnit, error) {
+	query := `
+        SELECT name, factor
+        FROM product_units
+        WHERE product_id = $1
+        ORDER BY factor, name
+    `
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := []models.PackagingUnit{}
+	for rows.Next() {
+		var u models.PackagingUnit
+		if err := rows.Scan(&u.Name, &u.Factor); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return units, nil
+}
+
+// SetPackagingUnits - Replace the packaging units of a product
+func (r *ProductRepository) SetPackagingUnits(productID int, units []models.PackagingUnit, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := productSnapshot(tx, productID, r.storeID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("product not found")
+			}
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM product_units WHERE product_id = $1", productID); err != nil {
+			return err
+		}
+		for _, u := range units {
+			query := "INSERT INTO product_units (product_id, name, factor) VALUES ($1, $2, $3)"
+			if _, err := tx.Exec(query, productID, u.Name, u.Factor); err != nil {
+				return err
+			}
+		}
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+		return auditProduct(tx, models.AuditUpdate, productID, r.storeID, before, actor)
+	})
+}
+
+// AdjustStock - Add delta (in the product unit, may be negative) to the stock.
+// Fails with ErrInsufficientStock instead of going below zero.
+func (r *ProductRepository) AdjustStock(id int, delta models.Quantity, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := productSnapshot(tx, id, r.storeID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.New("product not found")
+			}
+			return err
+		}
+
+		if r.storeID != 0 {
+			err = adjustStoreStock(tx, r.storeID, id, delta)
+		} else {
+			err = adjustCentralStock(tx, id, delta)
+		}
+		if err != nil {
+			return err
+		}
+		return auditProduct(tx, models.AuditUpdate, id, r.storeID, before, actor)
+	})
+}
+
+func adjustCentralStock(q querier, id int, delta models.Quantity) error {
+	query := `
+        UPDATE products
+        SET stock = stock + $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2 AND deleted_at IS NULL AND stock + $1 >= 0
+    `
+	result, err := q.Exec(query, delta, id)
 	if err != nil {
 		return err
 	}
@@ -104,19 +644,250 @@ func (r *ProductRepository) Delete(id int) error {
 	}
 
 	if rowsAffected == 0 {
+		// The product exists, so the stock would have gone below zero
+		err := productMissingOrModified(q, id)
+		if err == models.ErrVersionMismatch {
+			return models.ErrInsufficientStock
+		}
+		return err
+	}
+
+	return nil
+}
+
+// adjustStoreStock - AdjustStock for a store
+func adjustStoreStock(q querier, storeID int, id int, delta models.Quantity) error {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
 		return errors.New("product not found")
 	}
+	return moveStoreStock(q, storeID, id, delta)
+}
+
+// moveStoreStock - Add delta to the stock of a product in a store and bump
+// the product version, which also locks the product row. Fails with
+// ErrInsufficientStock instead of going below zero.
+func moveStoreStock(q querier, storeID int, productID int, delta models.Quantity) error {
+	if err := touchProduct(q, productID); err != nil {
+		return err
+	}
+
+	insert := `
+        INSERT INTO store_inventory (store_id, product_id, stock)
+        VALUES ($1, $2, 0)
+        ON CONFLICT (store_id, product_id) DO NOTHING
+    `
+	if _, err := q.Exec(insert, storeID, productID); err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE store_inventory
+        SET stock = stock + $1, updated_at = CURRENT_TIMESTAMP
+        WHERE store_id = $2 AND product_id = $3 AND stock + $1 >= 0
+    `
+	result, err := q.Exec(query, delta, storeID, productID)
+	if err != nil {
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrInsufficientStock
+	}
 	return nil
 }
 
+// setStoreInventory - Set the stock and price override (nil = default price)
+// of a product in a store
+func setStoreInventory(q querier, storeID int, productID int, stock models.Quantity, price *int) error {
+	query := `
+        INSERT INTO store_inventory (store_id, product_id, stock, price)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (store_id, product_id) DO UPDATE
+        SET stock = EXCLUDED.stock, price = EXCLUDED.price, updated_at = CURRENT_TIMESTAMP
+    `
+	_, err := q.Exec(query, storeID, productID, stock, price)
+	return err
+}
+
 // CheckCategoryExists - Helper to validate category_id
 func (r *ProductRepository) CheckCategoryExists(categoryID int) (bool, error) {
-	query := "SELECT COUNT(*) FROM categories WHERE id = $1"
+	return checkCategoryExists(r.db, categoryID)
+}
+
+func checkCategoryExists(q querier, categoryID int) (bool, error) {
+	query := "SELECT COUNT(*) FROM categories WHERE id = $1 AND deleted_at IS NULL"
 	var count int
-	err := r.db.QueryRow(query, categoryID).Scan(&count)
+	err := q.QueryRow(query, categoryID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
+
+// GetInventorySummary - Aggregate stock figures used by the metrics
+// endpoint, over the central stock and the stock of every store. A product
+// counts as low when it is low in the central stock or in a store carrying it.
+func (r *ProductRepository) GetInventorySummary(lowStockThreshold int) (*models.InventorySummary, error) {
+	query := `
+        WITH stock AS (` + inventoryStock + `)
+        SELECT COUNT(DISTINCT id),
+               COUNT(DISTINCT id) FILTER (WHERE stock <= COALESCE(reorder_level, $1)),
+               COALESCE(ROUND(SUM(price * stock))::BIGINT, 0)
+        FROM stock
+    `
+	var summary models.InventorySummary
+	err := r.db.QueryRow(query, lowStockThreshold).Scan(&summary.TotalProducts,
+		&summary.LowStockProducts, &summary.CatalogValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stock in kg and in pcs do not add up
+	rows, err := r.db.Query("WITH stock AS (" + inventoryStock + ") SELECT unit, SUM(stock) FROM stock GROUP BY unit")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary.StockByUnit = map[string]models.Quantity{}
+	for rows.Next() {
+		var unit string
+		var stock models.Quantity
+		if err := rows.Scan(&unit, &stock); err != nil {
+			return nil, err
+		}
+		summary.StockByUnit[unit] = stock
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// inventoryStock - Central and store stock rows of the active products
+const inventoryStock = `
+            SELECT p.id, p.price, p.stock, p.unit, p.reorder_level
+            FROM products p
+            WHERE p.deleted_at IS NULL
+            UNION ALL
+            SELECT p.id, COALESCE(si.price, p.price), si.stock, p.unit, p.reorder_level
+            FROM store_inventory si
+            JOIN products p ON p.id = si.product_id
+            WHERE p.deleted_at IS NULL
+        `
+
+// GetLowStock - Active products at or below their reorder level, ordered by
+// category and name. In a store only the products it carries (with a
+// store_inventory row) are checked, against the store stock.
+func (r *ProductRepository) GetLowStock(defaultReorderLevel int) ([]models.LowStockItem, error) {
+	query := `
+        SELECT ` + lowStockColumns(storeStock("$2"), "st.id, st.name") + `
+        FROM products p
+        JOIN categories c ON p.category_id = c.id
+        ` + storeInventoryJoin("$2") + `
+        LEFT JOIN stores st ON st.id = si.store_id
+        WHERE p.deleted_at IS NULL AND ($2 = 0 OR si.product_id IS NOT NULL)
+          AND ` + lowStockWhere(storeStock("$2")) + `
+        ORDER BY c.name, p.name, p.id
+    `
+	return queryLowStock(r.db, query, defaultReorderLevel, r.storeID)
+}
+
+// ClaimLowStockAlerts - Mark products that crossed their reorder level since
+// the last check as alerted and return them: first the central stock, then
+// the stock of each store. Each product is returned once per crossing and
+// store, even with several checkers running.
+func (r *ProductRepository) ClaimLowStockAlerts(defaultReorderLevel int) ([]models.LowStockItem, error) {
+	central := `
+        UPDATE products p
+        SET low_stock_alerted_at = CURRENT_TIMESTAMP
+        FROM categories c
+        WHERE p.category_id = c.id AND p.deleted_at IS NULL
+          AND p.low_stock_alerted_at IS NULL AND ` + lowStockWhere("p.stock") + `
+        RETURNING ` + lowStockColumns("p.stock", centralStore)
+	items, err := queryLowStock(r.db, central, defaultReorderLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	stores := `
+        UPDATE store_inventory si
+        SET low_stock_alerted_at = CURRENT_TIMESTAMP
+        FROM products p, categories c, stores st
+        WHERE p.id = si.product_id AND p.category_id = c.id AND st.id = si.store_id
+          AND p.deleted_at IS NULL
+          AND si.low_stock_alerted_at IS NULL AND ` + lowStockWhere("si.stock") + `
+        RETURNING ` + lowStockColumns("si.stock", "st.id, st.name")
+	storeItems, err := queryLowStock(r.db, stores, defaultReorderLevel)
+	if err != nil {
+		return items, err
+	}
+	return append(items, storeItems...), nil
+}
+
+// ResetLowStockAlerts - Re-arm alerts of products that are back above their
+// reorder level (or archived), in the central stock and in every store, so
+// the next crossing alerts again
+func (r *ProductRepository) ResetLowStockAlerts(defaultReorderLevel int) (int64, error) {
+	central := `
+        UPDATE products p
+        SET low_stock_alerted_at = NULL
+        WHERE p.low_stock_alerted_at IS NOT NULL
+          AND (p.deleted_at IS NOT NULL OR NOT (` + lowStockWhere("p.stock") + `))
+    `
+	stores := `
+        UPDATE store_inventory si
+        SET low_stock_alerted_at = NULL
+        FROM products p
+        WHERE p.id = si.product_id AND si.low_stock_alerted_at IS NOT NULL
+          AND (p.deleted_at IS NOT NULL OR NOT (` + lowStockWhere("si.stock") + `))
+    `
+	var reset int64
+	for _, query := range []string{central, stores} {
+		result, err := r.db.Exec(query, defaultReorderLevel)
+		if err != nil {
+			return reset, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return reset, err
+		}
+		reset += rowsAffected
+	}
+	return reset, nil
+}
+
+func queryLowStock(q querier, query string, args ...interface{}) ([]models.LowStockItem, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.LowStockItem{}
+	for rows.Next() {
+		var item models.LowStockItem
+		err := rows.Scan(&item.ProductID, &item.Name, &item.CategoryID, &item.CategoryName, &item.Unit,
+			&item.Stock, &item.ReorderLevel, &item.ReorderQuantity, &item.SuggestedQuantity,
+			&item.StoreID, &item.StoreName)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"cashier-api/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestUpdateUnitChange - The unit only changes when the product holds no
+// stock anywhere; the check runs in the update transaction after the lock
+func TestUpdateUnitChange(t *testing.T) {
+	// The audit snapshot follows the check; failing it ends the update there
+	errSnapshot := errors.New("snapshot reached")
+
+	tests := []struct {
+		name      string
+		unit      string
+		wantCheck bool
+		hasStock  bool
+		wantErr   error
+	}{
+		{"same unit", "kg", false, false, errSnapshot},
+		{"new unit without stock", "g", true, false, errSnapshot},
+		{"new unit with stock", "g", true, true, models.ErrUnitChangeWithStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT p.price, p.stock, p.unit").WithArgs(1, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"price", "stock", "unit", "store_price"}).
+					AddRow(14000, "2.5", "kg", 14000))
+			if tt.wantCheck {
+				mock.ExpectQuery("SELECT p.stock <> 0").WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"has_stock"}).AddRow(tt.hasStock))
+			}
+			if tt.wantErr == errSnapshot {
+				mock.ExpectQuery("to_jsonb").WillReturnError(errSnapshot)
+			}
+			mock.ExpectRollback()
+
+			product := &models.Product{ID: 1, Name: "Beras", Price: 14000, Unit: tt.unit, CategoryID: 1}
+			err = NewProductRepository(db, nil).Update(product, models.Actor{Name: "test"})
+			if err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import "database/sql"
+
+// Cache namespaces of the catalog lists
+const (
+	cacheProducts   = "products"
+	cacheCategories = "categories"
+)
+
+// querier - Methods shared by *sql.DB and *sql.Tx so that the same query
+// code can run standalone or inside a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// withTx - Run fn in a transaction, committing on success and rolling back on error
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// nullString - nil for an empty string, so it is stored as NULL
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,329 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type StocktakeRepository struct {
+	db *sql.DB
+}
+
+func NewStocktakeRepository(db *sql.DB) *StocktakeRepository {
+	return &StocktakeRepository{db: db}
+}
+
+// stocktakeColumns - Columns scanned by scanStocktake (stocktakes s)
+var stocktakeColumns = `
+        s.id, s.note, s.category_id, s.store_id, s.status, s.created_at, s.finalized_at,
+        (SELECT COUNT(*) FROM stocktake_items i WHERE i.stocktake_id = s.id),
+        (SELECT COUNT(*) FROM stocktake_items i WHERE i.stocktake_id = s.id AND i.counted IS NOT NULL)`
+
+func scanStocktake(row rowScanner) (*models.Stocktake, error) {
+	var s models.Stocktake
+	err := row.Scan(&s.ID, &s.Note, &s.CategoryID, &s.StoreID, &s.Status, &s.CreatedAt, &s.FinalizedAt,
+		&s.ItemCount, &s.CountedItems)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetAll - Stock takes, newest first
+func (r *StocktakeRepository) GetAll() ([]models.Stocktake, error) {
+	rows, err := r.db.Query("SELECT " + stocktakeColumns + " FROM stocktakes s ORDER BY s.id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocktakes := []models.Stocktake{}
+	for rows.Next() {
+		s, err := scanStocktake(rows)
+		if err != nil {
+			return nil, err
+		}
+		stocktakes = append(stocktakes, *s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stocktakes, nil
+}
+
+func (r *StocktakeRepository) GetByID(id int) (*models.Stocktake, error) {
+	row := r.db.QueryRow("SELECT "+stocktakeColumns+" FROM stocktakes s WHERE s.id = $1", id)
+	s, err := scanStocktake(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrStocktakeNotFound
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetItems - Items of a stock take ordered by category and product name
+func (r *StocktakeRepository) GetItems(id int) ([]models.StocktakeItem, error) {
+	query := `
+        SELECT i.product_id, p.name, p.category_id, c.name, p.unit, i.price,
+               i.expected, i.counted, i.counted_at, i.applied
+        FROM stocktake_items i
+        JOIN products p ON i.product_id = p.id
+        JOIN categories c ON p.category_id = c.id
+        WHERE i.stocktake_id = $1
+        ORDER BY c.name, p.name, p.id
+    `
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.StocktakeItem{}
+	for rows.Next() {
+		var item models.StocktakeItem
+		err := rows.Scan(&item.ProductID, &item.ProductName, &item.CategoryID, &item.CategoryName,
+			&item.Unit, &item.Price, &item.Expected, &item.Counted, &item.CountedAt, &item.Applied)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Create - Open a stock take and snapshot the expected stock of every active
+// product (of the category subtree when CategoryID is set) in the store, or
+// in the central stock when StoreID is nil. Only one stock take per store can
+// be open at a time.
+func (r *StocktakeRepository) Create(stocktake *models.Stocktake) error {
+	categoryID := 0
+	if stocktake.CategoryID != nil {
+		categoryID = *stocktake.CategoryID
+	}
+	storeID := 0
+	if stocktake.StoreID != nil {
+		storeID = *stocktake.StoreID
+	}
+
+	return withTx(r.db, func(tx *sql.Tx) error {
+		query := `
+            INSERT INTO stocktakes (note, category_id, store_id)
+            VALUES ($1, $2, $3)
+            RETURNING id, status, created_at
+        `
+		err := tx.QueryRow(query, stocktake.Note, stocktake.CategoryID, stocktake.StoreID).Scan(&stocktake.ID,
+			&stocktake.Status, &stocktake.CreatedAt)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return models.ErrStocktakeOpenExists
+			}
+			return err
+		}
+
+		snapshot := `
+            INSERT INTO stocktake_items (stocktake_id, product_id, expected, price)
+            SELECT $1, p.id, ` + storeStock("$3") + `, ` + storePrice + `
+            FROM products p
+            ` + storeInventoryJoin("$3") + `
+            WHERE p.deleted_at IS NULL
+              AND ($2 = 0 OR p.category_id IN (` + categorySubtreeQuery("$2") + `))
+        `
+		result, err := tx.Exec(snapshot, stocktake.ID, categoryID, storeID)
+		if err != nil {
+			return err
+		}
+
+		itemCount, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		stocktake.ItemCount = int(itemCount)
+		return nil
+	})
+}
+
+// RecordCounts - Store counted quantities (already in the product unit).
+// Several devices may count at once: the stock take row is share-locked, so
+// counts run concurrently but never interleave with Finalize.
+func (r *StocktakeRepository) RecordCounts(id int, counts []models.StocktakeCount) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if err := lockOpenStocktake(tx, id, "FOR SHARE"); err != nil {
+			return err
+		}
+
+		query := `
+            UPDATE stocktake_items
+            SET counted = CASE WHEN $1 = 'set' THEN $2 ELSE COALESCE(counted, 0) + $2 END,
+                counted_at = CURRENT_TIMESTAMP
+            WHERE stocktake_id = $3 AND product_id = $4
+        `
+		for _, count := range counts {
+			result, err := tx.Exec(query, count.Mode, count.Quantity, id, count.ProductID)
+			if err != nil {
+				// counted has CHECK (counted >= 0)
+				if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23514" {
+					return models.ErrNegativeCount
+				}
+				return err
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return models.ErrStocktakeProduct
+			}
+		}
+		return nil
+	})
+}
+
+// Finalize - Apply the variance (counted - expected) of every counted item to
+// the stock of the store (or the central stock) and close the stock take, all
+// in one transaction. Applying the difference keeps sales made while
+// counting. A variance that would take the stock below zero stops at zero;
+// the change actually made is stored as applied. Uncounted items are left
+// unchanged. Every adjusted product gets a stock.changed event.
+func (r *StocktakeRepository) Finalize(id int) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if err := lockOpenStocktake(tx, id, "FOR UPDATE"); err != nil {
+			return err
+		}
+
+		var storeID sql.NullInt64
+		if err := tx.QueryRow("SELECT store_id FROM stocktakes WHERE id = $1", id).Scan(&storeID); err != nil {
+			return err
+		}
+
+		query := `
+            SELECT product_id, counted - expected
+            FROM stocktake_items
+            WHERE stocktake_id = $1 AND counted IS NOT NULL AND counted <> expected
+            ORDER BY product_id
+        `
+		rows, err := tx.Query(query, id)
+		if err != nil {
+			return err
+		}
+		type itemVariance struct {
+			ProductID int
+			Quantity  models.Quantity
+		}
+		var counted []itemVariance
+		for rows.Next() {
+			var count itemVariance
+			if err := rows.Scan(&count.ProductID, &count.Quantity); err != nil {
+				rows.Close()
+				return err
+			}
+			counted = append(counted, count)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, variance := range counted {
+			applied, err := applyVariance(tx, int(storeID.Int64), variance.ProductID, variance.Quantity)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("UPDATE stocktake_items SET applied = $1 WHERE stocktake_id = $2 AND product_id = $3",
+				applied, id, variance.ProductID)
+			if err != nil {
+				return err
+			}
+			if err := publishStockChanged(tx, variance.ProductID, int(storeID.Int64), models.StockSourceStocktake); err != nil {
+				return err
+			}
+		}
+
+		closeQuery := `
+            UPDATE stocktakes
+            SET status = 'finalized', finalized_at = CURRENT_TIMESTAMP
+            WHERE id = $1
+        `
+		_, err = tx.Exec(closeQuery, id)
+		return err
+	})
+}
+
+// applyVariance - Add variance to the stock of a product in a store (0 = the
+// central stock), stopping at zero, and return the change made. Bumps the
+// product version like any other stock change.
+func applyVariance(tx *sql.Tx, storeID int, productID int, variance models.Quantity) (models.Quantity, error) {
+	var stock models.Quantity
+	if storeID == 0 {
+		err := tx.QueryRow("SELECT stock FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&stock)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		if err := touchProduct(tx, productID); err != nil {
+			return 0, err
+		}
+		insert := `
+            INSERT INTO store_inventory (store_id, product_id, stock)
+            VALUES ($1, $2, 0)
+            ON CONFLICT (store_id, product_id) DO NOTHING
+        `
+		if _, err := tx.Exec(insert, storeID, productID); err != nil {
+			return 0, err
+		}
+		query := "SELECT stock FROM store_inventory WHERE store_id = $1 AND product_id = $2 FOR UPDATE"
+		if err := tx.QueryRow(query, storeID, productID).Scan(&stock); err != nil {
+			return 0, err
+		}
+	}
+
+	applied := variance
+	if stock+applied < 0 {
+		applied = -stock
+	}
+
+	var err error
+	if storeID == 0 {
+		query := `
+            UPDATE products
+            SET stock = stock + $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $2
+        `
+		_, err = tx.Exec(query, applied, productID)
+	} else {
+		query := `
+            UPDATE store_inventory
+            SET stock = stock + $1, updated_at = CURRENT_TIMESTAMP
+            WHERE store_id = $2 AND product_id = $3
+        `
+		_, err = tx.Exec(query, applied, storeID, productID)
+	}
+	return applied, err
+}
+
+// lockOpenStocktake - Lock the stock take row and check it is still open
+func lockOpenStocktake(tx *sql.Tx, id int, lock string) error {
+	var status string
+	err := tx.QueryRow("SELECT status FROM stocktakes WHERE id = $1 "+lock, id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrStocktakeNotFound
+		}
+		return err
+	}
+	if status != models.StocktakeOpen {
+		return models.ErrStocktakeNotOpen
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type StoreRepository struct {
+	db *sql.DB
+}
+
+func NewStoreRepository(db *sql.DB) *StoreRepository {
+	return &StoreRepository{db: db}
+}
+
+func (r *StoreRepository) GetAll() ([]models.Store, error) {
+	rows, err := r.db.Query("SELECT id, code, name, address, created_at FROM stores ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []models.Store{}
+	for rows.Next() {
+		var s models.Store
+		if err := rows.Scan(&s.ID, &s.Code, &s.Name, &s.Address, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		stores = append(stores, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stores, nil
+}
+
+func (r *StoreRepository) GetByID(id int) (*models.Store, error) {
+	query := "SELECT id, code, name, address, created_at FROM stores WHERE id = $1"
+	var s models.Store
+	err := r.db.QueryRow(query, id).Scan(&s.ID, &s.Code, &s.Name, &s.Address, &s.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrStoreNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Exists - Used by the store context middleware
+func (r *StoreRepository) Exists(id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM stores WHERE id = $1)", id).Scan(&exists)
+	return exists, err
+}
+
+func (r *StoreRepository) Create(store *models.Store) error {
+	query := `
+        INSERT INTO stores (code, name, address)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `
+	err := r.db.QueryRow(query, store.Code, store.Name, store.Address).Scan(&store.ID, &store.CreatedAt)
+	return storeWriteError(err)
+}
+
+func (r *StoreRepository) Update(store *models.Store) error {
+	query := `
+        UPDATE stores
+        SET code = $1, name = $2, address = $3, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $4
+        RETURNING created_at
+    `
+	err := r.db.QueryRow(query, store.Code, store.Name, store.Address, store.ID).Scan(&store.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrStoreNotFound
+	}
+	return storeWriteError(err)
+}
+
+// storeWriteError - Map the unique violation on stores.code
+func storeWriteError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return models.ErrStoreCodeExists
+	}
+	return err
+}
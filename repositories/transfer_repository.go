@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+)
+
+type TransferRepository struct {
+	db *sql.DB
+}
+
+func NewTransferRepository(db *sql.DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+// transferSelect - Query of the columns scanned by scanTransfer
+var transferSelect = `
+        SELECT t.id, t.source_store_id, src.name, t.destination_store_id, dst.name, t.status, t.note,
+               t.created_by, t.shipped_by, t.received_by, t.created_at, t.shipped_at, t.received_at
+        FROM stock_transfers t
+        JOIN stores src ON src.id = t.source_store_id
+        JOIN stores dst ON dst.id = t.destination_store_id`
+
+func scanTransfer(row rowScanner) (*models.Transfer, error) {
+	var t models.Transfer
+	err := row.Scan(&t.ID, &t.SourceStoreID, &t.SourceStoreName, &t.DestinationStoreID, &t.DestinationStoreName,
+		&t.Status, &t.Note, &t.CreatedBy, &t.ShippedBy, &t.ReceivedBy, &t.CreatedAt, &t.ShippedAt, &t.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetAll - Transfers, newest first
+func (r *TransferRepository) GetAll(filter models.TransferFilter) ([]models.Transfer, error) {
+	query := transferSelect + `
+        WHERE ($1 = '' OR t.status = $1)
+          AND ($2 = 0 OR $2 IN (t.source_store_id, t.destination_store_id))
+        ORDER BY t.id DESC
+    `
+	rows, err := r.db.Query(query, filter.Status, filter.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []models.Transfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (r *TransferRepository) GetByID(id int) (*models.Transfer, error) {
+	t, err := scanTransfer(r.db.QueryRow(transferSelect+" WHERE t.id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrTransferNotFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetLines - Lines of a transfer ordered by product name
+func (r *TransferRepository) GetLines(id int) ([]models.TransferLine, error) {
+	query := `
+        SELECT l.product_id, p.name, p.unit, l.quantity, l.received
+        FROM stock_transfer_lines l
+        JOIN products p ON l.product_id = p.id
+        WHERE l.transfer_id = $1
+        ORDER BY p.name, p.id
+    `
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.TransferLine{}
+	for rows.Next() {
+		var l models.TransferLine
+		if err := rows.Scan(&l.ProductID, &l.ProductName, &l.Unit, &l.Quantity, &l.Received); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// Create - Save a draft transfer with its lines (quantities in the product unit)
+func (r *TransferRepository) Create(transfer *models.Transfer) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		query := `
+            INSERT INTO stock_transfers (source_store_id, destination_store_id, note, created_by)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id, status, created_at
+        `
+		err := tx.QueryRow(query, transfer.SourceStoreID, transfer.DestinationStoreID, transfer.Note,
+			transfer.CreatedBy).Scan(&transfer.ID, &transfer.Status, &transfer.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return insertTransferLines(tx, transfer.ID, transfer.Lines)
+	})
+}
+
+// Update - Replace the stores, note and lines of a draft transfer
+func (r *TransferRepository) Update(transfer *models.Transfer) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if _, err := lockTransfer(tx, transfer.ID, models.TransferDraft); err != nil {
+			return err
+		}
+
+		query := `
+            UPDATE stock_transfers
+            SET source_store_id = $1, destination_store_id = $2, note = $3
+            WHERE id = $4
+        `
+		_, err := tx.Exec(query, transfer.SourceStoreID, transfer.DestinationStoreID, transfer.Note, transfer.ID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM stock_transfer_lines WHERE transfer_id = $1", transfer.ID); err != nil {
+			return err
+		}
+		return insertTransferLines(tx, transfer.ID, transfer.Lines)
+	})
+}
+
+// Delete - Remove a draft transfer
+func (r *TransferRepository) Delete(id int) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if _, err := lockTransfer(tx, id, models.TransferDraft); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM stock_transfers WHERE id = $1", id)
+		return err
+	})
+}
+
+// Ship - Take every line from the source store stock and mark the transfer
+// shipped, all in one transaction. Fails with ErrInsufficientStock when the
+// source store does not have enough of a product.
+func (r *TransferRepository) Ship(id int, shippedBy *string) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		transfer, err := lockTransfer(tx, id, models.TransferDraft)
+		if err != nil {
+			return err
+		}
+
+		lines, err := transferLines(tx, id)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := moveStoreStock(tx, transfer.SourceStoreID, line.ProductID, -line.Quantity); err != nil {
+				return err
+			}
+			err := publishStockChanged(tx, line.ProductID, transfer.SourceStoreID, models.StockSourceTransfer)
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `
+            UPDATE stock_transfers
+            SET status = 'shipped', shipped_by = $1, shipped_at = CURRENT_TIMESTAMP
+            WHERE id = $2
+        `
+		_, err = tx.Exec(query, shippedBy, id)
+		return err
+	})
+}
+
+// Receive - Record what arrived and add it to the destination store stock.
+// received holds the quantities of partially received products; the other
+// lines arrived complete. More than was shipped cannot arrive: the source
+// store only gave up the shipped quantity.
+func (r *TransferRepository) Receive(id int, received map[int]models.Quantity, receivedBy *string) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		transfer, err := lockTransfer(tx, id, models.TransferShipped)
+		if err != nil {
+			return err
+		}
+
+		lines, err := transferLines(tx, id)
+		if err != nil {
+			return err
+		}
+		shipped := map[int]models.Quantity{}
+		for _, line := range lines {
+			shipped[line.ProductID] = line.Quantity
+		}
+		for productID, quantity := range received {
+			shippedQuantity, ok := shipped[productID]
+			if !ok {
+				return models.ErrTransferProduct
+			}
+			if quantity > shippedQuantity {
+				return models.ErrReceivedExceedsShipped
+			}
+		}
+
+		update := "UPDATE stock_transfer_lines SET received = $1 WHERE transfer_id = $2 AND product_id = $3"
+		for _, line := range lines {
+			quantity, ok := received[line.ProductID]
+			if !ok {
+				quantity = line.Quantity
+			}
+			if _, err := tx.Exec(update, quantity, id, line.ProductID); err != nil {
+				return err
+			}
+			if quantity == 0 {
+				continue
+			}
+			if err := moveStoreStock(tx, transfer.DestinationStoreID, line.ProductID, quantity); err != nil {
+				return err
+			}
+			err := publishStockChanged(tx, line.ProductID, transfer.DestinationStoreID, models.StockSourceTransfer)
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `
+            UPDATE stock_transfers
+            SET status = 'received', received_by = $1, received_at = CURRENT_TIMESTAMP
+            WHERE id = $2
+        `
+		_, err = tx.Exec(query, receivedBy, id)
+		return err
+	})
+}
+
+// lockTransfer - Lock the transfer row and check its status
+func lockTransfer(tx *sql.Tx, id int, status string) (*models.Transfer, error) {
+	var transfer models.Transfer
+	query := "SELECT id, source_store_id, destination_store_id, status FROM stock_transfers WHERE id = $1 FOR UPDATE"
+	err := tx.QueryRow(query, id).Scan(&transfer.ID, &transfer.SourceStoreID, &transfer.DestinationStoreID,
+		&transfer.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrTransferNotFound
+		}
+		return nil, err
+	}
+
+	if transfer.Status != status {
+		if status == models.TransferDraft {
+			return nil, models.ErrTransferNotDraft
+		}
+		return nil, models.ErrTransferNotShipped
+	}
+	return &transfer, nil
+}
+
+// transferLines - Product and shipped quantity of the lines of a transfer, in
+// product order so that concurrent transfers lock products in the same order
+func transferLines(tx *sql.Tx, id int) ([]models.TransferLine, error) {
+	query := "SELECT product_id, quantity FROM stock_transfer_lines WHERE transfer_id = $1 ORDER BY product_id"
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.TransferLine
+	for rows.Next() {
+		var l models.TransferLine
+		if err := rows.Scan(&l.ProductID, &l.Quantity); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func insertTransferLines(tx *sql.Tx, id int, lines []models.TransferLine) error {
+	query := "INSERT INTO stock_transfer_lines (transfer_id, product_id, quantity) VALUES ($1, $2, $3)"
+	for _, line := range lines {
+		if _, err := tx.Exec(query, id, line.ProductID, line.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
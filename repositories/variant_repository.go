@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+type VariantRepository struct {
+	db *sql.DB
+}
+
+func NewVariantRepository(db *sql.DB) *VariantRepository {
+	return &VariantRepository{db: db}
+}
+
+// GetByProductID - All variants of a product, with the effective price resolved
+func (r *VariantRepository) GetByProductID(productID int) ([]models.Variant, error) {
+	query := `
+        SELECT v.id, v.product_id, v.sku, v.barcode, v.options, v.price, v.stock,
+               COALESCE(v.price, p.price)
+        FROM product_variants v
+        JOIN products p ON v.product_id = p.id
+        WHERE v.product_id = $1
+        ORDER BY v.id
+    `
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []models.Variant{}
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, *v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (r *VariantRepository) GetByID(productID int, id int) (*models.Variant, error) {
+	query := `
+        SELECT v.id, v.product_id, v.sku, v.barcode, v.options, v.price, v.stock,
+               COALESCE(v.price, p.price)
+        FROM product_variants v
+        JOIN products p ON v.product_id = p.id
+        WHERE v.product_id = $1 AND v.id = $2
+    `
+	v, err := scanVariant(r.db.QueryRow(query, productID, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// Create - The new variant is recorded in the audit log as an update of
+// its product made by actor
+func (r *VariantRepository) Create(variant *models.Variant, actor models.Actor) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO product_variants (product_id, sku, barcode, options, price, stock)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := variantProductSnapshot(tx, variant.ProductID)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(query, variant.ProductID, variant.SKU, variant.Barcode, options,
+			variant.Price, variant.Stock).Scan(&variant.ID)
+		if err != nil {
+			return variantWriteError(err)
+		}
+		return auditVariantWrite(tx, variant.ProductID, before, actor)
+	})
+}
+
+func (r *VariantRepository) Update(variant *models.Variant, actor models.Actor) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE product_variants
+        SET sku = $1, barcode = $2, options = $3, price = $4, stock = $5,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE product_id = $6 AND id = $7
+    `
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := variantProductSnapshot(tx, variant.ProductID)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(query, variant.SKU, variant.Barcode, options, variant.Price,
+			variant.Stock, variant.ProductID, variant.ID)
+		if err != nil {
+			return variantWriteError(err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return models.ErrVariantNotFound
+		}
+
+		return auditVariantWrite(tx, variant.ProductID, before, actor)
+	})
+}
+
+func (r *VariantRepository) Delete(productID int, id int, actor models.Actor) error {
+	query := "DELETE FROM product_variants WHERE product_id = $1 AND id = $2"
+	return withTx(r.db, func(tx *sql.Tx) error {
+		before, err := variantProductSnapshot(tx, productID)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(query, productID, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return models.ErrVariantNotFound
+		}
+
+		return auditVariantWrite(tx, productID, before, actor)
+	})
+}
+
+// variantProductSnapshot - productSnapshot of the parent product (which
+// covers its variants) before a variant write
+func variantProductSnapshot(q querier, productID int) ([]byte, error) {
+	before, err := productSnapshot(q, productID, 0)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("product not found")
+	}
+	return before, err
+}
+
+// touchProduct - Bump the parent product version so its ETag (which covers
+// the embedded variants) changes
+func touchProduct(q querier, productID int) error {
+	_, err := q.Exec("UPDATE products SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1", productID)
+	return err
+}
+
+// auditVariantWrite - touchProduct, and record the variant write as an
+// update of the product made by actor
+func auditVariantWrite(q querier, productID int, before []byte, actor models.Actor) error {
+	if err := touchProduct(q, productID); err != nil {
+		return err
+	}
+	return auditProduct(q, models.AuditUpdate, productID, 0, before, actor)
+}
+
+// CountByProductID - Number of variants of a product
+func (r *VariantRepository) CountByProductID(productID int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM product_variants WHERE product_id = $1", productID).Scan(&count)
+	return count, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVariant(row rowScanner) (*models.Variant, error) {
+	var v models.Variant
+	var options []byte
+	err := row.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Barcode, &options, &v.Price, &v.Stock, &v.EffectivePrice)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(options, &v.Options); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// variantWriteError - Translate unique violations into validation errors
+func variantWriteError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		if pqErr.Constraint == "uq_product_variants_options" {
+			return models.ErrDuplicateVariant
+		}
+		return models.ErrDuplicateSKU
+	}
+	return err
+}
@@ -0,0 +1,333 @@
+package repositories
+
+import (
+	"cashier-api/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// subscriptionColumns - Columns scanned by scanSubscription (without the secret)
+var subscriptionColumns = "id, url, events, description, active, created_at, updated_at"
+
+func scanSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	err := row.Scan(&s.ID, &s.URL, pq.Array(&s.Events), &s.Description, &s.Active, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *WebhookRepository) GetAll() ([]models.WebhookSubscription, error) {
+	rows, err := r.db.Query("SELECT " + subscriptionColumns + " FROM webhook_subscriptions ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) GetByID(id int) (*models.WebhookSubscription, error) {
+	row := r.db.QueryRow("SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE id = $1", id)
+	s, err := scanSubscription(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create - Save a subscription; the secret is stored as given
+func (r *WebhookRepository) Create(subscription *models.WebhookSubscription) error {
+	query := `
+        INSERT INTO webhook_subscriptions (url, events, secret, description, active)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, updated_at
+    `
+	return r.db.QueryRow(query, subscription.URL, pq.Array(subscription.Events), subscription.Secret,
+		subscription.Description, subscription.Active).Scan(&subscription.ID, &subscription.CreatedAt,
+		&subscription.UpdatedAt)
+}
+
+// Update - Replace a subscription. An empty secret keeps the current one.
+func (r *WebhookRepository) Update(subscription *models.WebhookSubscription) error {
+	query := `
+        UPDATE webhook_subscriptions
+        SET url = $1, events = $2, secret = COALESCE(NULLIF($3, ''), secret), description = $4, active = $5,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $6
+        RETURNING created_at, updated_at
+    `
+	err := r.db.QueryRow(query, subscription.URL, pq.Array(subscription.Events), subscription.Secret,
+		subscription.Description, subscription.Active, subscription.ID).Scan(&subscription.CreatedAt,
+		&subscription.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrWebhookNotFound
+	}
+	return err
+}
+
+// Delete - Remove a subscription together with its queued and past deliveries
+func (r *WebhookRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// deliveryColumns - Columns of webhook_deliveries d joined with
+// webhook_subscriptions s scanned by scanDelivery
+var deliveryColumns = `d.id, d.subscription_id, s.url, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+               d.next_attempt_at, d.last_attempt_at, d.last_status, d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return &d, nil
+}
+
+// GetDeliveries - Deliveries matching the filter, newest first
+func (r *WebhookRepository) GetDeliveries(filter models.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := "SELECT " + deliveryColumns + `
+        FROM webhook_deliveries d
+        JOIN webhook_subscriptions s ON s.id = d.subscription_id
+        WHERE ($1 = 0 OR d.subscription_id = $1) AND ($2 = '' OR d.status = $2)
+        ORDER BY d.created_at DESC, d.id DESC
+        LIMIT $3
+    `
+	rows, err := r.db.Query(query, filter.SubscriptionID, filter.Status, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery - Queue a dead delivery again with a fresh set of attempts
+func (r *WebhookRepository) RetryDelivery(id int64) (*models.WebhookDelivery, error) {
+	var status string
+	err := r.db.QueryRow("SELECT status FROM webhook_deliveries WHERE id = $1", id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	if status != models.DeliveryDead {
+		return nil, models.ErrDeliveryNotDead
+	}
+
+	query := `
+        UPDATE webhook_deliveries d
+        SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+        FROM webhook_subscriptions s
+        WHERE d.id = $1 AND d.status = 'dead' AND s.id = d.subscription_id
+        RETURNING ` + deliveryColumns
+	delivery, err := scanDelivery(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		// Retried by another request in the meantime
+		return nil, models.ErrDeliveryNotDead
+	}
+	return delivery, err
+}
+
+// ClaimDeliveries - Take up to limit due deliveries of active subscriptions,
+// oldest first, and hide them from other dispatchers for lease. A delivery
+// whose dispatcher dies is picked up again when the lease runs out.
+func (r *WebhookRepository) ClaimDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+        FROM webhook_subscriptions s
+        WHERE s.id = d.subscription_id AND d.id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+              AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE active)
+            ORDER BY next_attempt_at, id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + deliveryColumns + `, s.secret`
+	rows, err := r.db.Query(query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.EventID, &d.EventType, &payload, &d.Status,
+			&d.Attempts, &d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatus, &d.LastError, &d.CreatedAt,
+			&d.DeliveredAt, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkDelivered - Record a successful attempt
+func (r *WebhookRepository) MarkDelivered(id int64, status int) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = 'delivered', attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP,
+            last_status = $2, last_error = NULL, delivered_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+    `
+	_, err := r.db.Exec(query, id, status)
+	return err
+}
+
+// MarkFailed - Record a failed attempt (status 0 = no response) and schedule
+// the next one at retryAt, or move the delivery to the dead letters when
+// retryAt is nil
+func (r *WebhookRepository) MarkFailed(id int64, status int, message string, retryAt *time.Time) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+            attempts = attempts + 1, last_attempt_at = CURRENT_TIMESTAMP,
+            last_status = NULLIF($2, 0), last_error = $3,
+            next_attempt_at = COALESCE($4, next_attempt_at)
+        WHERE id = $1
+    `
+	_, err := r.db.Exec(query, id, status, message, retryAt)
+	return err
+}
+
+// catalogEventTypes - Webhook event of each audited action, by entity
+var catalogEventTypes = map[string]map[string]string{
+	models.AuditEntityProduct: {
+		models.AuditCreate:  models.EventProductCreated,
+		models.AuditUpdate:  models.EventProductUpdated,
+		models.AuditDelete:  models.EventProductDeleted,
+		models.AuditRestore: models.EventProductRestored,
+	},
+	models.AuditEntityCategory: {
+		models.AuditCreate:  models.EventCategoryCreated,
+		models.AuditUpdate:  models.EventCategoryUpdated,
+		models.AuditDelete:  models.EventCategoryDeleted,
+		models.AuditRestore: models.EventCategoryRestored,
+	},
+}
+
+// publishCatalogEvent - Queue the webhook event of an audited write. current
+// is the snapshot of the entity after the write.
+func publishCatalogEvent(q querier, entry *models.AuditEntry, current []byte) error {
+	return enqueueEvent(q, catalogEventTypes[entry.Entity][entry.Action], models.CatalogEvent{
+		ID:      entry.EntityID,
+		StoreID: entry.StoreID,
+		Actor:   entry.Actor,
+		Before:  entry.Before,
+		After:   entry.After,
+		Current: current,
+	})
+}
+
+// publishStockChanged - Queue a stock.changed event with the current stock
+// of a product in a store (0 = the central stock)
+func publishStockChanged(q querier, productID int, storeID int, source string) error {
+	event := models.StockEvent{ProductID: productID, Source: source}
+	if storeID != 0 {
+		event.StoreID = &storeID
+	}
+
+	query := "SELECT " + storeStock("$2") + " FROM products p " + storeInventoryJoin("$2") + " WHERE p.id = $1"
+	if err := q.QueryRow(query, productID, storeID).Scan(&event.Stock); err != nil {
+		return err
+	}
+	return enqueueEvent(q, models.EventStockChanged, event)
+}
+
+// enqueueEvent - Add the event to the outbox of every active subscription
+// that asked for it. Called in the transaction of the change, so an event is
+// only delivered when the change was committed.
+func enqueueEvent(q querier, eventType string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	eventID := make([]byte, 16)
+	if _, err := rand.Read(eventID); err != nil {
+		return err
+	}
+	event := models.WebhookEvent{
+		ID:        hex.EncodeToString(eventID),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      encoded,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+        SELECT id, $1, $2, $3 FROM webhook_subscriptions WHERE active AND $2 = ANY (events)
+    `
+	_, err = q.Exec(query, event.ID, event.Type, string(payload))
+	return err
+}
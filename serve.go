@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"cashier-api/alerts"
+	"cashier-api/config"
+	"cashier-api/database"
+	"cashier-api/handlers"
+	"cashier-api/middleware"
+	"cashier-api/services"
+	"cashier-api/storage"
+	"cashier-api/webhooks"
+)
+
+// serve - cashier-api [serve]: run the HTTP server with its background jobs
+// until it fails
+func serve(a *app) error {
+	// Schema changes are applied with the migrate command, not on start
+	if status, err := database.GetMigrationStatus(a.db); err != nil {
+		log.Println("⚠️  Failed to check migrations:", err)
+	} else if pending := pendingMigrations(status); pending > 0 {
+		log.Printf("⚠️  %d pending migrations, run: cashier-api migrate up", pending)
+	}
+
+	registerMetrics(a)
+
+	// Background low-stock checker (LOW_STOCK_CHECK_INTERVAL=0 disables it)
+	if a.cfg.LowStockCheckInterval > 0 {
+		notifier, err := alerts.New(alerts.Config{
+			Names:      splitList(a.cfg.AlertNotifiers),
+			WebhookURL: a.cfg.AlertWebhookURL,
+			SMTPAddr:   a.cfg.AlertSMTPAddr,
+			EmailFrom:  a.cfg.AlertEmailFrom,
+			EmailTo:    splitList(a.cfg.AlertEmailTo),
+		})
+		if err != nil {
+			return fmt.Errorf("invalid alert configuration: %w", err)
+		}
+
+		checker := alerts.NewChecker(a.productRepo, notifier, a.cfg.LowStockCheckInterval, a.cfg.LowStockThreshold)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go checker.Run(ctx)
+	}
+
+	// Background price scheduler (PRICE_SCHEDULE_INTERVAL=0 disables it)
+	if a.cfg.PriceScheduleInterval > 0 {
+		scheduler := services.NewPriceScheduler(a.priceService, a.cfg.PriceScheduleInterval)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go scheduler.Run(ctx)
+	}
+
+	// Background webhook dispatcher (WEBHOOK_DISPATCH_INTERVAL=0 disables it)
+	if a.cfg.WebhookDispatchInterval > 0 {
+		dispatcher := webhooks.NewDispatcher(a.webhookRepo, webhooks.Config{
+			Interval:    a.cfg.WebhookDispatchInterval,
+			Timeout:     a.cfg.WebhookTimeout,
+			MaxAttempts: a.cfg.WebhookMaxAttempts,
+			RetryBase:   a.cfg.WebhookRetryBase,
+			RetryMax:    a.cfg.WebhookRetryMax,
+
+			AllowPrivate: a.cfg.WebhookAllowPrivate,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go dispatcher.Run(ctx)
+	}
+
+	// Setup routes
+	mux := newRouter(a)
+
+	// Start server
+	addr := "0.0.0.0:" + a.cfg.Port
+	fmt.Println("🚀 Cashier API Server running on http://" + addr)
+	fmt.Println("📊 Available endpoints:")
+	fmt.Println("  GET    /health")
+	fmt.Println("  GET    /metrics")
+	fmt.Println("  GET    /openapi.json")
+	fmt.Println("  GET    /docs")
+	fmt.Println("  Products (with category relationships):")
+	fmt.Println("    GET    /api/products")
+	fmt.Println("    POST   /api/products")
+	fmt.Println("    GET    /api/products/{id}")
+	fmt.Println("    PUT    /api/products/{id}")
+	fmt.Println("    PATCH  /api/products/{id}")
+	fmt.Println("    DELETE /api/products/{id}")
+	fmt.Println("    POST   /api/products/{id}/restore")
+	fmt.Println("    POST   /api/products/import")
+	fmt.Println("    GET    /api/products/export")
+	fmt.Println("    POST   /api/products/batch")
+	fmt.Println("    PUT    /api/products/{id}/units")
+	fmt.Println("    POST   /api/products/{id}/stock")
+	fmt.Println("    POST   /api/products/{id}/images")
+	fmt.Println("    DELETE /api/products/{id}/images/{imageID}")
+	fmt.Println("    GET    /api/products/{id}/price-history")
+	fmt.Println("    GET    /api/products/{id}/scheduled-prices")
+	fmt.Println("    POST   /api/products/{id}/scheduled-prices")
+	fmt.Println("    DELETE /api/products/{id}/scheduled-prices/{scheduleID}")
+	fmt.Println("  Variants:")
+	fmt.Println("    PUT    /api/products/{id}/options")
+	fmt.Println("    GET    /api/products/{id}/variants")
+	fmt.Println("    POST   /api/products/{id}/variants")
+	fmt.Println("    PUT    /api/products/{id}/variants/{variantID}")
+	fmt.Println("    DELETE /api/products/{id}/variants/{variantID}")
+	fmt.Println("  Categories:")
+	fmt.Println("    GET    /api/categories")
+	fmt.Println("    POST   /api/categories")
+	fmt.Println("    GET    /api/categories/tree")
+	fmt.Println("    GET    /api/categories/{id}")
+	fmt.Println("    PUT    /api/categories/{id}")
+	fmt.Println("    PATCH  /api/categories/{id}")
+	fmt.Println("    DELETE /api/categories/{id}")
+	fmt.Println("    POST   /api/categories/{id}/restore")
+	fmt.Println("  Stores (send X-Store-ID to work on a store's stock and prices):")
+	fmt.Println("    GET    /api/stores")
+	fmt.Println("    POST   /api/stores")
+	fmt.Println("    GET    /api/stores/{id}")
+	fmt.Println("    PUT    /api/stores/{id}")
+	fmt.Println("  Stock transfers:")
+	fmt.Println("    GET    /api/transfers")
+	fmt.Println("    POST   /api/transfers")
+	fmt.Println("    GET    /api/transfers/{id}")
+	fmt.Println("    PUT    /api/transfers/{id}")
+	fmt.Println("    DELETE /api/transfers/{id}")
+	fmt.Println("    POST   /api/transfers/{id}/ship")
+	fmt.Println("    POST   /api/transfers/{id}/receive")
+	fmt.Println("  Inventory:")
+	fmt.Println("    GET    /api/inventory/low-stock")
+	fmt.Println("  Stock takes:")
+	fmt.Println("    GET    /api/stocktakes")
+	fmt.Println("    POST   /api/stocktakes")
+	fmt.Println("    GET    /api/stocktakes/{id}")
+	fmt.Println("    POST   /api/stocktakes/{id}/counts")
+	fmt.Println("    POST   /api/stocktakes/{id}/finalize")
+	fmt.Println("    GET    /api/stocktakes/{id}/report")
+	fmt.Println("  Audit log:")
+	fmt.Println("    GET    /api/audit")
+	fmt.Println("  Webhooks:")
+	fmt.Println("    GET    /api/webhooks")
+	fmt.Println("    POST   /api/webhooks")
+	fmt.Println("    GET    /api/webhooks/{id}")
+	fmt.Println("    PUT    /api/webhooks/{id}")
+	fmt.Println("    DELETE /api/webhooks/{id}")
+	fmt.Println("    GET    /api/webhooks/{id}/deliveries")
+	fmt.Println("    GET    /api/webhooks/dead-letters")
+	fmt.Println("    POST   /api/webhooks/deliveries/{deliveryID}/retry")
+	fmt.Println("  Live events (Server-Sent Events):")
+	fmt.Println("    GET    /api/events")
+	fmt.Println("  API keys (admin):")
+	fmt.Println("    GET    /api/admin/api-keys")
+	fmt.Println("    POST   /api/admin/api-keys")
+	fmt.Println("    GET    /api/admin/api-keys/{id}")
+	fmt.Println("    DELETE /api/admin/api-keys/{id}")
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newHandler(a, mux),
+		ReadHeaderTimeout: a.cfg.ServerReadHeaderTimeout,
+		ReadTimeout:       a.cfg.ServerReadTimeout,
+		WriteTimeout:      a.cfg.ServerWriteTimeout,
+		IdleTimeout:       a.cfg.ServerIdleTimeout,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	return nil
+}
+
+// registerMetrics - Collectors of the database pool, the inventory and the
+// event stream
+func registerMetrics(a *app) {
+	a.metrics.RegisterDB(a.db)
+	a.metrics.RegisterInventory(a.productRepo, a.cfg.LowStockThreshold)
+	a.metrics.RegisterEventStream(a.broker.Subscribers)
+}
+
+// newHandler - The routes of mux behind the middleware chain
+func newHandler(a *app, mux *router) http.Handler {
+	// Routes with a body limit other than BODY_LIMIT; image uploads are
+	// limited by the handler
+	bodyLimits := map[string]int64{
+		"POST /api/products/batch":       a.cfg.BodyLimitBatch,
+		"POST /api/products/import":      a.cfg.BodyLimitImport,
+		"POST /api/products/{id}/images": 0,
+	}
+
+	return middleware.Chain(mux,
+		middleware.RequestInfo(trustedHops(a.cfg)),
+		middleware.Metrics(a.metrics, mux.ServeMux),
+		middleware.AccessLog(),
+		middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins:   splitList(a.cfg.CORSAllowedOrigins),
+			AllowedMethods:   splitList(a.cfg.CORSAllowedMethods),
+			AllowedHeaders:   splitList(a.cfg.CORSAllowedHeaders),
+			AllowCredentials: a.cfg.CORSAllowCredentials,
+			MaxAge:           a.cfg.CORSMaxAge,
+		}),
+		middleware.Auth(middleware.AuthConfig{
+			Keys:            a.apiKeyService,
+			AdminToken:      a.cfg.AdminToken,
+			AnonymousScopes: splitList(a.cfg.AuthAnonymousScopes),
+			FailureRate:     a.cfg.AuthFailureRPS,
+			FailureBurst:    a.cfg.AuthFailureBurst,
+		}),
+		middleware.RateLimit(middleware.RateLimitConfig{
+			ReadRate:   a.cfg.RateLimitReadRPS,
+			ReadBurst:  a.cfg.RateLimitReadBurst,
+			WriteRate:  a.cfg.RateLimitWriteRPS,
+			WriteBurst: a.cfg.RateLimitWriteBurst,
+			Key:        rateLimitKey,
+		}),
+		middleware.BodyLimit(mux.ServeMux, a.cfg.BodyLimit, bodyLimits),
+		middleware.StoreContext(a.storeRepo),
+	)
+}
+
+// trustedHops - Proxies whose X-Forwarded-For entries are trusted, 0 without
+// TRUST_PROXY
+func trustedHops(cfg *config.Config) int {
+	if !cfg.TrustProxy {
+		return 0
+	}
+	return cfg.TrustProxyHops
+}
+
+// router - ServeMux that remembers its patterns, so that they can be
+// checked against the OpenAPI document
+type router struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (r *router) Handle(pattern string, handler http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+	r.ServeMux.Handle(pattern, handler)
+}
+
+func (r *router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	r.patterns = append(r.patterns, pattern)
+	r.ServeMux.HandleFunc(pattern, handler)
+}
+
+// newRouter - Handlers of the app and their routes
+func newRouter(a *app) *router {
+	categoryHandler := handlers.NewCategoryHandler(a.categoryService)
+	storeHandler := handlers.NewStoreHandler(a.storeService)
+	imageHandler := handlers.NewProductImageHandler(a.imageService)
+	productHandler := handlers.NewProductHandler(a.productService)
+	priceHandler := handlers.NewPriceHandler(a.priceService)
+	variantHandler := handlers.NewVariantHandler(a.variantService)
+	inventoryHandler := handlers.NewInventoryHandler(a.inventoryService)
+	stocktakeHandler := handlers.NewStocktakeHandler(a.stocktakeService)
+	transferHandler := handlers.NewTransferHandler(a.transferService)
+	auditHandler := handlers.NewAuditHandler(a.auditService)
+	webhookHandler := handlers.NewWebhookHandler(a.webhookService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(a.apiKeyService)
+	eventHandler := handlers.NewEventHandler(a.eventService)
+	docsHandler := handlers.NewDocsHandler()
+
+	mux := &router{ServeMux: http.NewServeMux()}
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "OK",
+			"message": "Cashier API Running with Complete Layered Architecture",
+			"version": "2.1.0",
+			"env":     a.cfg.Env,
+		})
+	})
+
+	if a.cfg.MetricsEnabled {
+		mux.Handle("/metrics", a.metrics.Handler())
+	}
+
+	// API documentation
+	if a.cfg.DocsEnabled {
+		mux.HandleFunc("/openapi.json", docsHandler.OpenAPI)
+		mux.HandleFunc("/docs", docsHandler.SwaggerUI)
+		mux.HandleFunc("GET /docs/swagger-ui/{file}", docsHandler.SwaggerUIAsset)
+	}
+
+	// Product routes
+	mux.HandleFunc("/api/products", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			productHandler.HandleProducts(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/products/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			productHandler.HandleProductByID(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("POST /api/products/{id}/restore", productHandler.Restore)
+	mux.HandleFunc("POST /api/products/import", productHandler.Import)
+	mux.HandleFunc("GET /api/products/export", productHandler.Export)
+	mux.HandleFunc("POST /api/products/batch", productHandler.Batch)
+	mux.HandleFunc("PUT /api/products/{id}/units", productHandler.SetPackagingUnits)
+	mux.HandleFunc("POST /api/products/{id}/stock", productHandler.AdjustStock)
+	mux.HandleFunc("POST /api/products/{id}/images", imageHandler.Upload)
+	mux.HandleFunc("DELETE /api/products/{id}/images/{imageID}", imageHandler.Delete)
+
+	mux.HandleFunc("GET /api/products/{id}/price-history", priceHandler.GetHistory)
+	mux.HandleFunc("GET /api/products/{id}/scheduled-prices", priceHandler.GetScheduled)
+	mux.HandleFunc("POST /api/products/{id}/scheduled-prices", priceHandler.Schedule)
+	mux.HandleFunc("DELETE /api/products/{id}/scheduled-prices/{scheduleID}", priceHandler.CancelScheduled)
+
+	// Uploaded files of the local storage driver
+	if local, ok := a.store.(*storage.Local); ok {
+		mux.Handle("GET "+storage.DefaultLocalURL+"/", local.Handler())
+	}
+
+	// Variant routes
+	mux.HandleFunc("PUT /api/products/{id}/options", variantHandler.SetOptionTypes)
+	mux.HandleFunc("GET /api/products/{id}/variants", variantHandler.GetAll)
+	mux.HandleFunc("POST /api/products/{id}/variants", variantHandler.Create)
+	mux.HandleFunc("PUT /api/products/{id}/variants/{variantID}", variantHandler.Update)
+	mux.HandleFunc("DELETE /api/products/{id}/variants/{variantID}", variantHandler.Delete)
+
+	// Category routes
+	mux.HandleFunc("/api/categories", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			categoryHandler.HandleCategories(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/categories/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			categoryHandler.HandleCategoryByID(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("GET /api/categories/tree", categoryHandler.GetTree)
+	mux.HandleFunc("POST /api/categories/{id}/restore", categoryHandler.Restore)
+
+	// Store routes
+	mux.HandleFunc("GET /api/stores", storeHandler.GetAll)
+	mux.HandleFunc("POST /api/stores", storeHandler.Create)
+	mux.HandleFunc("GET /api/stores/{id}", storeHandler.GetByID)
+	mux.HandleFunc("PUT /api/stores/{id}", storeHandler.Update)
+
+	// Stock transfer routes
+	mux.HandleFunc("GET /api/transfers", transferHandler.GetAll)
+	mux.HandleFunc("POST /api/transfers", transferHandler.Create)
+	mux.HandleFunc("GET /api/transfers/{id}", transferHandler.GetByID)
+	mux.HandleFunc("PUT /api/transfers/{id}", transferHandler.Update)
+	mux.HandleFunc("DELETE /api/transfers/{id}", transferHandler.Delete)
+	mux.HandleFunc("POST /api/transfers/{id}/ship", transferHandler.Ship)
+	mux.HandleFunc("POST /api/transfers/{id}/receive", transferHandler.Receive)
+
+	// Inventory routes
+	mux.HandleFunc("GET /api/inventory/low-stock", inventoryHandler.GetLowStock)
+
+	// Stock take routes
+	mux.HandleFunc("GET /api/stocktakes", stocktakeHandler.GetAll)
+	mux.HandleFunc("POST /api/stocktakes", stocktakeHandler.Create)
+	mux.HandleFunc("GET /api/stocktakes/{id}", stocktakeHandler.GetByID)
+	mux.HandleFunc("POST /api/stocktakes/{id}/counts", stocktakeHandler.RecordCounts)
+	mux.HandleFunc("POST /api/stocktakes/{id}/finalize", stocktakeHandler.Finalize)
+	mux.HandleFunc("GET /api/stocktakes/{id}/report", stocktakeHandler.Report)
+
+	// Audit log routes
+	mux.HandleFunc("GET /api/audit", auditHandler.GetAll)
+
+	// Webhook routes
+	mux.HandleFunc("GET /api/webhooks", webhookHandler.GetAll)
+	mux.HandleFunc("POST /api/webhooks", webhookHandler.Create)
+	mux.HandleFunc("GET /api/webhooks/{id}", webhookHandler.GetByID)
+	mux.HandleFunc("PUT /api/webhooks/{id}", webhookHandler.Update)
+	mux.HandleFunc("DELETE /api/webhooks/{id}", webhookHandler.Delete)
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", webhookHandler.GetDeliveries)
+	mux.HandleFunc("GET /api/webhooks/dead-letters", webhookHandler.GetDeadLetters)
+	mux.HandleFunc("POST /api/webhooks/deliveries/{deliveryID}/retry", webhookHandler.RetryDelivery)
+
+	// Event stream routes
+	mux.HandleFunc("GET /api/events", eventHandler.Stream)
+
+	// API key routes (admin scope)
+	mux.HandleFunc("GET /api/admin/api-keys", apiKeyHandler.GetAll)
+	mux.HandleFunc("POST /api/admin/api-keys", apiKeyHandler.Create)
+	mux.HandleFunc("GET /api/admin/api-keys/{id}", apiKeyHandler.GetByID)
+	mux.HandleFunc("DELETE /api/admin/api-keys/{id}", apiKeyHandler.Revoke)
+
+	return mux
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"cashier-api/config"
+	"cashier-api/docs"
+	"cashier-api/metrics"
+	"cashier-api/storage"
+)
+
+// testRouter - Router with every optional route enabled; the handlers are
+// not called, so the services can stay nil
+func testRouter(t *testing.T) *router {
+	t.Helper()
+	store, err := storage.NewLocal(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newRouter(&app{
+		cfg:     &config.Config{DocsEnabled: true, MetricsEnabled: true},
+		metrics: metrics.New(),
+		store:   store,
+	})
+}
+
+func openAPIPaths(t *testing.T) map[string]map[string]json.RawMessage {
+	t.Helper()
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(docs.OpenAPISpec, &spec); err != nil {
+		t.Fatal("invalid openapi.json: ", err)
+	}
+	return spec.Paths
+}
+
+// TestRoutesDocumented - Every registered route has a path (and, for method
+// patterns, an operation) in openapi.json
+func TestRoutesDocumented(t *testing.T) {
+	paths := openAPIPaths(t)
+
+	for _, pattern := range testRouter(t).patterns {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = "", pattern
+		}
+
+		if strings.HasSuffix(path, "/") {
+			// Subtree pattern: some documented path below it
+			found := false
+			for p := range paths {
+				if strings.HasPrefix(p, path) && len(p) > len(path) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("route %q has no path below %s in openapi.json", pattern, path)
+			}
+			continue
+		}
+
+		operations, ok := paths[path]
+		if !ok {
+			t.Errorf("route %q has no path %s in openapi.json", pattern, path)
+			continue
+		}
+		if method != "" {
+			if _, ok := operations[strings.ToLower(method)]; !ok {
+				t.Errorf("route %q has no %s operation in openapi.json", pattern, method)
+			}
+		}
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// TestDocumentedPathsRouted - Every documented operation reaches a route
+func TestDocumentedPathsRouted(t *testing.T) {
+	mux := testRouter(t)
+
+	for path, operations := range openAPIPaths(t) {
+		target := pathParam.ReplaceAllString(path, "1")
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			req := httptest.NewRequest(strings.ToUpper(method), target, nil)
+			if _, pattern := mux.Handler(req); pattern == "" {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+// TestSwaggerUIAssets - /docs loads its script and stylesheet from the binary
+func TestSwaggerUIAssets(t *testing.T) {
+	mux := testRouter(t)
+
+	page := httptest.NewRecorder()
+	mux.ServeHTTP(page, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if strings.Contains(page.Body.String(), "https://") {
+		t.Error("/docs references an external host")
+	}
+
+	for _, asset := range []string{"/docs/swagger-ui/swagger-ui-bundle.js", "/docs/swagger-ui/swagger-ui.css"} {
+		if !strings.Contains(page.Body.String(), asset) {
+			t.Errorf("/docs does not load %s", asset)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, asset, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("GET %s = %d with %d bytes", asset, rec.Code, rec.Body.Len())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"cashier-api/models"
+	"cashier-api/repositories"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// lastUsedResolution - last_used_at is only written when older than this,
+// so a busy key does not cost a write per request
+const lastUsedResolution = time.Minute
+
+type APIKeyService struct {
+	repo      *repositories.APIKeyRepository
+	storeRepo StoreRepository
+}
+
+func NewAPIKeyService(repo *repositories.APIKeyRepository, storeRepo StoreRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, storeRepo: storeRepo}
+}
+
+func (s *APIKeyService) GetAll() ([]models.APIKey, error) {
+	return s.repo.GetAll()
+}
+
+func (s *APIKeyService) GetByID(id int) (*models.APIKey, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	return s.repo.GetByID(id)
+}
+
+// Create - Generate a key with the given scopes, bound to a store when
+// StoreID is set. Only its hash is stored; the returned key is the only place
+// the secret is shown.
+func (s *APIKeyService) Create(input models.APIKeyInput) (*models.APIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, models.ErrAPIKeyName
+	}
+
+	var scopes []string
+	for _, scope := range input.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return nil, fmt.Errorf("%w %q", models.ErrUnknownScope, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, models.ErrAPIKeyScopes
+	}
+	if input.StoreID != nil {
+		if *input.StoreID <= 0 {
+			return nil, models.ErrInvalidStoreID
+		}
+		if _, err := s.storeRepo.GetByID(*input.StoreID); err != nil {
+			return nil, err
+		}
+	}
+
+	secret, prefix, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{Name: name, Prefix: prefix, Scopes: scopes, StoreID: input.StoreID}
+	if err := s.repo.Create(key, hashAPIKey(secret)); err != nil {
+		return nil, err
+	}
+	key.Key = secret
+	return key, nil
+}
+
+// Revoke - Reject the key from now on; it stays listed
+func (s *APIKeyService) Revoke(id int) error {
+	if id <= 0 {
+		return models.ErrInvalidID
+	}
+	return s.repo.Revoke(id)
+}
+
+// Authenticate - Key of a secret sent by a client; unknown and revoked keys
+// are ErrInvalidAPIKey. Implements middleware.KeyAuthenticator.
+func (s *APIKeyService) Authenticate(secret string) (*models.APIKey, error) {
+	key, err := s.repo.GetByHash(hashAPIKey(secret))
+	if err == models.ErrAPIKeyNotFound {
+		return nil, models.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > lastUsedResolution {
+		if err := s.repo.TouchLastUsed(key.ID); err != nil {
+			log.Printf("api keys: failed to record use of key %d: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// newAPIKey - Random key "ck_<prefix>_<secret>" and its prefix
+func newAPIKey() (string, string, error) {
+	b := make([]byte, 30)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(b[:6])
+	return "ck_" + prefix + "_" + hex.EncodeToString(b[6:]), prefix, nil
+}
+
+// hashAPIKey - SHA-256 of the key. Keys are random, so a plain hash cannot
+// be brute-forced like a password.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
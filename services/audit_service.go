@@ -0,0 +1,33 @@
+package services
+
+import (
+	"cashier-api/models"
+	"cashier-api/repositories"
+)
+
+type AuditService struct {
+	repo *repositories.AuditRepository
+}
+
+func NewAuditService(repo *repositories.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// GetAll - Newest audit entries matching the filter. The limit defaults to
+// DefaultAuditLimit and is capped at MaxAuditLimit.
+func (s *AuditService) GetAll(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Entity != "" && filter.Entity != models.AuditEntityProduct && filter.Entity != models.AuditEntityCategory {
+		return nil, models.ErrInvalidAuditEntity
+	}
+	if filter.EntityID != 0 && filter.Entity == "" {
+		return nil, models.ErrAuditIDWithoutType
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = models.DefaultAuditLimit
+	}
+	if filter.Limit > models.MaxAuditLimit {
+		filter.Limit = models.MaxAuditLimit
+	}
+	return s.repo.GetAll(filter)
+}
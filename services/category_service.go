@@ -2,48 +2,175 @@ package services
 
 import (
 	"cashier-api/models"
-	"cashier-api/repositories"
+	"encoding/json"
 )
 
 type CategoryService struct {
-	repo *repositories.CategoryRepository
+	repo CategoryRepository
 }
 
-func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService {
+func NewCategoryService(repo CategoryRepository) *CategoryService {
 	return &CategoryService{repo: repo}
 }
 
-func (s *CategoryService) GetAll() ([]models.Category, error) {
-	return s.repo.GetAll()
+func (s *CategoryService) GetAll(includeDeleted bool) ([]models.Category, error) {
+	return s.repo.GetAll(includeDeleted)
 }
 
-func (s *CategoryService) GetByID(id int) (*models.Category, error) {
+func (s *CategoryService) GetByID(id int, includeDeleted bool) (*models.Category, error) {
 	if id <= 0 {
 		return nil, models.ErrInvalidID
 	}
-	return s.repo.GetByID(id)
+	return s.repo.GetByID(id, includeDeleted)
 }
 
-func (s *CategoryService) Create(category *models.Category) error {
+func (s *CategoryService) Create(category *models.Category, actor models.Actor) error {
 	if category.Name == "" {
 		return models.ErrNameRequired
 	}
-	return s.repo.Create(category)
+	if err := s.validateParent(category); err != nil {
+		return err
+	}
+	if err := s.repo.Create(category, actor); err != nil {
+		return err
+	}
+	s.repo.InvalidateCache()
+	return nil
 }
 
-func (s *CategoryService) Update(category *models.Category) error {
+func (s *CategoryService) Update(category *models.Category, actor models.Actor) error {
 	if category.ID <= 0 {
 		return models.ErrInvalidID
 	}
 	if category.Name == "" {
 		return models.ErrNameRequired
 	}
-	return s.repo.Update(category)
+	if err := s.validateParent(category); err != nil {
+		return err
+	}
+	if err := s.repo.Update(category, actor); err != nil {
+		return err
+	}
+	s.repo.InvalidateCache()
+	return nil
 }
 
-func (s *CategoryService) Delete(id int) error {
+// validateParent - The parent must exist and must not be the category itself
+// or one of its descendants (which would create a cycle). Update repeats the
+// cycle check under lock; this one answers early for the common case.
+func (s *CategoryService) validateParent(category *models.Category) error {
+	if category.ParentID == nil {
+		return nil
+	}
+	parentID := *category.ParentID
+	if parentID <= 0 {
+		return models.ErrParentNotFound
+	}
+
+	if category.ID > 0 {
+		cycle, err := s.repo.IsDescendant(parentID, category.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return models.ErrCategoryCycle
+		}
+	}
+
+	if _, err := s.repo.GetByID(parentID, false); err != nil {
+		if err.Error() == "category not found" {
+			return models.ErrParentNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetTree - Active categories nested under their parents
+func (s *CategoryService) GetTree() ([]*models.CategoryNode, error) {
+	categories, err := s.repo.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int]*models.CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &models.CategoryNode{Category: c, Children: []*models.CategoryNode{}}
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// Delete - version is the expected current version (0 skips the check)
+func (s *CategoryService) Delete(id int, version int, actor models.Actor) error {
 	if id <= 0 {
 		return models.ErrInvalidID
 	}
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id, version, actor); err != nil {
+		return err
+	}
+	s.repo.InvalidateCache()
+	return nil
+}
+
+// Restore - Un-archive a soft deleted category
+func (s *CategoryService) Restore(id int, actor models.Actor) (*models.Category, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if _, err := s.repo.Restore(id, actor); err != nil {
+		return nil, err
+	}
+	s.repo.InvalidateCache()
+	return s.repo.GetByID(id, false)
+}
+
+// Patch - Apply a JSON Merge Patch (RFC 7396) to an existing category and
+// validate the merged result like a full update. version is the expected
+// current version (0 skips the check).
+func (s *CategoryService) Patch(id int, version int, patch []byte, actor models.Actor) (*models.Category, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+
+	current, err := s.repo.GetByID(id, false)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && current.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := applyMergePatch(original, patch)
+	if err != nil {
+		return nil, models.ErrInvalidPatch
+	}
+
+	var category models.Category
+	if err := json.Unmarshal(merged, &category); err != nil {
+		return nil, models.ErrInvalidPatch
+	}
+
+	category.ID = id
+	category.Version = current.Version
+	if err := s.Update(&category, actor); err != nil {
+		return nil, err
+	}
+	return &category, nil
 }
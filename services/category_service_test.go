@@ -0,0 +1,306 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cashier-api/models"
+)
+
+func categoryID(id int) *int {
+	return &id
+}
+
+// newCategoryTree - Food (1) > Snacks (2) > Chips (3), and Drinks (4)
+func newCategoryTree() *fakeCatalog {
+	c := newFakeCatalog()
+	c.categoryRepo.add(models.Category{ID: 1, Name: "Food", Description: "Food and snacks"})
+	c.categoryRepo.add(models.Category{ID: 2, Name: "Snacks", ParentID: categoryID(1)})
+	c.categoryRepo.add(models.Category{ID: 3, Name: "Chips", ParentID: categoryID(2)})
+	c.categoryRepo.add(models.Category{ID: 4, Name: "Drinks"})
+	return c
+}
+
+func TestCategoryPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         int
+		version    int
+		patch      string
+		wantErr    error
+		wantName   string
+		wantDesc   string
+		wantParent *int
+	}{
+		{"description only", 2, 1, `{"description": "Crisps and nuts"}`, nil, "Snacks", "Crisps and nuts", categoryID(1)},
+		{"move", 2, 1, `{"parent_id": 4}`, nil, "Snacks", "", categoryID(4)},
+		{"null makes it a root", 2, 0, `{"parent_id": null}`, nil, "Snacks", "", nil},
+		{"stale version", 2, 2, `{"name": "Crisps"}`, models.ErrVersionMismatch, "", "", nil},
+		{"null name", 2, 1, `{"name": null}`, models.ErrNameRequired, "", "", nil},
+		{"empty name", 2, 1, `{"name": ""}`, models.ErrNameRequired, "", "", nil},
+		{"own parent", 2, 1, `{"parent_id": 2}`, models.ErrCategoryCycle, "", "", nil},
+		{"below a descendant", 1, 1, `{"parent_id": 3}`, models.ErrCategoryCycle, "", "", nil},
+		{"unknown parent", 2, 1, `{"parent_id": 9}`, models.ErrParentNotFound, "", "", nil},
+		{"invalid parent", 2, 1, `{"parent_id": 0}`, models.ErrParentNotFound, "", "", nil},
+		{"wrong type", 2, 1, `{"name": 5}`, models.ErrInvalidPatch, "", "", nil},
+		{"malformed JSON", 2, 1, `{`, models.ErrInvalidPatch, "", "", nil},
+		{"unknown category", 9, 0, `{"name": "Toys"}`, errors.New("category not found"), "", "", nil},
+		{"invalid ID", -1, 0, `{"name": "Toys"}`, models.ErrInvalidID, "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCategoryTree()
+
+			category, err := c.categories.Patch(tt.id, tt.version, []byte(tt.patch), models.Actor{Name: "ayu"})
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.categoryRepo.audit) != 0 {
+					t.Error("rejected patch was saved")
+				}
+				return
+			}
+
+			stored := c.categoryRepo.categories[tt.id]
+			if stored.Name != tt.wantName || stored.Description != tt.wantDesc {
+				t.Errorf("stored %q %q, want %q %q", stored.Name, stored.Description, tt.wantName, tt.wantDesc)
+			}
+			if (stored.ParentID == nil) != (tt.wantParent == nil) ||
+				(stored.ParentID != nil && *stored.ParentID != *tt.wantParent) {
+				t.Errorf("parent %v, want %v", stored.ParentID, tt.wantParent)
+			}
+			if category.Version != 2 {
+				t.Errorf("version %d, want 2", category.Version)
+			}
+		})
+	}
+}
+
+func TestCategoryDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       int
+		version  int
+		archived []int // products archived before the delete
+		wantErr  error
+	}{
+		{"leaf", 4, 1, nil, nil},
+		{"without version check", 4, 0, nil, nil},
+		{"stale version", 4, 2, nil, models.ErrVersionMismatch},
+		{"active children", 2, 0, nil, models.ErrCategoryHasChildren},
+		{"products", 3, 0, nil, errors.New("cannot delete category that has products")},
+		{"archived products only", 3, 0, []int{1}, nil},
+		{"unknown category", 9, 0, nil, errors.New("category not found")},
+		{"invalid ID", 0, 0, nil, models.ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCategoryTree()
+			c.productRepo.add(models.ProductDetail{ID: 1, Name: "Chitato", Price: 9000, Unit: models.UnitPieces, CategoryID: 3})
+			for _, id := range tt.archived {
+				if err := c.products.Delete(id, 0, models.Actor{Name: "ayu"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := c.categories.Delete(tt.id, tt.version, models.Actor{Name: "ayu"})
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.categoryRepo.audit) != 0 {
+					t.Error("rejected delete was saved")
+				}
+				return
+			}
+
+			if _, err := c.categories.GetByID(tt.id, false); err == nil {
+				t.Error("archived category still readable")
+			}
+			if category, err := c.categories.GetByID(tt.id, true); err != nil || category.DeletedAt == nil {
+				t.Errorf("archived category with include_deleted: %+v, %v", category, err)
+			}
+			if list, _ := c.categories.GetAll(false); len(list) != 3 {
+				t.Errorf("%d categories listed, want the 3 active ones", len(list))
+			}
+		})
+	}
+}
+
+func TestCategoryRestore(t *testing.T) {
+	c := newCategoryTree()
+	actor := models.Actor{Name: "ayu"}
+	for _, id := range []int{3, 2} { // leaf first
+		if err := c.categories.Delete(id, 0, actor); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := c.categories.Restore(3, actor); err != models.ErrParentArchived {
+		t.Errorf("child of an archived parent: %v", err)
+	}
+	if _, err := c.categories.Restore(1, actor); err != models.ErrNotDeleted {
+		t.Errorf("active category: %v", err)
+	}
+	if _, err := c.categories.Restore(9, actor); !sameError(err, errors.New("category not found")) {
+		t.Errorf("unknown category: %v", err)
+	}
+	if _, err := c.categories.Restore(0, actor); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+
+	for _, id := range []int{2, 3} { // parent first
+		category, err := c.categories.Restore(id, actor)
+		if err != nil {
+			t.Fatalf("restore %d: %v", id, err)
+		}
+		if category.ID != id || category.DeletedAt != nil || category.Version != 3 {
+			t.Errorf("restored %+v", category)
+		}
+	}
+	if list, _ := c.categories.GetAll(false); len(list) != 4 {
+		t.Errorf("%d categories listed after the restore, want 4", len(list))
+	}
+}
+
+func TestCategoryCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		category models.Category
+		wantErr  error
+	}{
+		{"root", models.Category{Name: "Household"}, nil},
+		{"subcategory", models.Category{Name: "Tea", ParentID: categoryID(4)}, nil},
+		{"below a leaf", models.Category{Name: "Kettle chips", ParentID: categoryID(3)}, nil},
+		{"unknown parent", models.Category{Name: "Tea", ParentID: categoryID(9)}, models.ErrParentNotFound},
+		{"invalid parent", models.Category{Name: "Tea", ParentID: categoryID(-1)}, models.ErrParentNotFound},
+		{"archived parent", models.Category{Name: "Tea", ParentID: categoryID(5)}, models.ErrParentNotFound},
+		{"no name", models.Category{ParentID: categoryID(4)}, models.ErrNameRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCategoryTree()
+			archived := c.categoryRepo.add(models.Category{ID: 5, Name: "Tobacco"})
+			archived.DeletedAt = &time.Time{}
+
+			category := tt.category
+			err := c.categories.Create(&category, models.Actor{Name: "ayu"})
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.categoryRepo.categories) != 5 {
+					t.Error("rejected category was saved")
+				}
+				return
+			}
+			stored := c.categoryRepo.categories[category.ID]
+			if category.ID != 6 || stored == nil || stored.Name != tt.category.Name {
+				t.Fatalf("created %+v, stored %+v", category, stored)
+			}
+			if (stored.ParentID == nil) != (tt.category.ParentID == nil) ||
+				(stored.ParentID != nil && *stored.ParentID != *tt.category.ParentID) {
+				t.Errorf("parent %v, want %v", stored.ParentID, tt.category.ParentID)
+			}
+		})
+	}
+}
+
+func TestCategoryUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		category models.Category
+		wantErr  error
+	}{
+		{"move to another root", models.Category{ID: 3, Name: "Chips", ParentID: categoryID(4)}, nil},
+		{"move a subtree", models.Category{ID: 2, Name: "Snacks", ParentID: categoryID(4)}, nil},
+		{"make a root", models.Category{ID: 3, Name: "Chips"}, nil},
+		{"root under another root", models.Category{ID: 4, Name: "Drinks", ParentID: categoryID(1)}, nil},
+		{"own parent", models.Category{ID: 4, Name: "Drinks", ParentID: categoryID(4)}, models.ErrCategoryCycle},
+		{"below its child", models.Category{ID: 1, Name: "Food", ParentID: categoryID(2)}, models.ErrCategoryCycle},
+		{"below its grandchild", models.Category{ID: 1, Name: "Food", ParentID: categoryID(3)}, models.ErrCategoryCycle},
+		{"unknown parent", models.Category{ID: 3, Name: "Chips", ParentID: categoryID(9)}, models.ErrParentNotFound},
+		{"invalid parent", models.Category{ID: 3, Name: "Chips", ParentID: categoryID(0)}, models.ErrParentNotFound},
+		{"stale version", models.Category{ID: 3, Name: "Chips", Version: 2}, models.ErrVersionMismatch},
+		{"no name", models.Category{ID: 3, ParentID: categoryID(2)}, models.ErrNameRequired},
+		{"invalid ID", models.Category{Name: "Chips"}, models.ErrInvalidID},
+		{"unknown category", models.Category{ID: 9, Name: "Toys"}, errors.New("category not found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCategoryTree()
+
+			category := tt.category
+			err := c.categories.Update(&category, models.Actor{Name: "ayu"})
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.categoryRepo.audit) != 0 {
+					t.Error("rejected update was saved")
+				}
+				return
+			}
+			stored := c.categoryRepo.categories[tt.category.ID]
+			if (stored.ParentID == nil) != (tt.category.ParentID == nil) ||
+				(stored.ParentID != nil && *stored.ParentID != *tt.category.ParentID) {
+				t.Errorf("parent %v, want %v", stored.ParentID, tt.category.ParentID)
+			}
+		})
+	}
+}
+
+// treeIDs - "id(children...)" of the nodes, in order
+func treeIDs(nodes []*models.CategoryNode) string {
+	var b strings.Builder
+	for i, n := range nodes {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(strconv.Itoa(n.ID))
+		if len(n.Children) > 0 {
+			b.WriteString("(" + treeIDs(n.Children) + ")")
+		}
+	}
+	return b.String()
+}
+
+func TestCategoryTree(t *testing.T) {
+	c := newCategoryTree()
+	c.categoryRepo.add(models.Category{ID: 5, Name: "Nuts", ParentID: categoryID(2)})
+	c.categoryRepo.add(models.Category{ID: 6, Name: "Tea", ParentID: categoryID(4)})
+	archived := c.categoryRepo.add(models.Category{ID: 7, Name: "Tobacco", ParentID: categoryID(1)})
+	archived.DeletedAt = &time.Time{}
+
+	tree, err := c.categories.GetTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := treeIDs(tree); got != "1(2(3 5)) 4(6)" {
+		t.Errorf("tree %s, want 1(2(3 5)) 4(6)", got)
+	}
+	if leaf := tree[0].Children[0].Children[0]; leaf.Name != "Chips" || leaf.Children == nil {
+		t.Errorf("leaf %+v, want Chips with empty children", leaf)
+	}
+
+	// Moving a subtree moves it in the tree
+	if err := c.categories.Update(&models.Category{ID: 2, Name: "Snacks", ParentID: categoryID(4)}, models.Actor{Name: "ayu"}); err != nil {
+		t.Fatal(err)
+	}
+	if tree, _ := c.categories.GetTree(); treeIDs(tree) != "1 4(2(3 5) 6)" {
+		t.Errorf("tree after the move %s, want 1 4(2(3 5) 6)", treeIDs(tree))
+	}
+
+	empty, err := newFakeCatalog().categories.GetTree()
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("empty tree %v, %v; want an empty list", empty, err)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"cashier-api/events"
+	"cashier-api/models"
+	"strconv"
+	"strings"
+)
+
+type EventService struct {
+	broker       *events.Broker
+	categoryRepo CategoryRepository
+	storeRepo    StoreRepository
+}
+
+func NewEventService(broker *events.Broker, categoryRepo CategoryRepository,
+	storeRepo StoreRepository) *EventService {
+	return &EventService{
+		broker:       broker,
+		categoryRepo: categoryRepo,
+		storeRepo:    storeRepo,
+	}
+}
+
+// Subscribe - Register a stream client. categoryID (0 = all) matches the
+// category and the descendants it has now; productIDs (empty = all) lists
+// the products of interest. lastEventID is the Last-Event-ID to resume
+// after, empty for a new stream.
+func (s *EventService) Subscribe(categoryID int, productIDs []int, storeID int, lastEventID string) (*events.Subscription, error) {
+	filter := events.Filter{StoreID: storeID}
+
+	if categoryID != 0 {
+		if _, err := s.categoryRepo.GetByID(categoryID, false); err != nil {
+			return nil, err
+		}
+		ids, err := s.categoryRepo.GetSubtreeIDs(categoryID)
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryIDs = map[int]bool{}
+		for _, id := range ids {
+			filter.CategoryIDs[id] = true
+		}
+	}
+
+	if len(productIDs) > 0 {
+		filter.ProductIDs = map[int]bool{}
+		for _, id := range productIDs {
+			if id <= 0 {
+				return nil, models.ErrInvalidID
+			}
+			filter.ProductIDs[id] = true
+		}
+	}
+
+	if storeID != 0 {
+		exists, err := s.storeRepo.Exists(storeID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, models.ErrStoreNotFound
+		}
+	}
+
+	var resumeFrom uint64
+	lastEventID = strings.TrimSpace(lastEventID)
+	if lastEventID != "" {
+		var err error
+		if resumeFrom, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			return nil, models.ErrInvalidLastEventID
+		}
+	}
+
+	return s.broker.Subscribe(filter, resumeFrom, lastEventID != ""), nil
+}
+
+// Unsubscribe - Forget a disconnected client
+func (s *EventService) Unsubscribe(sub *events.Subscription) {
+	s.broker.Unsubscribe(sub)
+}
@@ -0,0 +1,965 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"cashier-api/events"
+	"cashier-api/models"
+)
+
+// In-memory repositories for the service tests. Each embeds its interface,
+// so the methods a test does not reach panic. Writes record the actor name
+// in audit.
+
+type fakeCategoryRepo struct {
+	CategoryRepository
+	categories map[int]*models.Category
+	products   *fakeProductRepo // for the in-use check of Delete
+	nextID     int
+	audit      []string
+}
+
+func newFakeCategoryRepo() *fakeCategoryRepo {
+	return &fakeCategoryRepo{categories: map[int]*models.Category{}, nextID: 1}
+}
+
+// add - Store c as is, bypassing the service
+func (f *fakeCategoryRepo) add(c models.Category) *models.Category {
+	if c.ID == 0 {
+		c.ID = f.nextID
+	}
+	if c.ID >= f.nextID {
+		f.nextID = c.ID + 1
+	}
+	if c.Version == 0 {
+		c.Version = 1
+	}
+	f.categories[c.ID] = &c
+	return &c
+}
+
+func (f *fakeCategoryRepo) InvalidateCache() {}
+
+func (f *fakeCategoryRepo) GetAll(includeDeleted bool) ([]models.Category, error) {
+	list := []models.Category{}
+	for _, c := range f.categories {
+		if includeDeleted || c.DeletedAt == nil {
+			list = append(list, *c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (f *fakeCategoryRepo) GetByID(id int, includeDeleted bool) (*models.Category, error) {
+	c, ok := f.categories[id]
+	if !ok || (!includeDeleted && c.DeletedAt != nil) {
+		return nil, errors.New("category not found")
+	}
+	category := *c
+	return &category, nil
+}
+
+func (f *fakeCategoryRepo) Create(category *models.Category, actor models.Actor) error {
+	category.ID, category.Version = 0, 0
+	*category = *f.add(*category)
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeCategoryRepo) Update(category *models.Category, actor models.Actor) error {
+	c, ok := f.categories[category.ID]
+	if !ok || c.DeletedAt != nil {
+		return errors.New("category not found")
+	}
+	if category.Version != 0 && category.Version != c.Version {
+		return models.ErrVersionMismatch
+	}
+	c.Name, c.Description, c.ParentID = category.Name, category.Description, category.ParentID
+	c.Version++
+	category.Version = c.Version
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeCategoryRepo) Delete(id int, version int, actor models.Actor) error {
+	c, ok := f.categories[id]
+	if !ok || c.DeletedAt != nil {
+		return errors.New("category not found")
+	}
+	if version != 0 && version != c.Version {
+		return models.ErrVersionMismatch
+	}
+	for _, child := range f.categories {
+		if child.ParentID != nil && *child.ParentID == id && child.DeletedAt == nil {
+			return models.ErrCategoryHasChildren
+		}
+	}
+	for _, p := range f.products.products {
+		if inSubtree, _ := f.IsDescendant(p.CategoryID, id); inSubtree && p.DeletedAt == nil {
+			return errors.New("cannot delete category that has products")
+		}
+	}
+	now := time.Now()
+	c.DeletedAt = &now
+	c.Version++
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeCategoryRepo) Restore(id int, actor models.Actor) (int, error) {
+	c, ok := f.categories[id]
+	if !ok {
+		return 0, errors.New("category not found")
+	}
+	if c.DeletedAt == nil {
+		return 0, models.ErrNotDeleted
+	}
+	if c.ParentID != nil && f.categories[*c.ParentID].DeletedAt != nil {
+		return 0, models.ErrParentArchived
+	}
+	c.DeletedAt = nil
+	c.Version++
+	f.audit = append(f.audit, actor.Name)
+	return c.Version, nil
+}
+
+// IsDescendant - Walks up the parents of id
+func (f *fakeCategoryRepo) IsDescendant(id int, ancestorID int) (bool, error) {
+	for c, ok := f.categories[id]; ok; c, ok = f.categories[*c.ParentID] {
+		if c.ID == ancestorID {
+			return true, nil
+		}
+		if c.ParentID == nil {
+			break
+		}
+	}
+	return false, nil
+}
+
+// fakeProductRepo - Products of the central stock; ForStore returns the same
+// repository
+type fakeProductRepo struct {
+	ProductRepository
+	products   map[int]*models.ProductDetail
+	categories *fakeCategoryRepo
+	nextID     int
+	audit      []string
+	batches    int                  // RunBatch calls
+	prices     []models.PriceChange // price history, oldest first
+
+	storeStock map[int]map[int]models.Quantity // by store and product ID
+}
+
+func newFakeProductRepo(categories *fakeCategoryRepo) *fakeProductRepo {
+	return &fakeProductRepo{products: map[int]*models.ProductDetail{}, categories: categories, nextID: 1,
+		storeStock: map[int]map[int]models.Quantity{}}
+}
+
+// add - Store p as is, bypassing the service
+func (f *fakeProductRepo) add(p models.ProductDetail) *models.ProductDetail {
+	if p.ID == 0 {
+		p.ID = f.nextID
+	}
+	if p.ID >= f.nextID {
+		f.nextID = p.ID + 1
+	}
+	if p.Version == 0 {
+		p.Version = 1
+	}
+	if p.Unit == "" {
+		p.Unit = models.DefaultUnit
+	}
+	if p.OptionTypes == nil {
+		p.OptionTypes = []string{}
+	}
+	if p.PackagingUnits == nil {
+		p.PackagingUnits = []models.PackagingUnit{}
+	}
+	f.products[p.ID] = &p
+	return &p
+}
+
+func (f *fakeProductRepo) ForStore(storeID int) ProductRepository {
+	return &fakeStoreProductRepo{fakeProductRepo: f, storeID: storeID}
+}
+
+func (f *fakeProductRepo) InvalidateCache() {}
+
+func (f *fakeProductRepo) GetAll(filter models.ProductFilter) ([]models.ProductList, error) {
+	list := []models.ProductList{}
+	for _, p := range f.products {
+		if filter.IncludeDeleted || p.DeletedAt == nil {
+			list = append(list, models.ProductList{ID: p.ID, Name: p.Name, Price: p.Price, Stock: p.Stock,
+				Unit: p.Unit, DeletedAt: p.DeletedAt})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (f *fakeProductRepo) GetByID(id int, includeDeleted bool) (*models.ProductDetail, error) {
+	p, ok := f.products[id]
+	if !ok || (!includeDeleted && p.DeletedAt != nil) {
+		return nil, errors.New("product not found")
+	}
+	product := *p
+	product.OptionTypes = append([]string{}, p.OptionTypes...)
+	product.PackagingUnits = append([]models.PackagingUnit{}, p.PackagingUnits...)
+	return &product, nil
+}
+
+func (f *fakeProductRepo) CheckCategoryExists(categoryID int) (bool, error) {
+	_, err := f.categories.GetByID(categoryID, false)
+	return err == nil, nil
+}
+
+func (f *fakeProductRepo) Create(product *models.Product, actor models.Actor) error {
+	stored := f.add(models.ProductDetail{
+		Name: product.Name, Price: product.Price, Stock: product.Stock, Unit: product.Unit,
+		CategoryID: product.CategoryID, ReorderLevel: product.ReorderLevel, ReorderQuantity: product.ReorderQuantity,
+	})
+	product.ID, product.Version = stored.ID, stored.Version
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeProductRepo) Update(product *models.Product, actor models.Actor) error {
+	p, ok := f.products[product.ID]
+	if !ok || p.DeletedAt != nil {
+		return errors.New("product not found")
+	}
+	if product.Version != 0 && product.Version != p.Version {
+		return models.ErrVersionMismatch
+	}
+	if p.Price != product.Price {
+		f.recordPrice(models.PriceChange{ProductID: p.ID, OldPrice: p.Price, NewPrice: product.Price,
+			ChangedBy: optionalActor(actor.Name), Source: models.PriceSourceManual})
+	}
+	p.Name, p.Price, p.Stock, p.Unit, p.CategoryID = product.Name, product.Price, product.Stock, product.Unit, product.CategoryID
+	p.ReorderLevel, p.ReorderQuantity = product.ReorderLevel, product.ReorderQuantity
+	p.Version++
+	product.Version = p.Version
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+// moveStoreStock - Add delta to the stock of a product in a store
+func (f *fakeProductRepo) moveStoreStock(storeID int, productID int, delta models.Quantity) error {
+	if f.storeStock[storeID] == nil {
+		f.storeStock[storeID] = map[int]models.Quantity{}
+	}
+	if f.storeStock[storeID][productID]+delta < 0 {
+		return models.ErrInsufficientStock
+	}
+	f.storeStock[storeID][productID] += delta
+	return nil
+}
+
+func (f *fakeProductRepo) recordPrice(change models.PriceChange) models.PriceChange {
+	change.ID = len(f.prices) + 1
+	change.ChangedAt = time.Now()
+	f.prices = append(f.prices, change)
+	return change
+}
+
+func (f *fakeProductRepo) Delete(id int, version int, actor models.Actor) error {
+	p, ok := f.products[id]
+	if !ok || p.DeletedAt != nil {
+		return errors.New("product not found")
+	}
+	if version != 0 && version != p.Version {
+		return models.ErrVersionMismatch
+	}
+	now := time.Now()
+	p.DeletedAt = &now
+	p.Version++
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeProductRepo) Restore(id int, actor models.Actor) (int, error) {
+	p, ok := f.products[id]
+	if !ok {
+		return 0, errors.New("product not found")
+	}
+	if p.DeletedAt == nil {
+		return 0, models.ErrNotDeleted
+	}
+	if f.categories.categories[p.CategoryID].DeletedAt != nil {
+		return 0, models.ErrCategoryArchived
+	}
+	p.DeletedAt = nil
+	p.Version++
+	f.audit = append(f.audit, actor.Name)
+	return p.Version, nil
+}
+
+// fakeStoreProductRepo - The products as a store sees them: only the stock
+// differs from the central view
+type fakeStoreProductRepo struct {
+	*fakeProductRepo
+	storeID int
+}
+
+func (f *fakeStoreProductRepo) GetByID(id int, includeDeleted bool) (*models.ProductDetail, error) {
+	product, err := f.fakeProductRepo.GetByID(id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	product.Stock = f.storeStock[f.storeID][id]
+	product.StoreID = &f.storeID
+	return product, nil
+}
+
+func (f *fakeProductRepo) GetStocks(ids []int) (map[int]models.Quantity, error) {
+	stocks := map[int]models.Quantity{}
+	for _, id := range ids {
+		if p, ok := f.products[id]; ok {
+			stocks[id] = p.Stock
+		}
+	}
+	return stocks, nil
+}
+
+// RunBatch - fn in a "transaction": the products are restored when it fails
+func (f *fakeProductRepo) RunBatch(actor models.Actor, fn func(b ProductBatch) error) error {
+	f.batches++
+	undo := f.snapshot()
+	if err := fn(&fakeProductBatch{repo: f, actor: actor}); err != nil {
+		undo()
+		return err
+	}
+	return nil
+}
+
+// snapshot - Returns a func putting the products back as they are now
+func (f *fakeProductRepo) snapshot() func() {
+	products := map[int]models.ProductDetail{}
+	for id, p := range f.products {
+		products[id] = *p
+	}
+	nextID, audit := f.nextID, len(f.audit)
+	return func() {
+		f.products = map[int]*models.ProductDetail{}
+		for id, p := range products {
+			f.products[id] = &p
+		}
+		f.nextID, f.audit = nextID, f.audit[:audit]
+	}
+}
+
+// fakeProductBatch - ProductBatch on the fake, with savepoints as snapshots
+type fakeProductBatch struct {
+	repo  *fakeProductRepo
+	actor models.Actor
+}
+
+func (b *fakeProductBatch) Create(product *models.Product) error {
+	return b.repo.Create(product, b.actor)
+}
+
+func (b *fakeProductBatch) Update(product *models.Product) error {
+	return b.repo.Update(product, b.actor)
+}
+
+func (b *fakeProductBatch) Delete(id int, version int) error {
+	return b.repo.Delete(id, version, b.actor)
+}
+
+func (b *fakeProductBatch) CheckCategoryExists(categoryID int) (bool, error) {
+	return b.repo.CheckCategoryExists(categoryID)
+}
+
+func (b *fakeProductBatch) Savepoint(fn func() error) error {
+	undo := b.repo.snapshot()
+	if err := fn(); err != nil {
+		undo()
+		return err
+	}
+	return nil
+}
+
+func (f *fakeProductRepo) SetOptionTypes(id int, optionTypes []string, actor models.Actor) error {
+	f.products[id].OptionTypes = optionTypes
+	f.products[id].Version++
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeProductRepo) SetPackagingUnits(productID int, units []models.PackagingUnit, actor models.Actor) error {
+	f.products[productID].PackagingUnits = units
+	f.products[productID].Version++
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+// fakeVariantRepo - failCreate, when set, fails the Create of that SKU.
+// products, when set, gives the effective prices.
+type fakeVariantRepo struct {
+	VariantRepository
+	variants   map[int][]models.Variant // by product ID
+	products   *fakeProductRepo
+	nextID     int
+	failCreate string
+	audit      []string
+}
+
+func newFakeVariantRepo() *fakeVariantRepo {
+	return &fakeVariantRepo{variants: map[int][]models.Variant{}, nextID: 1}
+}
+
+func (f *fakeVariantRepo) GetByProductID(productID int) ([]models.Variant, error) {
+	variants := []models.Variant{}
+	for _, v := range f.variants[productID] {
+		variants = append(variants, f.withPrice(v))
+	}
+	return variants, nil
+}
+
+func (f *fakeVariantRepo) GetByID(productID int, id int) (*models.Variant, error) {
+	for _, v := range f.variants[productID] {
+		if v.ID == id {
+			v = f.withPrice(v)
+			return &v, nil
+		}
+	}
+	return nil, models.ErrVariantNotFound
+}
+
+func (f *fakeVariantRepo) withPrice(v models.Variant) models.Variant {
+	if v.Price != nil {
+		v.EffectivePrice = *v.Price
+	} else if f.products != nil {
+		v.EffectivePrice = f.products.products[v.ProductID].Price
+	}
+	return v
+}
+
+func (f *fakeVariantRepo) CountByProductID(productID int) (int, error) {
+	return len(f.variants[productID]), nil
+}
+
+func (f *fakeVariantRepo) Create(variant *models.Variant, actor models.Actor) error {
+	if variant.SKU == f.failCreate {
+		return errors.New("connection reset")
+	}
+	if err := f.checkUnique(variant); err != nil {
+		return err
+	}
+	variant.ID = f.nextID
+	f.nextID++
+	f.variants[variant.ProductID] = append(f.variants[variant.ProductID], *variant)
+	f.audit = append(f.audit, actor.Name)
+	return nil
+}
+
+func (f *fakeVariantRepo) Update(variant *models.Variant, actor models.Actor) error {
+	variants := f.variants[variant.ProductID]
+	for i := range variants {
+		if variants[i].ID == variant.ID {
+			if err := f.checkUnique(variant); err != nil {
+				return err
+			}
+			variants[i] = *variant
+			f.audit = append(f.audit, actor.Name)
+			return nil
+		}
+	}
+	return models.ErrVariantNotFound
+}
+
+func (f *fakeVariantRepo) Delete(productID int, id int, actor models.Actor) error {
+	variants := f.variants[productID]
+	for i := range variants {
+		if variants[i].ID == id {
+			f.variants[productID] = append(variants[:i:i], variants[i+1:]...)
+			f.audit = append(f.audit, actor.Name)
+			return nil
+		}
+	}
+	return models.ErrVariantNotFound
+}
+
+// checkUnique - The unique indexes: SKU and barcode overall, options within
+// the product
+func (f *fakeVariantRepo) checkUnique(variant *models.Variant) error {
+	for _, variants := range f.variants {
+		for _, v := range variants {
+			if v.ID == variant.ID {
+				continue
+			}
+			if v.SKU == variant.SKU || (v.Barcode != nil && variant.Barcode != nil && *v.Barcode == *variant.Barcode) {
+				return models.ErrDuplicateSKU
+			}
+			if v.ProductID == variant.ProductID && maps.Equal(v.Options, variant.Options) {
+				return models.ErrDuplicateVariant
+			}
+		}
+	}
+	return nil
+}
+
+type fakeImageRepo struct{ ProductImageRepository }
+
+func (fakeImageRepo) GetByProductID(productID int) ([]models.ProductImage, error) {
+	return []models.ProductImage{}, nil
+}
+
+type fakeStoreRepo struct {
+	StoreRepository
+	stores []models.Store
+}
+
+func (f *fakeStoreRepo) GetAll() ([]models.Store, error) {
+	return append([]models.Store{}, f.stores...), nil
+}
+
+func (f *fakeStoreRepo) GetByID(id int) (*models.Store, error) {
+	for _, s := range f.stores {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, models.ErrStoreNotFound
+}
+
+func (f *fakeStoreRepo) Exists(id int) (bool, error) {
+	for _, s := range f.stores {
+		if s.ID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStoreRepo) Create(store *models.Store) error {
+	store.ID = len(f.stores) + 1
+	f.stores = append(f.stores, *store)
+	return nil
+}
+
+// fakeCatalog - The fake repositories with the services on top of them
+type fakeCatalog struct {
+	categoryRepo *fakeCategoryRepo
+	productRepo  *fakeProductRepo
+	variantRepo  *fakeVariantRepo
+	storeRepo    *fakeStoreRepo
+	broker       *events.Broker
+
+	categories *CategoryService
+	products   *ProductService
+	variants   *VariantService
+	stores     *StoreService
+}
+
+func newFakeCatalog() *fakeCatalog {
+	c := &fakeCatalog{
+		categoryRepo: newFakeCategoryRepo(),
+		variantRepo:  newFakeVariantRepo(),
+		storeRepo:    &fakeStoreRepo{},
+		broker:       events.NewBroker(100),
+	}
+	c.productRepo = newFakeProductRepo(c.categoryRepo)
+	c.categoryRepo.products = c.productRepo
+	c.variantRepo.products = c.productRepo
+
+	images := NewProductImageService(fakeImageRepo{}, c.productRepo, nil, 0)
+	c.categories = NewCategoryService(c.categoryRepo)
+	c.products = NewProductService(c.productRepo, c.categoryRepo, c.variantRepo, images, c.broker)
+	c.variants = NewVariantService(c.variantRepo, c.productRepo)
+	c.stores = NewStoreService(c.storeRepo)
+	return c
+}
+
+// fakeStocktakeRepo - Stock takes of the products of a fakeProductRepo. The
+// stock of store stock takes is the central stock too.
+type fakeStocktakeRepo struct {
+	StocktakeRepository
+	stocktakes map[int]*models.Stocktake
+	items      map[int][]models.StocktakeItem // by stock take ID, in report order
+	products   *fakeProductRepo
+	nextID     int
+}
+
+func newFakeStocktakeRepo(products *fakeProductRepo) *fakeStocktakeRepo {
+	return &fakeStocktakeRepo{stocktakes: map[int]*models.Stocktake{}, items: map[int][]models.StocktakeItem{},
+		products: products, nextID: 1}
+}
+
+func (f *fakeStocktakeRepo) GetAll() ([]models.Stocktake, error) {
+	list := []models.Stocktake{}
+	for id := f.nextID - 1; id > 0; id-- {
+		stocktake, _ := f.GetByID(id)
+		list = append(list, *stocktake)
+	}
+	return list, nil
+}
+
+func (f *fakeStocktakeRepo) GetByID(id int) (*models.Stocktake, error) {
+	s, ok := f.stocktakes[id]
+	if !ok {
+		return nil, models.ErrStocktakeNotFound
+	}
+	stocktake := *s
+	stocktake.ItemCount, stocktake.CountedItems = len(f.items[id]), 0
+	for _, item := range f.items[id] {
+		if item.Counted != nil {
+			stocktake.CountedItems++
+		}
+	}
+	return &stocktake, nil
+}
+
+func (f *fakeStocktakeRepo) GetItems(id int) ([]models.StocktakeItem, error) {
+	return append([]models.StocktakeItem{}, f.items[id]...), nil
+}
+
+func (f *fakeStocktakeRepo) Create(stocktake *models.Stocktake) error {
+	for _, s := range f.stocktakes {
+		if s.Status == models.StocktakeOpen && (s.StoreID == nil) == (stocktake.StoreID == nil) &&
+			(s.StoreID == nil || *s.StoreID == *stocktake.StoreID) {
+			return models.ErrStocktakeOpenExists
+		}
+	}
+
+	stocktake.ID = f.nextID
+	f.nextID++
+	stocktake.Status = models.StocktakeOpen
+	stocktake.CreatedAt = time.Now()
+
+	items := []models.StocktakeItem{}
+	for _, p := range f.products.products {
+		if p.DeletedAt != nil {
+			continue
+		}
+		if stocktake.CategoryID != nil {
+			if inSubtree, _ := f.products.categories.IsDescendant(p.CategoryID, *stocktake.CategoryID); !inSubtree {
+				continue
+			}
+		}
+		items = append(items, models.StocktakeItem{
+			ProductID: p.ID, ProductName: p.Name, CategoryID: p.CategoryID,
+			CategoryName: f.products.categories.categories[p.CategoryID].Name,
+			Unit:         p.Unit, Price: p.Price, Expected: p.Stock,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CategoryName != items[j].CategoryName {
+			return items[i].CategoryName < items[j].CategoryName
+		}
+		return items[i].ProductName < items[j].ProductName
+	})
+
+	stored := *stocktake
+	f.stocktakes[stocktake.ID] = &stored
+	f.items[stocktake.ID] = items
+	stocktake.ItemCount = len(items)
+	return nil
+}
+
+// RecordCounts - All counts or none, like the transaction
+func (f *fakeStocktakeRepo) RecordCounts(id int, counts []models.StocktakeCount) error {
+	if err := f.checkOpen(id); err != nil {
+		return err
+	}
+
+	items := append([]models.StocktakeItem{}, f.items[id]...)
+	for _, count := range counts {
+		i := slices.IndexFunc(items, func(item models.StocktakeItem) bool { return item.ProductID == count.ProductID })
+		if i < 0 {
+			return models.ErrStocktakeProduct
+		}
+		counted := count.Quantity
+		if count.Mode != models.CountModeSet && items[i].Counted != nil {
+			counted += *items[i].Counted
+		}
+		if counted < 0 {
+			return models.ErrNegativeCount
+		}
+		now := time.Now()
+		items[i].Counted, items[i].CountedAt = &counted, &now
+	}
+	f.items[id] = items
+	return nil
+}
+
+func (f *fakeStocktakeRepo) Finalize(id int) error {
+	if err := f.checkOpen(id); err != nil {
+		return err
+	}
+
+	items := f.items[id]
+	for i := range items {
+		if items[i].Counted == nil || *items[i].Counted == items[i].Expected {
+			continue
+		}
+		product := f.products.products[items[i].ProductID]
+		applied := *items[i].Counted - items[i].Expected
+		if product.Stock+applied < 0 {
+			applied = -product.Stock
+		}
+		product.Stock += applied
+		items[i].Applied = &applied
+	}
+
+	now := time.Now()
+	f.stocktakes[id].Status = models.StocktakeFinalized
+	f.stocktakes[id].FinalizedAt = &now
+	return nil
+}
+
+func (f *fakeStocktakeRepo) checkOpen(id int) error {
+	s, ok := f.stocktakes[id]
+	if !ok {
+		return models.ErrStocktakeNotFound
+	}
+	if s.Status != models.StocktakeOpen {
+		return models.ErrStocktakeNotOpen
+	}
+	return nil
+}
+
+// fakePriceRepo - Scheduled prices of the products of a fakeProductRepo,
+// which keeps the price history
+type fakePriceRepo struct {
+	PriceRepository
+	scheduled []*models.ScheduledPrice
+	products  *fakeProductRepo
+}
+
+func (f *fakePriceRepo) GetHistory(productID int) ([]models.PriceChange, error) {
+	changes := []models.PriceChange{}
+	for i := len(f.products.prices) - 1; i >= 0; i-- {
+		if f.products.prices[i].ProductID == productID {
+			changes = append(changes, f.products.prices[i])
+		}
+	}
+	return changes, nil
+}
+
+func (f *fakePriceRepo) GetScheduled(productID int) ([]models.ScheduledPrice, error) {
+	scheduled := []models.ScheduledPrice{}
+	for _, s := range f.scheduled {
+		if s.ProductID == productID && s.AppliedAt == nil {
+			scheduled = append(scheduled, *s)
+		}
+	}
+	sort.SliceStable(scheduled, func(i, j int) bool { return scheduled[i].EffectiveFrom.Before(scheduled[j].EffectiveFrom) })
+	return scheduled, nil
+}
+
+func (f *fakePriceRepo) CreateScheduled(scheduled *models.ScheduledPrice) error {
+	for _, s := range f.scheduled {
+		if s.ProductID == scheduled.ProductID && s.EffectiveFrom.Equal(scheduled.EffectiveFrom) && s.AppliedAt == nil {
+			return models.ErrScheduledPriceExists
+		}
+	}
+	scheduled.ID = len(f.scheduled) + 1
+	scheduled.CreatedAt = time.Now()
+	stored := *scheduled
+	f.scheduled = append(f.scheduled, &stored)
+	return nil
+}
+
+func (f *fakePriceRepo) DeleteScheduled(productID int, id int) error {
+	for i, s := range f.scheduled {
+		if s.ProductID == productID && s.ID == id {
+			if s.AppliedAt != nil {
+				return models.ErrScheduledPriceApplied
+			}
+			f.scheduled = append(f.scheduled[:i:i], f.scheduled[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrScheduledPriceNotFound
+}
+
+// ApplyDue - Like the repository: oldest first, failures marked and joined
+func (f *fakePriceRepo) ApplyDue() ([]models.PriceChange, error) {
+	due := []*models.ScheduledPrice{}
+	for _, s := range f.scheduled {
+		if s.AppliedAt == nil && s.FailedAt == nil && !s.EffectiveFrom.After(time.Now()) {
+			due = append(due, s)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].EffectiveFrom.Before(due[j].EffectiveFrom) })
+
+	var changes []models.PriceChange
+	var failures []error
+	for _, s := range due {
+		now := time.Now()
+		product, ok := f.products.products[s.ProductID]
+		if !ok {
+			lastError := "sql: no rows in result set"
+			s.FailedAt, s.LastError = &now, &lastError
+			failures = append(failures, fmt.Errorf("scheduled price %d of product %d: %s", s.ID, s.ProductID, lastError))
+			continue
+		}
+		s.AppliedAt = &now
+		if product.Price == s.Price {
+			continue
+		}
+		change := f.products.recordPrice(models.PriceChange{ProductID: s.ProductID, OldPrice: product.Price,
+			NewPrice: s.Price, ChangedBy: s.CreatedBy, Source: models.PriceSourceScheduled, ScheduledPriceID: &s.ID})
+		product.Price = s.Price
+		product.Version++
+		changes = append(changes, change)
+	}
+	return changes, errors.Join(failures...)
+}
+
+// fakeTransferRepo - Transfers moving the store stock of a fakeProductRepo
+type fakeTransferRepo struct {
+	TransferRepository
+	transfers map[int]*models.Transfer
+	lines     map[int][]models.TransferLine // by transfer ID, in product name order
+	products  *fakeProductRepo
+	stores    *fakeStoreRepo
+	nextID    int
+}
+
+func newFakeTransferRepo(products *fakeProductRepo, stores *fakeStoreRepo) *fakeTransferRepo {
+	return &fakeTransferRepo{transfers: map[int]*models.Transfer{}, lines: map[int][]models.TransferLine{},
+		products: products, stores: stores, nextID: 1}
+}
+
+func (f *fakeTransferRepo) GetAll(filter models.TransferFilter) ([]models.Transfer, error) {
+	list := []models.Transfer{}
+	for id := f.nextID - 1; id > 0; id-- {
+		t, ok := f.transfers[id]
+		if !ok || (filter.Status != "" && t.Status != filter.Status) ||
+			(filter.StoreID != 0 && filter.StoreID != t.SourceStoreID && filter.StoreID != t.DestinationStoreID) {
+			continue
+		}
+		list = append(list, *t)
+	}
+	return list, nil
+}
+
+func (f *fakeTransferRepo) GetByID(id int) (*models.Transfer, error) {
+	t, ok := f.transfers[id]
+	if !ok {
+		return nil, models.ErrTransferNotFound
+	}
+	transfer := *t
+	return &transfer, nil
+}
+
+func (f *fakeTransferRepo) GetLines(id int) ([]models.TransferLine, error) {
+	return append([]models.TransferLine{}, f.lines[id]...), nil
+}
+
+func (f *fakeTransferRepo) Create(transfer *models.Transfer) error {
+	transfer.ID = f.nextID
+	f.nextID++
+	transfer.Status = models.TransferDraft
+	transfer.CreatedAt = time.Now()
+	f.save(transfer)
+	return nil
+}
+
+func (f *fakeTransferRepo) Update(transfer *models.Transfer) error {
+	current, err := f.lock(transfer.ID, models.TransferDraft)
+	if err != nil {
+		return err
+	}
+	transfer.Status, transfer.CreatedBy, transfer.CreatedAt = current.Status, current.CreatedBy, current.CreatedAt
+	f.save(transfer)
+	return nil
+}
+
+func (f *fakeTransferRepo) Delete(id int) error {
+	if _, err := f.lock(id, models.TransferDraft); err != nil {
+		return err
+	}
+	delete(f.transfers, id)
+	delete(f.lines, id)
+	return nil
+}
+
+// Ship - All lines or none, like the transaction
+func (f *fakeTransferRepo) Ship(id int, shippedBy *string) error {
+	transfer, err := f.lock(id, models.TransferDraft)
+	if err != nil {
+		return err
+	}
+	for _, line := range f.lines[id] {
+		if f.products.storeStock[transfer.SourceStoreID][line.ProductID] < line.Quantity {
+			return models.ErrInsufficientStock
+		}
+	}
+	for _, line := range f.lines[id] {
+		f.products.moveStoreStock(transfer.SourceStoreID, line.ProductID, -line.Quantity)
+	}
+	now := time.Now()
+	transfer.Status, transfer.ShippedBy, transfer.ShippedAt = models.TransferShipped, shippedBy, &now
+	return nil
+}
+
+func (f *fakeTransferRepo) Receive(id int, received map[int]models.Quantity, receivedBy *string) error {
+	transfer, err := f.lock(id, models.TransferShipped)
+	if err != nil {
+		return err
+	}
+	lines := f.lines[id]
+	for productID, quantity := range received {
+		i := slices.IndexFunc(lines, func(line models.TransferLine) bool { return line.ProductID == productID })
+		if i < 0 {
+			return models.ErrTransferProduct
+		}
+		if quantity > lines[i].Quantity {
+			return models.ErrReceivedExceedsShipped
+		}
+	}
+	for i := range lines {
+		quantity, ok := received[lines[i].ProductID]
+		if !ok {
+			quantity = lines[i].Quantity
+		}
+		lines[i].Received = &quantity
+		f.products.moveStoreStock(transfer.DestinationStoreID, lines[i].ProductID, quantity)
+	}
+	now := time.Now()
+	transfer.Status, transfer.ReceivedBy, transfer.ReceivedAt = models.TransferReceived, receivedBy, &now
+	return nil
+}
+
+func (f *fakeTransferRepo) lock(id int, status string) (*models.Transfer, error) {
+	transfer, ok := f.transfers[id]
+	if !ok {
+		return nil, models.ErrTransferNotFound
+	}
+	if transfer.Status != status {
+		if status == models.TransferDraft {
+			return nil, models.ErrTransferNotDraft
+		}
+		return nil, models.ErrTransferNotShipped
+	}
+	return transfer, nil
+}
+
+// save - Store transfer with the store names and its lines with the product
+// names and units
+func (f *fakeTransferRepo) save(transfer *models.Transfer) {
+	stored := *transfer
+	source, _ := f.stores.GetByID(stored.SourceStoreID)
+	destination, _ := f.stores.GetByID(stored.DestinationStoreID)
+	stored.SourceStoreName, stored.DestinationStoreName = source.Name, destination.Name
+	stored.Lines = nil
+	f.transfers[stored.ID] = &stored
+
+	lines := []models.TransferLine{}
+	for _, line := range transfer.Lines {
+		product := f.products.products[line.ProductID]
+		line.ProductName, line.Unit = product.Name, product.Unit
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].ProductName < lines[j].ProductName })
+	f.lines[stored.ID] = lines
+}
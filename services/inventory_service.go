@@ -0,0 +1,48 @@
+package services
+
+import (
+	"cashier-api/models"
+)
+
+type InventoryService struct {
+	productRepo ProductRepository
+
+	// defaultReorderLevel - Used for products without their own reorder level
+	defaultReorderLevel int
+}
+
+func NewInventoryService(productRepo ProductRepository, defaultReorderLevel int) *InventoryService {
+	return &InventoryService{
+		productRepo:         productRepo,
+		defaultReorderLevel: defaultReorderLevel,
+	}
+}
+
+// ForStore - Service reporting on the stock of a store (0 = the central stock)
+func (s *InventoryService) ForStore(storeID int) *InventoryService {
+	scoped := *s
+	scoped.productRepo = s.productRepo.ForStore(storeID)
+	return &scoped
+}
+
+// GetLowStock - Products at or below their reorder level, grouped by category
+func (s *InventoryService) GetLowStock() ([]models.LowStockGroup, error) {
+	items, err := s.productRepo.GetLowStock(s.defaultReorderLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Items are ordered by category, so each group is a consecutive run
+	groups := []models.LowStockGroup{}
+	for _, item := range items {
+		if len(groups) == 0 || groups[len(groups)-1].CategoryID != item.CategoryID {
+			groups = append(groups, models.LowStockGroup{
+				CategoryID:   item.CategoryID,
+				CategoryName: item.CategoryName,
+			})
+		}
+		group := &groups[len(groups)-1]
+		group.Items = append(group.Items, item)
+	}
+	return groups, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import "encoding/json"
+
+// applyMergePatch - Apply a JSON Merge Patch (RFC 7396) to a JSON document
+func applyMergePatch(original, patch []byte) ([]byte, error) {
+	var target, patchValue interface{}
+	if err := json.Unmarshal(original, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		// A non-object patch replaces the target entirely
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// PriceScheduler - Periodically applies due scheduled prices. A price takes
+// effect at most one interval after its effective time.
+type PriceScheduler struct {
+	service  *PriceService
+	interval time.Duration
+}
+
+func NewPriceScheduler(service *PriceService, interval time.Duration) *PriceScheduler {
+	return &PriceScheduler{service: service, interval: interval}
+}
+
+// Run - Apply immediately and then every interval until ctx is cancelled
+func (p *PriceScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		changes, err := p.service.ApplyDue()
+		for _, change := range changes {
+			log.Printf("prices: product %d price %d -> %d (scheduled price %d)",
+				change.ProductID, change.OldPrice, change.NewPrice, *change.ScheduledPriceID)
+		}
+		if err != nil {
+			log.Printf("prices: applying scheduled prices failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"cashier-api/events"
+	"cashier-api/models"
+	"time"
+)
+
+type PriceService struct {
+	priceRepo   PriceRepository
+	productRepo ProductRepository
+	broker      *events.Broker // nil = no event stream
+}
+
+func NewPriceService(priceRepo PriceRepository, productRepo ProductRepository,
+	broker *events.Broker) *PriceService {
+	return &PriceService{
+		priceRepo:   priceRepo,
+		productRepo: productRepo,
+		broker:      broker,
+	}
+}
+
+// GetHistory - Price changes of a product (archived ones included), newest first
+func (s *PriceService) GetHistory(productID int) ([]models.PriceChange, error) {
+	if productID <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if _, err := s.productRepo.GetByID(productID, true); err != nil {
+		return nil, err
+	}
+	return s.priceRepo.GetHistory(productID)
+}
+
+// GetScheduled - Pending scheduled prices of a product
+func (s *PriceService) GetScheduled(productID int) ([]models.ScheduledPrice, error) {
+	if productID <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if _, err := s.productRepo.GetByID(productID, true); err != nil {
+		return nil, err
+	}
+	return s.priceRepo.GetScheduled(productID)
+}
+
+// Schedule - Set up a price that takes effect at input.EffectiveFrom
+func (s *PriceService) Schedule(productID int, input models.ScheduledPriceInput, createdBy string) (*models.ScheduledPrice, error) {
+	if productID <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if input.Price <= 0 {
+		return nil, models.ErrInvalidPrice
+	}
+	if !input.EffectiveFrom.After(time.Now()) {
+		return nil, models.ErrEffectiveFromPast
+	}
+	if _, err := s.productRepo.GetByID(productID, false); err != nil {
+		return nil, err
+	}
+
+	scheduled := &models.ScheduledPrice{
+		ProductID:     productID,
+		Price:         input.Price,
+		EffectiveFrom: input.EffectiveFrom,
+		CreatedBy:     optionalActor(createdBy),
+	}
+	if err := s.priceRepo.CreateScheduled(scheduled); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// CancelScheduled - Delete a scheduled price that has not been applied yet
+func (s *PriceService) CancelScheduled(productID int, id int) error {
+	if productID <= 0 || id <= 0 {
+		return models.ErrInvalidID
+	}
+	return s.priceRepo.DeleteScheduled(productID, id)
+}
+
+// ApplyDue - Apply scheduled prices whose time has come. The changes
+// that were applied are returned with the error of those that failed.
+func (s *PriceService) ApplyDue() ([]models.PriceChange, error) {
+	changes, err := s.priceRepo.ApplyDue()
+	if len(changes) > 0 {
+		s.productRepo.InvalidateCache()
+	}
+	for _, change := range changes {
+		repo := s.productRepo
+		if change.StoreID != nil {
+			repo = repo.ForStore(*change.StoreID)
+		}
+		publishProduct(s.broker, repo, models.EventProductUpdated, change.ProductID, nil)
+	}
+	return changes, err
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cashier-api/events"
+	"cashier-api/models"
+)
+
+type priceCatalog struct {
+	*fakeCatalog
+	priceRepo *fakePriceRepo
+	prices    *PriceService
+}
+
+// newPriceCatalog - newBatchCatalog (Indomie 1 at 3500, Aqua 2 at 4000)
+// with the archived Chitato (3, 9000)
+func newPriceCatalog() *priceCatalog {
+	c := newBatchCatalog()
+	archived := c.productRepo.add(models.ProductDetail{ID: 3, Name: "Chitato", Price: 9000, CategoryID: 1})
+	archived.DeletedAt = &time.Time{}
+
+	repo := &fakePriceRepo{products: c.productRepo}
+	return &priceCatalog{fakeCatalog: c, priceRepo: repo, prices: NewPriceService(repo, c.productRepo, c.broker)}
+}
+
+// due - Store a price of productID scheduled by ayu for at, which may be in
+// the past, as the repository holds it
+func (c *priceCatalog) due(t *testing.T, productID int, price int, at time.Time) *models.ScheduledPrice {
+	t.Helper()
+	scheduled := &models.ScheduledPrice{ProductID: productID, Price: price, EffectiveFrom: at, CreatedBy: optionalActor("ayu")}
+	if err := c.priceRepo.CreateScheduled(scheduled); err != nil {
+		t.Fatal(err)
+	}
+	return scheduled
+}
+
+func TestPriceSchedule(t *testing.T) {
+	tomorrow := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name      string
+		productID int
+		input     models.ScheduledPriceInput
+		createdBy string
+		wantErr   error
+	}{
+		{"price rise", 1, models.ScheduledPriceInput{Price: 4000, EffectiveFrom: tomorrow}, "ayu", nil},
+		{"without actor", 1, models.ScheduledPriceInput{Price: 4000, EffectiveFrom: tomorrow}, "  ", nil},
+		{"same time, other product", 2, models.ScheduledPriceInput{Price: 4500, EffectiveFrom: tomorrow}, "ayu", nil},
+		{"same time twice", 1, models.ScheduledPriceInput{Price: 4200, EffectiveFrom: tomorrow.Add(time.Hour)}, "ayu", models.ErrScheduledPriceExists},
+		{"in the past", 1, models.ScheduledPriceInput{Price: 4000, EffectiveFrom: time.Now().Add(-time.Minute)}, "ayu", models.ErrEffectiveFromPast},
+		{"without time", 1, models.ScheduledPriceInput{Price: 4000}, "ayu", models.ErrEffectiveFromPast},
+		{"zero price", 1, models.ScheduledPriceInput{Price: 0, EffectiveFrom: tomorrow}, "ayu", models.ErrInvalidPrice},
+		{"archived product", 3, models.ScheduledPriceInput{Price: 9500, EffectiveFrom: tomorrow}, "ayu", errors.New("product not found")},
+		{"unknown product", 9, models.ScheduledPriceInput{Price: 4000, EffectiveFrom: tomorrow}, "ayu", errors.New("product not found")},
+		{"invalid ID", 0, models.ScheduledPriceInput{Price: 4000, EffectiveFrom: tomorrow}, "ayu", models.ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newPriceCatalog()
+			// Already scheduled: product 1 an hour after tomorrow
+			if _, err := c.prices.Schedule(1, models.ScheduledPriceInput{Price: 4100, EffectiveFrom: tomorrow.Add(time.Hour)}, "budi"); err != nil {
+				t.Fatal(err)
+			}
+
+			scheduled, err := c.prices.Schedule(tt.productID, tt.input, tt.createdBy)
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.priceRepo.scheduled) != 1 {
+					t.Error("rejected price was scheduled")
+				}
+				return
+			}
+
+			if scheduled.ID != 2 || scheduled.ProductID != tt.productID || scheduled.Price != tt.input.Price ||
+				!scheduled.EffectiveFrom.Equal(tomorrow) {
+				t.Errorf("scheduled %+v", scheduled)
+			}
+			if want := optionalActor(tt.createdBy); (scheduled.CreatedBy == nil) != (want == nil) {
+				t.Errorf("created by %v, want %v", scheduled.CreatedBy, want)
+			}
+			if c.productRepo.products[tt.productID].Price != map[int]int{1: 3500, 2: 4000}[tt.productID] {
+				t.Error("scheduling changed the price")
+			}
+		})
+	}
+}
+
+func TestPriceCancelScheduled(t *testing.T) {
+	c := newPriceCatalog()
+	pending := c.due(t, 1, 4000, time.Now().Add(time.Hour))
+	applied := c.due(t, 1, 3800, time.Now().Add(-time.Hour))
+	if _, err := c.prices.ApplyDue(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		productID, id int
+		wantErr       error
+	}{
+		{"of another product", 2, pending.ID, models.ErrScheduledPriceNotFound},
+		{"unknown", 1, 9, models.ErrScheduledPriceNotFound},
+		{"applied", 1, applied.ID, models.ErrScheduledPriceApplied},
+		{"invalid ID", 1, 0, models.ErrInvalidID},
+		{"invalid product ID", 0, pending.ID, models.ErrInvalidID},
+		{"pending", 1, pending.ID, nil},
+		{"cancelled twice", 1, pending.ID, models.ErrScheduledPriceNotFound},
+	}
+	for _, tt := range tests {
+		if err := c.prices.CancelScheduled(tt.productID, tt.id); err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if scheduled, _ := c.prices.GetScheduled(1); len(scheduled) != 0 {
+		t.Errorf("scheduled %+v after the cancel", scheduled)
+	}
+}
+
+// TestPriceHistory - Updates record their price changes; other changes
+// do not
+func TestPriceHistory(t *testing.T) {
+	c := newPriceCatalog()
+	ayu, anonymous := models.Actor{Name: "ayu"}, models.Actor{}
+
+	if _, err := c.products.Patch(1, 0, []byte(`{"price": 3800}`), ayu); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.products.Patch(1, 0, []byte(`{"name": "Indomie Goreng"}`), ayu); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.products.Patch(1, 0, []byte(`{"price": 4000}`), anonymous); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := c.prices.GetHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history %+v, want 2 changes", history)
+	}
+	newest, oldest := history[0], history[1]
+	if newest.OldPrice != 3800 || newest.NewPrice != 4000 || newest.ChangedBy != nil || newest.Source != models.PriceSourceManual {
+		t.Errorf("newest %+v", newest)
+	}
+	if oldest.OldPrice != 3500 || oldest.NewPrice != 3800 || oldest.ChangedBy == nil || *oldest.ChangedBy != "ayu" {
+		t.Errorf("oldest %+v", oldest)
+	}
+
+	if history, err := c.prices.GetHistory(2); err != nil || history == nil || len(history) != 0 {
+		t.Errorf("unchanged product: %v, %v; want an empty list", history, err)
+	}
+	if _, err := c.prices.GetHistory(3); err != nil {
+		t.Errorf("archived product: %v", err)
+	}
+	if _, err := c.prices.GetHistory(9); !sameError(err, errors.New("product not found")) {
+		t.Errorf("unknown product: %v", err)
+	}
+	if _, err := c.prices.GetHistory(0); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+	if _, err := c.prices.GetScheduled(-1); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+}
+
+func TestApplyDue(t *testing.T) {
+	c := newPriceCatalog()
+	now := time.Now()
+	later := c.due(t, 1, 4000, now.Add(-time.Hour))
+	first := c.due(t, 1, 3800, now.Add(-2*time.Hour))
+	future := c.due(t, 1, 4500, now.Add(time.Hour))
+	c.due(t, 2, 4000, now.Add(-time.Hour))         // the current price
+	c.due(t, 3, 9500, now.Add(-time.Hour))         // archived: applied for when it is restored
+	gone := c.due(t, 4, 1000, now.Add(-time.Hour)) // product removed since: cannot be applied
+
+	sub := c.broker.Subscribe(events.Filter{}, 0, false)
+	defer c.broker.Unsubscribe(sub)
+
+	changes, err := c.prices.ApplyDue()
+	if err == nil || !strings.Contains(err.Error(), "of product 4") {
+		t.Errorf("error %v, want the failure of product 4", err)
+	}
+
+	type change struct{ product, old, new, scheduled int }
+	var got []change
+	for _, ch := range changes {
+		if ch.Source != models.PriceSourceScheduled || ch.ChangedBy == nil || *ch.ChangedBy != "ayu" {
+			t.Errorf("change %+v, want scheduled by ayu", ch)
+		}
+		got = append(got, change{ch.ProductID, ch.OldPrice, ch.NewPrice, *ch.ScheduledPriceID})
+	}
+	want := []change{{1, 3500, 3800, first.ID}, {1, 3800, 4000, later.ID}, {3, 9000, 9500, 5}}
+	if !slices.Equal(got, want) {
+		t.Errorf("changes %v, want %v", got, want)
+	}
+
+	for id, price := range map[int]int{1: 4000, 2: 4000, 3: 9500} {
+		if got := c.productRepo.products[id].Price; got != price {
+			t.Errorf("product %d price %d, want %d", id, got, price)
+		}
+	}
+	if scheduled, _ := c.prices.GetScheduled(1); len(scheduled) != 1 || scheduled[0].ID != future.ID {
+		t.Errorf("pending %+v, want only the future price", scheduled)
+	}
+	if failed := c.priceRepo.scheduled[gone.ID-1]; failed.FailedAt == nil || failed.LastError == nil {
+		t.Errorf("failed price %+v, want it marked", failed)
+	}
+
+	var updated []int
+	for len(sub.Events()) > 0 {
+		e := <-sub.Events()
+		if e.Type != models.EventProductUpdated || e.Data.Product == nil {
+			t.Errorf("event %s %+v", e.Type, e.Data)
+			continue
+		}
+		updated = append(updated, e.Data.ProductID)
+	}
+	if !slices.Equal(updated, []int{1, 1, 3}) {
+		t.Errorf("product.updated for %v, want 1, 1 and 3", updated)
+	}
+
+	// Nothing is applied twice and the failure is not retried
+	if changes, err := c.prices.ApplyDue(); len(changes) != 0 || err != nil {
+		t.Errorf("second run: %v, %v", changes, err)
+	}
+	if history, _ := c.prices.GetHistory(1); len(history) != 2 {
+		t.Errorf("%d price changes of product 1, want 2", len(history))
+	}
+}
+
+// TestPriceSchedulerRun - The scheduler applies due prices when started
+func TestPriceSchedulerRun(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c := newPriceCatalog()
+	c.due(t, 1, 3800, time.Now().Add(-time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	NewPriceScheduler(c.prices, time.Hour).Run(ctx)
+
+	if c.productRepo.products[1].Price != 3800 {
+		t.Errorf("price %d, want the scheduled 3800", c.productRepo.products[1].Price)
+	}
+	if !strings.Contains(logged.String(), "product 1 price 3500 -> 3800") {
+		t.Errorf("log %q, want the applied change", logged.String())
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"cashier-api/models"
+	"errors"
+	"fmt"
+)
+
+// errBatchAborted - Internal signal to roll back an atomic batch
+var errBatchAborted = errors.New("batch aborted")
+
+// Batch - Execute create/update/delete operations in a single transaction.
+//
+// In atomic mode the first failing operation rolls back the whole batch; in
+// best-effort mode every operation runs in its own savepoint and only the
+// failed ones are undone. Validation is the same as Create and Update.
+// Every write is recorded with actor.
+func (s *ProductService) Batch(req models.BatchRequest, actor models.Actor) (*models.BatchResult, error) {
+	if req.Mode == "" {
+		req.Mode = models.BatchAtomic
+	}
+	if req.Mode != models.BatchAtomic && req.Mode != models.BatchBestEffort {
+		return nil, models.ErrInvalidBatchMode
+	}
+	if len(req.Operations) == 0 {
+		return nil, models.ErrEmptyBatch
+	}
+	if len(req.Operations) > models.MaxBatchOperations {
+		return nil, models.ErrBatchTooLarge
+	}
+
+	result := &models.BatchResult{
+		Mode:    req.Mode,
+		Results: make([]models.BatchOperationResult, len(req.Operations)),
+	}
+	failedAt := -1
+
+	// Stock before the batch, to tell which updates changed it
+	var updateIDs []int
+	for _, op := range req.Operations {
+		if op.Op == models.BatchUpdate && op.ID > 0 {
+			updateIDs = append(updateIDs, op.ID)
+		}
+	}
+	stockBefore, err := s.productRepo.GetStocks(updateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.productRepo.RunBatch(actor, func(b ProductBatch) error {
+		for i, op := range req.Operations {
+			res := &result.Results[i]
+			res.Index = i
+			res.Op = op.Op
+			res.ID = op.ID
+
+			var opErr error
+			if req.Mode == models.BatchBestEffort {
+				opErr = b.Savepoint(func() error { return s.runBatchOperation(b, op, res) })
+			} else {
+				opErr = s.runBatchOperation(b, op, res)
+			}
+
+			if opErr != nil {
+				res.Error = opErr.Error()
+				res.Product = nil
+				if req.Mode == models.BatchAtomic {
+					failedAt = i
+					return errBatchAborted
+				}
+				continue
+			}
+			res.OK = true
+		}
+		return nil
+	})
+	if err != nil && err != errBatchAborted {
+		return nil, err
+	}
+
+	if failedAt >= 0 {
+		// Nothing was committed: report every other operation as rolled back
+		for i := range result.Results {
+			res := &result.Results[i]
+			res.Index = i
+			res.Op = req.Operations[i].Op
+			res.ID = req.Operations[i].ID
+			if i != failedAt {
+				res.OK = false
+				res.Product = nil
+				res.Error = fmt.Sprintf("rolled back: operation %d failed", failedAt)
+			}
+		}
+		result.Failed = len(result.Results)
+		return result, nil
+	}
+
+	result.Committed = true
+	s.productRepo.InvalidateCache()
+	for _, res := range result.Results {
+		if !res.OK {
+			result.Failed++
+			continue
+		}
+		result.Succeeded++
+
+		switch res.Op {
+		case models.BatchCreate:
+			s.publish(models.EventProductCreated, res.ID, nil)
+		case models.BatchUpdate:
+			var before *models.Quantity
+			if stock, ok := stockBefore[res.ID]; ok {
+				before = &stock
+			}
+			s.publish(models.EventProductUpdated, res.ID, before)
+		case models.BatchDelete:
+			s.publish(models.EventProductDeleted, res.ID, nil)
+		}
+	}
+	return result, nil
+}
+
+func (s *ProductService) runBatchOperation(b ProductBatch, op models.BatchOperation, res *models.BatchOperationResult) error {
+	switch op.Op {
+	case models.BatchCreate:
+		if op.Product == nil {
+			return models.ErrProductRequired
+		}
+		product := *op.Product
+		product.ID = 0
+		if err := validateProduct(b, &product); err != nil {
+			return err
+		}
+		if err := b.Create(&product); err != nil {
+			return err
+		}
+		res.ID = product.ID
+		res.Product = &product
+		return nil
+
+	case models.BatchUpdate:
+		if op.ID <= 0 {
+			return models.ErrInvalidID
+		}
+		if op.Product == nil {
+			return models.ErrProductRequired
+		}
+		product := *op.Product
+		product.ID = op.ID
+		product.Version = op.Version
+		if err := validateProduct(b, &product); err != nil {
+			return err
+		}
+		if err := b.Update(&product); err != nil {
+			return err
+		}
+		res.Product = &product
+		return nil
+
+	case models.BatchDelete:
+		if op.ID <= 0 {
+			return models.ErrInvalidID
+		}
+		return b.Delete(op.ID, op.Version)
+	}
+	return models.ErrInvalidBatchOp
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"cashier-api/events"
+	"cashier-api/models"
+)
+
+// newBatchCatalog - newPatchCatalog with product 2 (Aqua in Drinks)
+func newBatchCatalog() *fakeCatalog {
+	c := newPatchCatalog()
+	c.productRepo.add(models.ProductDetail{
+		ID: 2, Name: "Aqua 600ml", Price: 4000, Stock: models.NewQuantity(48), Unit: models.UnitPieces, CategoryID: 2,
+	})
+	return c
+}
+
+func batchProduct(name string, price int, stock int64) *models.Product {
+	return &models.Product{Name: name, Price: price, Stock: models.NewQuantity(stock), Unit: models.UnitPieces, CategoryID: 1}
+}
+
+// eventTypes - Types of the events already queued for sub
+func eventTypes(sub *events.Subscription) []string {
+	var types []string
+	for len(sub.Events()) > 0 {
+		types = append(types, (<-sub.Events()).Type)
+	}
+	return types
+}
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		ops           []models.BatchOperation
+		wantCommitted bool
+		wantOK        []bool
+		wantErrors    []string
+		wantProducts  []int // IDs stored afterwards, archived ones excluded
+		wantEvents    []string
+	}{
+		{"atomic", models.BatchAtomic, []models.BatchOperation{
+			{Op: models.BatchCreate, Product: batchProduct("Chitato", 9000, 12)},
+			{Op: models.BatchUpdate, ID: 1, Version: 3, Product: batchProduct("Indomie Goreng", 3600, 8)},
+			{Op: models.BatchDelete, ID: 2},
+		}, true, []bool{true, true, true}, []string{"", "", ""}, []int{1, 3},
+			[]string{models.EventProductCreated, models.EventProductUpdated, models.EventStockChanged, models.EventProductDeleted}},
+		{"mode defaults to atomic", "", []models.BatchOperation{
+			{Op: models.BatchCreate, Product: batchProduct("Chitato", 9000, 12)},
+			{Op: models.BatchDelete, ID: 9},
+		}, false, []bool{false, false}, []string{"rolled back: operation 1 failed", "product not found"}, []int{1, 2}, nil},
+		{"atomic rolls back earlier writes", models.BatchAtomic, []models.BatchOperation{
+			{Op: models.BatchDelete, ID: 2},
+			{Op: models.BatchUpdate, ID: 1, Product: batchProduct("Indomie Goreng", 0, 8)},
+			{Op: models.BatchCreate, Product: batchProduct("Chitato", 9000, 12)},
+		}, false, []bool{false, false, false},
+			[]string{"rolled back: operation 1 failed", models.ErrInvalidPrice.Error(), "rolled back: operation 1 failed"},
+			[]int{1, 2}, nil},
+		{"best effort skips failures", models.BatchBestEffort, []models.BatchOperation{
+			{Op: models.BatchCreate, Product: batchProduct("Chitato", 9000, 12)},
+			{Op: models.BatchUpdate, ID: 1, Version: 2, Product: batchProduct("Indomie Goreng", 3600, 8)},
+			{Op: models.BatchDelete, ID: 2},
+			{Op: "upsert", ID: 1},
+		}, true, []bool{true, false, true, false},
+			[]string{"", models.ErrVersionMismatch.Error(), "", models.ErrInvalidBatchOp.Error()},
+			[]int{1, 3}, []string{models.EventProductCreated, models.EventProductDeleted}},
+		{"best effort validation", models.BatchBestEffort, []models.BatchOperation{
+			{Op: models.BatchCreate},
+			{Op: models.BatchUpdate, ID: 0, Product: batchProduct("Chitato", 9000, 12)},
+			{Op: models.BatchUpdate, ID: 1, Product: &models.Product{Name: "Indomie", Price: 3500, CategoryID: 9}},
+			{Op: models.BatchDelete, ID: -1},
+			{Op: models.BatchUpdate, ID: 2, Product: &models.Product{Name: "Aqua 600ml", Price: 4200, Stock: models.NewQuantity(48), CategoryID: 2}},
+		}, true, []bool{false, false, false, false, true},
+			[]string{models.ErrProductRequired.Error(), models.ErrInvalidID.Error(), models.ErrCategoryNotFound.Error(),
+				models.ErrInvalidID.Error(), ""},
+			[]int{1, 2}, []string{models.EventProductUpdated}}, // stock unchanged: no stock.changed
+		{"best effort all failing", models.BatchBestEffort, []models.BatchOperation{
+			{Op: models.BatchDelete, ID: 9},
+		}, true, []bool{false}, []string{"product not found"}, []int{1, 2}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBatchCatalog()
+			sub := c.broker.Subscribe(events.Filter{}, 0, false)
+			defer c.broker.Unsubscribe(sub)
+
+			result, err := c.products.Batch(models.BatchRequest{Mode: tt.mode, Operations: tt.ops}, models.Actor{Name: "ayu"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Mode != models.BatchAtomic && result.Mode != models.BatchBestEffort {
+				t.Errorf("mode %q", result.Mode)
+			}
+			if result.Committed != tt.wantCommitted {
+				t.Errorf("committed %v, want %v", result.Committed, tt.wantCommitted)
+			}
+			succeeded := 0
+			for i, res := range result.Results {
+				if res.Index != i || res.Op != tt.ops[i].Op {
+					t.Errorf("result %d: index %d op %q", i, res.Index, res.Op)
+				}
+				if res.OK != tt.wantOK[i] || res.Error != tt.wantErrors[i] {
+					t.Errorf("result %d: ok %v error %q, want %v %q", i, res.OK, res.Error, tt.wantOK[i], tt.wantErrors[i])
+				}
+				if !res.OK && res.Product != nil {
+					t.Errorf("result %d: failed with a product", i)
+				}
+				if res.OK {
+					succeeded++
+				}
+			}
+			if result.Succeeded != succeeded || result.Succeeded+result.Failed != len(tt.ops) {
+				t.Errorf("succeeded %d failed %d", result.Succeeded, result.Failed)
+			}
+
+			var stored []int
+			for _, p := range c.productRepo.products {
+				if p.DeletedAt == nil {
+					stored = append(stored, p.ID)
+				}
+			}
+			slices.Sort(stored)
+			if !slices.Equal(stored, tt.wantProducts) {
+				t.Errorf("products %v, want %v", stored, tt.wantProducts)
+			}
+			if !tt.wantCommitted && (c.productRepo.products[1].Version != 3 || len(c.productRepo.audit) != 0) {
+				t.Error("rolled back batch left writes behind")
+			}
+			if got := eventTypes(sub); !slices.Equal(got, tt.wantEvents) {
+				t.Errorf("events %v, want %v", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestBatchCreateResult(t *testing.T) {
+	c := newBatchCatalog()
+	product := batchProduct("Chitato", 9000, 12)
+	product.ID = 1 // ignored on create
+
+	result, err := c.products.Batch(models.BatchRequest{Operations: []models.BatchOperation{
+		{Op: models.BatchCreate, Product: product},
+	}}, models.Actor{Name: "ayu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := result.Results[0]
+	if res.ID != 3 || res.Product == nil || res.Product.ID != 3 || res.Product.Version != 1 {
+		t.Errorf("result %+v, want the new product 3", res)
+	}
+	if c.productRepo.products[1].Name != "Indomie Godog" || product.ID != 1 {
+		t.Error("create overwrote product 1 or the request")
+	}
+}
+
+func TestBatchRequestErrors(t *testing.T) {
+	tooMany := make([]models.BatchOperation, models.MaxBatchOperations+1)
+	for i := range tooMany {
+		tooMany[i] = models.BatchOperation{Op: models.BatchDelete, ID: 1}
+	}
+	tests := []struct {
+		name    string
+		req     models.BatchRequest
+		wantErr error
+	}{
+		{"unknown mode", models.BatchRequest{Mode: "all_or_some", Operations: tooMany[:1]}, models.ErrInvalidBatchMode},
+		{"no operations", models.BatchRequest{}, models.ErrEmptyBatch},
+		{"empty operations", models.BatchRequest{Mode: models.BatchBestEffort, Operations: []models.BatchOperation{}}, models.ErrEmptyBatch},
+		{"too many", models.BatchRequest{Operations: tooMany}, models.ErrBatchTooLarge},
+	}
+
+	for _, tt := range tests {
+		c := newBatchCatalog()
+		if _, err := c.products.Batch(tt.req, models.Actor{Name: "ayu"}); err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if c.productRepo.batches != 0 {
+			t.Errorf("%s: batch started", tt.name)
+		}
+	}
+
+	// The largest batch is accepted
+	c := newBatchCatalog()
+	result, err := c.products.Batch(models.BatchRequest{Mode: models.BatchBestEffort, Operations: tooMany[1:]}, models.Actor{Name: "ayu"})
+	if err != nil || result.Succeeded != 1 || result.Failed != models.MaxBatchOperations-1 {
+		t.Errorf("%d operations: %v", models.MaxBatchOperations, err)
+	}
+}
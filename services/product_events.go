@@ -0,0 +1,84 @@
+package services
+
+import (
+	"cashier-api/events"
+	"cashier-api/models"
+	"log"
+)
+
+// publishProduct - Push a product event to GET /api/events. The product is
+// read back as the store of repo sees it. before is its stock before the
+// write (nil when new or unknown); when it differs, stock.changed follows.
+// The write is already committed, so failures are only logged.
+func publishProduct(broker *events.Broker, repo ProductRepository, eventType string, id int,
+	before *models.Quantity) {
+	if broker == nil {
+		return
+	}
+
+	product, err := repo.GetByID(id, true)
+	if err != nil {
+		log.Printf("events: failed to load product %d for %s: %v", id, eventType, err)
+		return
+	}
+
+	data := models.StreamEvent{
+		ProductID:  product.ID,
+		CategoryID: product.CategoryID,
+		StoreID:    product.StoreID,
+	}
+	if eventType != models.EventProductDeleted {
+		data.Product = product
+	}
+	broker.Publish(eventType, data)
+
+	if before != nil && *before != product.Stock {
+		stock := product.Stock
+		broker.Publish(models.EventStockChanged, models.StreamEvent{
+			ProductID:  product.ID,
+			CategoryID: product.CategoryID,
+			StoreID:    product.StoreID,
+			Stock:      &stock,
+			Source:     models.StockSourceProduct,
+		})
+	}
+}
+
+// publishStock - Push stock.changed for a product whose stock was moved by
+// source (a transfer or a stock take), as the store of repo sees it. Like
+// publishProduct it runs after the commit and only logs failures.
+func publishStock(broker *events.Broker, repo ProductRepository, id int, source string) {
+	if broker == nil {
+		return
+	}
+
+	product, err := repo.GetByID(id, true)
+	if err != nil {
+		log.Printf("events: failed to load product %d for %s: %v", id, models.EventStockChanged, err)
+		return
+	}
+
+	stock := product.Stock
+	broker.Publish(models.EventStockChanged, models.StreamEvent{
+		ProductID:  product.ID,
+		CategoryID: product.CategoryID,
+		StoreID:    product.StoreID,
+		Stock:      &stock,
+		Source:     source,
+	})
+}
+
+// publish - publishProduct in the store of the service
+func (s *ProductService) publish(eventType string, id int, before *models.Quantity) {
+	publishProduct(s.broker, s.productRepo, eventType, id, before)
+}
+
+// stockBefore - Current stock of a product about to be written, nil when
+// it cannot be read (the write reports the error)
+func (s *ProductService) stockBefore(id int) *models.Quantity {
+	product, err := s.productRepo.GetByID(id, false)
+	if err != nil {
+		return nil
+	}
+	return &product.Stock
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"cashier-api/models"
+	"cashier-api/storage"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+const (
+	// thumbnailSize - Longest side of generated thumbnails in pixels
+	thumbnailSize = 256
+	// maxImagePixels - Reject huge images before decoding them
+	maxImagePixels = 40_000_000
+)
+
+// imageExtensions - Accepted content types (sniffed, not trusted from the
+// client) and their file extension
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type ProductImageService struct {
+	imageRepo   ProductImageRepository
+	productRepo ProductRepository
+	store       storage.Storage
+	maxBytes    int64
+}
+
+func NewProductImageService(imageRepo ProductImageRepository, productRepo ProductRepository,
+	store storage.Storage, maxBytes int64) *ProductImageService {
+	return &ProductImageService{
+		imageRepo:   imageRepo,
+		productRepo: productRepo,
+		store:       store,
+		maxBytes:    maxBytes,
+	}
+}
+
+// MaxBytes - Largest accepted upload
+func (s *ProductImageService) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Upload - Validate an image, store it with a thumbnail and attach it to the product
+func (s *ProductImageService) Upload(ctx context.Context, productID int, file io.Reader) (*models.ProductImage, error) {
+	if productID <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if _, err := s.productRepo.GetByID(productID, false); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, models.ErrImageRequired
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, models.ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, models.ErrImageType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrImageCorrupt
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, models.ErrImageDimensions
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrImageCorrupt
+	}
+
+	thumbnail, thumbnailType, thumbnailExt, err := makeThumbnail(decoded, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	img := &models.ProductImage{
+		ProductID:    productID,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        config.Width,
+		Height:       config.Height,
+		Key:          fmt.Sprintf("products/%d/%s%s", productID, name, ext),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb%s", productID, name, thumbnailExt),
+	}
+
+	if err := s.store.Put(ctx, img.Key, bytes.NewReader(data), img.Size, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, img.ThumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailType); err != nil {
+		s.deleteFiles(ctx, img)
+		return nil, err
+	}
+
+	if err := s.imageRepo.Create(img); err != nil {
+		s.deleteFiles(ctx, img)
+		return nil, err
+	}
+
+	s.setURLs(img)
+	return img, nil
+}
+
+// Delete - Remove the image record and its files
+func (s *ProductImageService) Delete(ctx context.Context, productID int, id int) error {
+	if productID <= 0 || id <= 0 {
+		return models.ErrInvalidID
+	}
+
+	img, err := s.imageRepo.Delete(productID, id)
+	if err != nil {
+		return err
+	}
+
+	s.deleteFiles(ctx, img)
+	return nil
+}
+
+// GetByProductID - Images of a product with their URLs
+func (s *ProductImageService) GetByProductID(productID int) ([]models.ProductImage, error) {
+	images, err := s.imageRepo.GetByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		s.setURLs(&images[i])
+	}
+	return images, nil
+}
+
+func (s *ProductImageService) setURLs(img *models.ProductImage) {
+	img.URL = s.store.URL(img.Key)
+	img.ThumbnailURL = s.store.URL(img.ThumbnailKey)
+}
+
+// deleteFiles - Best effort cleanup; a leftover file is only wasted space
+func (s *ProductImageService) deleteFiles(ctx context.Context, img *models.ProductImage) {
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("images: failed to delete %s: %v", key, err)
+		}
+	}
+}
+
+// makeThumbnail - Scale img to fit thumbnailSize x thumbnailSize. PNG and GIF
+// sources give a PNG thumbnail (keeps transparency), others a JPEG.
+func makeThumbnail(img image.Image, contentType string) ([]byte, string, string, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			width, height = thumbnailSize, max(1, height*thumbnailSize/width)
+		} else {
+			width, height = max(1, width*thumbnailSize/height), thumbnailSize
+		}
+	}
+
+	keepAlpha := contentType == "image/png" || contentType == "image/gif"
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	if !keepAlpha {
+		// JPEG has no transparency, use a white background
+		draw.Draw(thumbnail, thumbnail.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if keepAlpha {
+		if err := png.Encode(&buf, thumbnail); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	}
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/jpeg", ".jpg", nil
+}
+
+// randomName - Unguessable file name for a stored image
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestMakeThumbnail(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		contentType   string
+		wantW, wantH  int
+		wantType      string
+		wantExt       string
+	}{
+		{"landscape jpeg", 1024, 512, "image/jpeg", 256, 128, "image/jpeg", ".jpg"},
+		{"portrait png", 300, 600, "image/png", 128, 256, "image/png", ".png"},
+		{"gif keeps alpha", 512, 512, "image/gif", 256, 256, "image/png", ".png"},
+		{"webp becomes jpeg", 400, 100, "image/webp", 256, 64, "image/jpeg", ".jpg"},
+		{"small image not enlarged", 100, 50, "image/jpeg", 100, 50, "image/jpeg", ".jpg"},
+		{"thin strip keeps a pixel", 2000, 1, "image/png", 256, 1, "image/png", ".png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			data, contentType, ext, err := makeThumbnail(src, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != tt.wantType || ext != tt.wantExt {
+				t.Errorf("got %s %s, want %s %s", contentType, ext, tt.wantType, tt.wantExt)
+			}
+
+			config, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if "image/"+format != tt.wantType {
+				t.Errorf("encoded as %s, want %s", format, tt.wantType)
+			}
+			if config.Width != tt.wantW || config.Height != tt.wantH {
+				t.Errorf("size %dx%d, want %dx%d", config.Width, config.Height, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestMakeThumbnailBackground(t *testing.T) {
+	// A transparent source gets a white background as JPEG and stays
+	// transparent as PNG
+	src := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+
+	data, _, _, err := makeThumbnail(src, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := img.At(5, 5).RGBA(); r < 0xf000 || g < 0xf000 || b < 0xf000 {
+		t.Errorf("JPEG pixel = %v, want white", img.At(5, 5))
+	}
+
+	data, _, _, err = makeThumbnail(src, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(5, 5).RGBA(); a != 0 {
+		t.Errorf("PNG pixel = %v, want transparent", color.NRGBAModel.Convert(img.At(5, 5)))
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"cashier-api/models"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exportHeader - Columns written by Export; Import accepts the same file back
+var exportHeader = []interface{}{"id", "name", "price", "stock", "unit", "category_id", "category_name"}
+
+// Import - Validate rows (first row is the header) and create all products in
+// one transaction. Nothing is written when dryRun is set or any row is invalid.
+//
+// Recognised columns: name, price, stock, unit, and one of category_id,
+// category_name or category (id or name). Other columns are ignored.
+func (s *ProductService) Import(rows [][]string, dryRun bool, actor models.Actor) (*models.ImportResult, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", models.ErrInvalidImportFile)
+	}
+
+	columns := importColumns{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing required column %q", models.ErrInvalidImportFile, required)
+		}
+	}
+	_, hasCategoryID := columns["category_id"]
+	_, hasCategoryName := columns["category_name"]
+	_, hasCategory := columns["category"]
+	if !hasCategoryID && !hasCategoryName && !hasCategory {
+		return nil, fmt.Errorf("%w: missing category_id, category_name or category column", models.ErrInvalidImportFile)
+	}
+
+	categories, err := s.categoryRepo.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := map[int]bool{}
+	categoryByName := map[string]int{}
+	for _, c := range categories {
+		categoryIDs[c.ID] = true
+		categoryByName[strings.ToLower(c.Name)] = c.ID
+	}
+
+	result := &models.ImportResult{DryRun: dryRun, Errors: []models.ImportRowError{}}
+	var products []models.Product
+
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		result.TotalRows++
+
+		var rowErrors []string
+		var product models.Product
+
+		product.Name, _ = columns.cell(row, "name")
+
+		price, _ := columns.cell(row, "price")
+		if product.Price, err = strconv.Atoi(price); err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("price %q is not a whole number", price))
+		}
+
+		if stock, ok := columns.cell(row, "stock"); ok && stock != "" {
+			if product.Stock, err = models.ParseQuantity(stock); err != nil {
+				rowErrors = append(rowErrors, fmt.Sprintf("stock %q: %v", stock, err))
+			}
+		}
+
+		product.Unit, _ = columns.cell(row, "unit")
+		product.Unit = strings.ToLower(product.Unit)
+
+		categoryID, categoryErr := columns.resolveCategory(row, categoryIDs, categoryByName)
+		if categoryErr != nil {
+			rowErrors = append(rowErrors, categoryErr.Error())
+		}
+		product.CategoryID = categoryID
+
+		if len(rowErrors) == 0 {
+			if err := validateProductFields(&product); err != nil {
+				rowErrors = append(rowErrors, err.Error())
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: i + 2, Errors: rowErrors})
+			continue
+		}
+		products = append(products, product)
+	}
+
+	result.ValidRows = len(products)
+	if dryRun || len(result.Errors) > 0 || len(products) == 0 {
+		return result, nil
+	}
+
+	if err := s.productRepo.CreateMany(products, actor); err != nil {
+		return nil, err
+	}
+	result.Created = len(products)
+	s.productRepo.InvalidateCache()
+	for _, product := range products {
+		s.publish(models.EventProductCreated, product.ID, nil)
+	}
+
+	return result, nil
+}
+
+// importColumns - Lower-cased header name to column index
+type importColumns map[string]int
+
+// cell - Trimmed value of a column; ok reports whether the column exists
+func (c importColumns) cell(row []string, column string) (string, bool) {
+	i, ok := c[column]
+	if !ok || i >= len(row) {
+		return "", ok
+	}
+	return strings.TrimSpace(row[i]), true
+}
+
+// resolveCategory - Find the category of an import row by id or by name
+func (c importColumns) resolveCategory(row []string, categoryIDs map[int]bool, categoryByName map[string]int) (int, error) {
+	byID := func(value string) (int, error) {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			return 0, models.ErrInvalidCategoryID
+		}
+		if !categoryIDs[id] {
+			return 0, models.ErrCategoryNotFound
+		}
+		return id, nil
+	}
+	byName := func(value string) (int, error) {
+		id, ok := categoryByName[strings.ToLower(value)]
+		if !ok {
+			return 0, fmt.Errorf("category %q not found", value)
+		}
+		return id, nil
+	}
+
+	if value, _ := c.cell(row, "category_id"); value != "" {
+		return byID(value)
+	}
+	if value, _ := c.cell(row, "category_name"); value != "" {
+		return byName(value)
+	}
+	if value, _ := c.cell(row, "category"); value != "" {
+		if _, err := strconv.Atoi(value); err == nil {
+			return byID(value)
+		}
+		return byName(value)
+	}
+	return 0, models.ErrInvalidCategoryID
+}
+
+// Export - Rows (header first) for the products matching filter
+func (s *ProductService) Export(filter models.ProductFilter) ([][]interface{}, error) {
+	products, err := s.productRepo.GetAllWithCategory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := [][]interface{}{exportHeader}
+	for _, p := range products {
+		rows = append(rows, []interface{}{p.ID, p.Name, p.Price, p.Stock.Float64(), p.Unit, p.CategoryID, p.CategoryName})
+	}
+	return rows, nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,50 +1,110 @@
 package services
 
 import (
+	"cashier-api/events"
 	"cashier-api/models"
-	"cashier-api/repositories"
+	"encoding/json"
 )
 
 type ProductService struct {
-	productRepo  *repositories.ProductRepository
-	categoryRepo *repositories.CategoryRepository
+	productRepo  ProductRepository
+	categoryRepo CategoryRepository
+	variantRepo  VariantRepository
+	imageService *ProductImageService
+	broker       *events.Broker // nil = no event stream
 }
 
-func NewProductService(productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository) *ProductService {
+func NewProductService(productRepo ProductRepository, categoryRepo CategoryRepository,
+	variantRepo VariantRepository, imageService *ProductImageService, broker *events.Broker) *ProductService {
 	return &ProductService{
 		productRepo:  productRepo,
 		categoryRepo: categoryRepo,
+		variantRepo:  variantRepo,
+		imageService: imageService,
+		broker:       broker,
 	}
 }
 
-func (s *ProductService) GetAll() ([]models.ProductList, error) {
-	return s.productRepo.GetAll()
+// ForStore - Service working on the stock and prices of a store (0 = the
+// central stock and default prices)
+func (s *ProductService) ForStore(storeID int) *ProductService {
+	scoped := *s
+	scoped.productRepo = s.productRepo.ForStore(storeID)
+	return &scoped
 }
 
-func (s *ProductService) GetByID(id int) (*models.ProductDetail, error) {
+func (s *ProductService) GetAll(filter models.ProductFilter) ([]models.ProductList, error) {
+	return s.productRepo.GetAll(filter)
+}
+
+// GetByID - Product detail including its variants and images
+func (s *ProductService) GetByID(id int, includeDeleted bool) (*models.ProductDetail, error) {
 	if id <= 0 {
 		return nil, models.ErrInvalidID
 	}
-	return s.productRepo.GetByID(id)
+
+	product, err := s.productRepo.GetByID(id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Variants, err = s.variantRepo.GetByProductID(id); err != nil {
+		return nil, err
+	}
+	if product.Images, err = s.imageService.GetByProductID(id); err != nil {
+		return nil, err
+	}
+	return product, nil
 }
 
-func (s *ProductService) Create(product *models.Product) error {
-	// Basic validation
-	if product.Name == "" {
-		return models.ErrNameRequired
+func (s *ProductService) Create(product *models.Product, actor models.Actor) error {
+	if err := s.validate(product); err != nil {
+		return err
 	}
-	if product.Price <= 0 {
-		return models.ErrInvalidPrice
+	if err := s.productRepo.Create(product, actor); err != nil {
+		return err
 	}
-	if product.Stock < 0 {
-		return models.ErrInvalidStock
+	s.productRepo.InvalidateCache()
+	s.publish(models.EventProductCreated, product.ID, nil)
+	return nil
+}
+
+// Update - actor is recorded in the audit log, and in the price history when
+// the price changes
+func (s *ProductService) Update(product *models.Product, actor models.Actor) error {
+	if product.ID <= 0 {
+		return models.ErrInvalidID
 	}
-	if product.CategoryID <= 0 {
-		return models.ErrInvalidCategoryID
+	if err := s.validate(product); err != nil {
+		return err
+	}
+
+	before := s.stockBefore(product.ID)
+	if err := s.productRepo.Update(product, actor); err != nil {
+		return err
+	}
+	s.productRepo.InvalidateCache()
+	s.publish(models.EventProductUpdated, product.ID, before)
+	return nil
+}
+
+// categoryChecker - Implemented by ProductRepository and ProductBatch
+type categoryChecker interface {
+	CheckCategoryExists(categoryID int) (bool, error)
+}
+
+// validate - Field validation plus category existence check
+func (s *ProductService) validate(product *models.Product) error {
+	return validateProduct(s.productRepo, product)
+}
+
+func validateProduct(checker categoryChecker, product *models.Product) error {
+	if err := validateProductFields(product); err != nil {
+		return err
 	}
 
 	// Validate category exists
-	categoryExists, err := s.productRepo.CheckCategoryExists(product.CategoryID)
+	categoryExists, err := checker.CheckCategoryExists(product.CategoryID)
 	if err != nil {
 		return err
 	}
@@ -52,13 +112,11 @@ func (s *ProductService) Create(product *models.Product) error {
 		return models.ErrCategoryNotFound
 	}
 
-	return s.productRepo.Create(product)
+	return nil
 }
 
-func (s *ProductService) Update(product *models.Product) error {
-	if product.ID <= 0 {
-		return models.ErrInvalidID
-	}
+// validateProductFields - Basic validation that needs no database access
+func validateProductFields(product *models.Product) error {
 	if product.Name == "" {
 		return models.ErrNameRequired
 	}
@@ -68,25 +126,99 @@ func (s *ProductService) Update(product *models.Product) error {
 	if product.Stock < 0 {
 		return models.ErrInvalidStock
 	}
+	if product.Unit == "" {
+		product.Unit = models.DefaultUnit
+	}
+	if !models.IsValidUnit(product.Unit) {
+		return models.ErrInvalidUnit
+	}
+	if models.IsCountUnit(product.Unit) && !product.Stock.IsWhole() {
+		return models.ErrFractionalQuantity
+	}
+	if (product.ReorderLevel != nil && *product.ReorderLevel < 0) || product.ReorderQuantity < 0 {
+		return models.ErrInvalidReorder
+	}
+	if models.IsCountUnit(product.Unit) && ((product.ReorderLevel != nil && !product.ReorderLevel.IsWhole()) ||
+		!product.ReorderQuantity.IsWhole()) {
+		return models.ErrFractionalQuantity
+	}
 	if product.CategoryID <= 0 {
 		return models.ErrInvalidCategoryID
 	}
+	return nil
+}
 
-	// Validate category exists
-	categoryExists, err := s.productRepo.CheckCategoryExists(product.CategoryID)
-	if err != nil {
-		return err
+// Delete - version is the expected current version (0 skips the check)
+func (s *ProductService) Delete(id int, version int, actor models.Actor) error {
+	if id <= 0 {
+		return models.ErrInvalidID
 	}
-	if !categoryExists {
-		return models.ErrCategoryNotFound
+	if err := s.productRepo.Delete(id, version, actor); err != nil {
+		return err
 	}
+	s.productRepo.InvalidateCache()
+	s.publish(models.EventProductDeleted, id, nil)
+	return nil
+}
 
-	return s.productRepo.Update(product)
+// Restore - Un-archive a soft deleted product
+func (s *ProductService) Restore(id int, actor models.Actor) (*models.ProductDetail, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if _, err := s.productRepo.Restore(id, actor); err != nil {
+		return nil, err
+	}
+	s.productRepo.InvalidateCache()
+	s.publish(models.EventProductRestored, id, nil)
+	return s.GetByID(id, false)
 }
 
-func (s *ProductService) Delete(id int) error {
+// Patch - Apply a JSON Merge Patch (RFC 7396) to an existing product and
+// validate the merged result like a full update. version is the expected
+// current version (0 skips the check).
+func (s *ProductService) Patch(id int, version int, patch []byte, actor models.Actor) (*models.Product, error) {
 	if id <= 0 {
-		return models.ErrInvalidID
+		return nil, models.ErrInvalidID
+	}
+
+	current, err := s.productRepo.GetByID(id, false)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && current.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	original, err := json.Marshal(models.Product{
+		ID:         current.ID,
+		Name:       current.Name,
+		Price:      current.Price,
+		Stock:      current.Stock,
+		Unit:       current.Unit,
+		CategoryID: current.CategoryID,
+
+		ReorderLevel:    current.ReorderLevel,
+		ReorderQuantity: current.ReorderQuantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := applyMergePatch(original, patch)
+	if err != nil {
+		return nil, models.ErrInvalidPatch
+	}
+
+	var product models.Product
+	if err := json.Unmarshal(merged, &product); err != nil {
+		return nil, models.ErrInvalidPatch
+	}
+
+	product.ID = id
+	product.Version = current.Version
+	if err := s.Update(&product, actor); err != nil {
+		return nil, err
 	}
-	return s.productRepo.Delete(id)
+	return &product, nil
 }
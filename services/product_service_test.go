@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"cashier-api/events"
+	"cashier-api/models"
+)
+
+func quantity(units int64) *models.Quantity {
+	q := models.NewQuantity(units)
+	return &q
+}
+
+// newPatchCatalog - Product 1 (10 pcs of Indomie in Food, reorder level 5)
+// at version 3, and the categories Food (1) and Drinks (2)
+func newPatchCatalog() *fakeCatalog {
+	c := newFakeCatalog()
+	c.categoryRepo.add(models.Category{ID: 1, Name: "Food"})
+	c.categoryRepo.add(models.Category{ID: 2, Name: "Drinks"})
+	c.productRepo.add(models.ProductDetail{
+		ID: 1, Name: "Indomie Godog", Price: 3500, Stock: models.NewQuantity(10), Unit: models.UnitPieces,
+		CategoryID: 1, ReorderLevel: quantity(5), ReorderQuantity: models.NewQuantity(24), Version: 3,
+	})
+	return c
+}
+
+func TestProductPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		version int
+		patch   string
+		wantErr error
+		check   func(t *testing.T, p *models.ProductDetail)
+	}{
+		{"price only", 1, 3, `{"price": 4000}`, nil, func(t *testing.T, p *models.ProductDetail) {
+			if p.Price != 4000 || p.Stock != models.NewQuantity(10) || p.Name != "Indomie Godog" || p.CategoryID != 1 {
+				t.Errorf("other fields changed: %+v", p)
+			}
+		}},
+		{"without version check", 1, 0, `{"name": "Indomie Goreng"}`, nil, func(t *testing.T, p *models.ProductDetail) {
+			if p.Name != "Indomie Goreng" {
+				t.Errorf("name %q", p.Name)
+			}
+		}},
+		{"null removes the reorder level", 1, 3, `{"reorder_level": null}`, nil, func(t *testing.T, p *models.ProductDetail) {
+			if p.ReorderLevel != nil || p.ReorderQuantity != models.NewQuantity(24) {
+				t.Errorf("reorder level %v, quantity %s", p.ReorderLevel, p.ReorderQuantity)
+			}
+		}},
+		{"decimal stock as a string", 1, 3, `{"unit": "kg", "stock": "2.5"}`, nil, func(t *testing.T, p *models.ProductDetail) {
+			if p.Unit != models.UnitKilogram || p.Stock.String() != "2.5" {
+				t.Errorf("stock %s %s", p.Stock, p.Unit)
+			}
+		}},
+		{"category", 1, 3, `{"category_id": 2}`, nil, func(t *testing.T, p *models.ProductDetail) {
+			if p.CategoryID != 2 {
+				t.Errorf("category %d", p.CategoryID)
+			}
+		}},
+		{"id in the patch ignored", 1, 3, `{"id": 7, "price": 3600}`, nil, func(t *testing.T, p *models.ProductDetail) {
+			if p.ID != 1 || p.Price != 3600 {
+				t.Errorf("%+v", p)
+			}
+		}},
+		{"stale version", 1, 2, `{"price": 4000}`, models.ErrVersionMismatch, nil},
+		{"invalid ID", 0, 0, `{"price": 4000}`, models.ErrInvalidID, nil},
+		{"unknown product", 9, 0, `{"price": 4000}`, errors.New("product not found"), nil},
+		{"malformed JSON", 1, 3, `{"price": `, models.ErrInvalidPatch, nil},
+		{"not an object", 1, 3, `[1]`, models.ErrInvalidPatch, nil},
+		{"wrong type", 1, 3, `{"price": "cheap"}`, models.ErrInvalidPatch, nil},
+		{"invalid merged price", 1, 3, `{"price": 0}`, models.ErrInvalidPrice, nil},
+		{"null name", 1, 3, `{"name": null}`, models.ErrNameRequired, nil},
+		{"negative stock", 1, 3, `{"stock": -1}`, models.ErrInvalidStock, nil},
+		{"fractional pieces", 1, 3, `{"stock": 1.5}`, models.ErrFractionalQuantity, nil},
+		{"unknown unit", 1, 3, `{"unit": "box"}`, models.ErrInvalidUnit, nil},
+		{"unknown category", 1, 3, `{"category_id": 9}`, models.ErrCategoryNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newPatchCatalog()
+			before := *c.productRepo.products[1]
+
+			product, err := c.products.Patch(tt.id, tt.version, []byte(tt.patch), models.Actor{Name: "ayu"})
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			stored := c.productRepo.products[1]
+			if tt.wantErr != nil {
+				if stored.Version != before.Version || len(c.productRepo.audit) != 0 {
+					t.Error("rejected patch was saved")
+				}
+				return
+			}
+
+			if product.Version != 4 || stored.Version != 4 {
+				t.Errorf("version %d (stored %d), want 4", product.Version, stored.Version)
+			}
+			if len(c.productRepo.audit) != 1 || c.productRepo.audit[0] != "ayu" {
+				t.Errorf("audit %v, want the actor once", c.productRepo.audit)
+			}
+			tt.check(t, stored)
+		})
+	}
+}
+
+// sameError - errors.New errors of the repositories compare by message
+func sameError(err, want error) bool {
+	if err == nil || want == nil {
+		return err == want
+	}
+	return err == want || err.Error() == want.Error()
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name, original, patch, want string
+	}{
+		{"replace", `{"a": 1, "b": 2}`, `{"a": 3}`, `{"a":3,"b":2}`},
+		{"add", `{"a": 1}`, `{"b": "x"}`, `{"a":1,"b":"x"}`},
+		{"remove", `{"a": 1, "b": 2}`, `{"b": null}`, `{"a":1}`},
+		{"remove missing", `{"a": 1}`, `{"b": null}`, `{"a":1}`},
+		{"nested", `{"a": {"b": 1, "c": 2}}`, `{"a": {"c": null, "d": 3}}`, `{"a":{"b":1,"d":3}}`},
+		{"array replaced", `{"a": [1, 2]}`, `{"a": [3]}`, `{"a":[3]}`},
+		{"object into scalar", `{"a": 1}`, `{"a": {"b": 2}}`, `{"a":{"b":2}}`},
+		{"empty patch", `{"a": 1}`, `{}`, `{"a":1}`},
+		{"non-object patch", `{"a": 1}`, `"x"`, `"x"`},
+	}
+
+	for _, tt := range tests {
+		got, err := applyMergePatch([]byte(tt.original), []byte(tt.patch))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := applyMergePatch([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Error("malformed patch accepted")
+	}
+}
+
+func TestProductDeleteRestore(t *testing.T) {
+	c := newPatchCatalog()
+	sub := c.broker.Subscribe(events.Filter{}, 0, false)
+	defer c.broker.Unsubscribe(sub)
+	actor := models.Actor{Name: "ayu"}
+
+	if err := c.products.Delete(0, 0, actor); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+	if err := c.products.Delete(1, 2, actor); err != models.ErrVersionMismatch {
+		t.Errorf("stale version: %v", err)
+	}
+	if err := c.products.Delete(1, 3, actor); err != nil {
+		t.Fatal(err)
+	}
+
+	e := <-sub.Events()
+	if e.Type != models.EventProductDeleted || e.Data.ProductID != 1 || e.Data.CategoryID != 1 || e.Data.Product != nil {
+		t.Errorf("event %s %+v, want product.deleted without the product", e.Type, e.Data)
+	}
+	if list, _ := c.products.GetAll(models.ProductFilter{}); len(list) != 0 {
+		t.Errorf("archived product listed: %+v", list)
+	}
+	if list, _ := c.products.GetAll(models.ProductFilter{IncludeDeleted: true}); len(list) != 1 || list[0].DeletedAt == nil {
+		t.Errorf("archived product missing with include_deleted: %+v", list)
+	}
+	if _, err := c.products.GetByID(1, false); !sameError(err, errors.New("product not found")) {
+		t.Errorf("archived product read: %v", err)
+	}
+	if err := c.products.Delete(1, 0, actor); !sameError(err, errors.New("product not found")) {
+		t.Errorf("deleted twice: %v", err)
+	}
+	if _, err := c.products.Patch(1, 0, []byte(`{"price": 4000}`), actor); !sameError(err, errors.New("product not found")) {
+		t.Errorf("archived product patched: %v", err)
+	}
+
+	if _, err := c.products.Restore(-1, actor); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+	if _, err := c.products.Restore(9, actor); !sameError(err, errors.New("product not found")) {
+		t.Errorf("unknown product: %v", err)
+	}
+	product, err := c.products.Restore(1, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.DeletedAt != nil || product.Version != 5 || product.Name != "Indomie Godog" {
+		t.Errorf("restored %+v", product)
+	}
+	e = <-sub.Events()
+	if e.Type != models.EventProductRestored || e.Data.Product == nil || e.Data.Product.DeletedAt != nil {
+		t.Errorf("event %s %+v, want product.restored with the product", e.Type, e.Data)
+	}
+	if _, err := c.products.Restore(1, actor); err != models.ErrNotDeleted {
+		t.Errorf("restored twice: %v", err)
+	}
+	if len(c.productRepo.audit) != 2 {
+		t.Errorf("audit %v, want the delete and the restore", c.productRepo.audit)
+	}
+	if len(sub.Events()) != 0 {
+		t.Error("event published for a rejected call")
+	}
+}
+
+// TestProductRestoreArchivedCategory - A product cannot come back into an
+// archived category; the category has to be restored first
+func TestProductRestoreArchivedCategory(t *testing.T) {
+	c := newPatchCatalog()
+	actor := models.Actor{Name: "ayu"}
+	if err := c.products.Delete(1, 0, actor); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.categories.Delete(1, 0, actor); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.products.Restore(1, actor); err != models.ErrCategoryArchived {
+		t.Fatalf("restore into an archived category: %v", err)
+	}
+	if c.productRepo.products[1].DeletedAt == nil {
+		t.Error("product restored")
+	}
+
+	if _, err := c.categories.Restore(1, actor); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.products.Restore(1, actor); err != nil {
+		t.Errorf("restore after the category: %v", err)
+	}
+}
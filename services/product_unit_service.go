@@ -0,0 +1,97 @@
+package services
+
+import (
+	"cashier-api/models"
+	"strings"
+)
+
+// SetPackagingUnits - Replace the packaging units (e.g. box = 24 pcs) of a product
+func (s *ProductService) SetPackagingUnits(id int, units []models.PackagingUnit, actor models.Actor) (*models.ProductDetail, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+
+	product, err := s.productRepo.GetByID(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i := range units {
+		unit := &units[i]
+		unit.Name = strings.ToLower(strings.TrimSpace(unit.Name))
+		if unit.Name == "" || seen[unit.Name] || models.IsValidUnit(unit.Name) || unit.Factor <= 0 {
+			return nil, models.ErrInvalidPackagingUnit
+		}
+		if models.IsCountUnit(product.Unit) && !unit.Factor.IsWhole() {
+			return nil, models.ErrFractionalQuantity
+		}
+		seen[unit.Name] = true
+	}
+
+	if err := s.productRepo.SetPackagingUnits(id, units, actor); err != nil {
+		return nil, err
+	}
+	s.publish(models.EventProductUpdated, id, nil)
+	return s.GetByID(id, false)
+}
+
+// AdjustStock - Add (or remove, when negative) a quantity given in any unit
+// the product can be converted from, e.g. receive 2 boxes of a pcs product.
+func (s *ProductService) AdjustStock(id int, adjustment models.StockAdjustment, actor models.Actor) (*models.ProductDetail, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if adjustment.Quantity == 0 {
+		return nil, models.ErrZeroAdjustment
+	}
+
+	product, err := s.productRepo.GetByID(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	delta, err := convertToProductUnit(product, adjustment.Quantity, adjustment.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.productRepo.AdjustStock(id, delta, actor); err != nil {
+		return nil, err
+	}
+	s.productRepo.InvalidateCache()
+	s.publish(models.EventProductUpdated, id, &product.Stock)
+	return s.GetByID(id, false)
+}
+
+// convertToProductUnit - Convert q given in unit (empty = product unit, a
+// standard unit of the same dimension, or a packaging unit of the product)
+// to the product unit. Count-based products only accept whole results.
+func convertToProductUnit(product *models.ProductDetail, q models.Quantity, unit string) (models.Quantity, error) {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+
+	var converted models.Quantity
+	var err error
+	switch {
+	case unit == "" || unit == product.Unit:
+		converted = q
+	case models.IsValidUnit(unit):
+		converted, err = models.ConvertUnit(q, unit, product.Unit)
+	default:
+		err = models.ErrUnknownUnit
+		for _, packaging := range product.PackagingUnits {
+			if packaging.Name == unit {
+				converted, err = q.Mul(packaging.Factor)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if models.IsCountUnit(product.Unit) && !converted.IsWhole() {
+		return 0, models.ErrFractionalQuantity
+	}
+	return converted, nil
+}
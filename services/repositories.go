@@ -0,0 +1,126 @@
+package services
+
+import (
+	"cashier-api/models"
+	"cashier-api/repositories"
+)
+
+// ProductRepository - Implemented by repositories.ProductRepository through
+// ProductRepo
+type ProductRepository interface {
+	ForStore(storeID int) ProductRepository
+	InvalidateCache()
+	GetAll(filter models.ProductFilter) ([]models.ProductList, error)
+	GetByID(id int, includeDeleted bool) (*models.ProductDetail, error)
+	GetStocks(ids []int) (map[int]models.Quantity, error)
+	GetAllWithCategory(filter models.ProductFilter) ([]models.ProductDetail, error)
+	Create(product *models.Product, actor models.Actor) error
+	CreateMany(products []models.Product, actor models.Actor) error
+	Update(product *models.Product, actor models.Actor) error
+	Delete(id int, version int, actor models.Actor) error
+	Restore(id int, actor models.Actor) (int, error)
+	SetOptionTypes(id int, optionTypes []string, actor models.Actor) error
+	SetPackagingUnits(productID int, units []models.PackagingUnit, actor models.Actor) error
+	AdjustStock(id int, delta models.Quantity, actor models.Actor) error
+	CheckCategoryExists(categoryID int) (bool, error)
+	GetLowStock(defaultReorderLevel int) ([]models.LowStockItem, error)
+	RunBatch(actor models.Actor, fn func(b ProductBatch) error) error
+}
+
+// ProductBatch - Implemented by repositories.ProductBatch
+type ProductBatch interface {
+	Create(product *models.Product) error
+	Update(product *models.Product) error
+	Delete(id int, version int) error
+	CheckCategoryExists(categoryID int) (bool, error)
+	Savepoint(fn func() error) error
+}
+
+// ProductRepo - repo as a ProductRepository
+func ProductRepo(repo *repositories.ProductRepository) ProductRepository {
+	return productRepo{repo}
+}
+
+// productRepo - Returns the interfaces of this package from ForStore and
+// RunBatch
+type productRepo struct {
+	*repositories.ProductRepository
+}
+
+func (r productRepo) ForStore(storeID int) ProductRepository {
+	return productRepo{r.ProductRepository.ForStore(storeID)}
+}
+
+func (r productRepo) RunBatch(actor models.Actor, fn func(b ProductBatch) error) error {
+	return r.ProductRepository.RunBatch(actor, func(b *repositories.ProductBatch) error { return fn(b) })
+}
+
+// CategoryRepository - Implemented by repositories.CategoryRepository
+type CategoryRepository interface {
+	InvalidateCache()
+	GetAll(includeDeleted bool) ([]models.Category, error)
+	GetByID(id int, includeDeleted bool) (*models.Category, error)
+	Create(category *models.Category, actor models.Actor) error
+	Update(category *models.Category, actor models.Actor) error
+	Delete(id int, version int, actor models.Actor) error
+	Restore(id int, actor models.Actor) (int, error)
+	IsDescendant(id int, ancestorID int) (bool, error)
+	GetSubtreeIDs(id int) ([]int, error)
+}
+
+// VariantRepository - Implemented by repositories.VariantRepository
+type VariantRepository interface {
+	GetByProductID(productID int) ([]models.Variant, error)
+	GetByID(productID int, id int) (*models.Variant, error)
+	Create(variant *models.Variant, actor models.Actor) error
+	Update(variant *models.Variant, actor models.Actor) error
+	Delete(productID int, id int, actor models.Actor) error
+	CountByProductID(productID int) (int, error)
+}
+
+// ProductImageRepository - Implemented by repositories.ProductImageRepository
+type ProductImageRepository interface {
+	GetByProductID(productID int) ([]models.ProductImage, error)
+	Create(image *models.ProductImage) error
+	Delete(productID int, id int) (*models.ProductImage, error)
+}
+
+// StoreRepository - Implemented by repositories.StoreRepository
+type StoreRepository interface {
+	GetAll() ([]models.Store, error)
+	GetByID(id int) (*models.Store, error)
+	Exists(id int) (bool, error)
+	Create(store *models.Store) error
+	Update(store *models.Store) error
+}
+
+// PriceRepository - Implemented by repositories.PriceRepository
+type PriceRepository interface {
+	GetHistory(productID int) ([]models.PriceChange, error)
+	GetScheduled(productID int) ([]models.ScheduledPrice, error)
+	CreateScheduled(scheduled *models.ScheduledPrice) error
+	DeleteScheduled(productID int, id int) error
+	ApplyDue() ([]models.PriceChange, error)
+}
+
+// StocktakeRepository - Implemented by repositories.StocktakeRepository
+type StocktakeRepository interface {
+	GetAll() ([]models.Stocktake, error)
+	GetByID(id int) (*models.Stocktake, error)
+	GetItems(id int) ([]models.StocktakeItem, error)
+	Create(stocktake *models.Stocktake) error
+	RecordCounts(id int, counts []models.StocktakeCount) error
+	Finalize(id int) error
+}
+
+// TransferRepository - Implemented by repositories.TransferRepository
+type TransferRepository interface {
+	GetAll(filter models.TransferFilter) ([]models.Transfer, error)
+	GetByID(id int) (*models.Transfer, error)
+	GetLines(id int) ([]models.TransferLine, error)
+	Create(transfer *models.Transfer) error
+	Update(transfer *models.Transfer) error
+	Delete(id int) error
+	Ship(id int, shippedBy *string) error
+	Receive(id int, received map[int]models.Quantity, receivedBy *string) error
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"cashier-api/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// SeedService - Loads a fixture through the catalog services, so that seeded
+// data passes the same validation and audit logging as API requests
+type SeedService struct {
+	categories *CategoryService
+	stores     *StoreService
+	products   *ProductService
+	variants   *VariantService
+}
+
+func NewSeedService(categories *CategoryService, stores *StoreService, products *ProductService,
+	variants *VariantService) *SeedService {
+	return &SeedService{
+		categories: categories,
+		stores:     stores,
+		products:   products,
+		variants:   variants,
+	}
+}
+
+// ParseFixture - Decode a JSON or YAML fixture (JSON is valid YAML); unknown
+// fields are rejected
+func ParseFixture(data []byte) (*models.Fixture, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidFixture, err)
+	}
+	// Through JSON so that the json tags and Quantity parsing apply
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidFixture, err)
+	}
+
+	var fixture models.Fixture
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidFixture, err)
+	}
+	return &fixture, nil
+}
+
+// Seed - Create the categories, stores and products of the fixture that do
+// not exist yet, and the variants existing products are missing. Entries
+// are created one by one; after a failure the seed can be run again and
+// continues with the missing ones.
+func (s *SeedService) Seed(fixture *models.Fixture, actor models.Actor) (*models.SeedResult, error) {
+	result := &models.SeedResult{}
+
+	categories, err := s.categories.GetAll(true)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := make(map[string]int, len(categories))
+	for _, c := range categories {
+		categoryIDs[c.Name] = c.ID
+	}
+
+	for _, fc := range fixture.Categories {
+		if _, ok := categoryIDs[fc.Name]; ok {
+			result.Skipped++
+			continue
+		}
+		category := models.Category{Name: fc.Name, Description: fc.Description}
+		if fc.Parent != "" {
+			parentID, ok := categoryIDs[fc.Parent]
+			if !ok {
+				return result, fmt.Errorf("category %q: %w %q", fc.Name, models.ErrFixtureCategory, fc.Parent)
+			}
+			category.ParentID = &parentID
+		}
+		if err := s.categories.Create(&category, actor); err != nil {
+			return result, fmt.Errorf("category %q: %w", fc.Name, err)
+		}
+		categoryIDs[category.Name] = category.ID
+		result.Categories++
+	}
+
+	stores, err := s.stores.GetAll()
+	if err != nil {
+		return result, err
+	}
+	storeCodes := make(map[string]bool, len(stores))
+	for _, st := range stores {
+		storeCodes[st.Code] = true
+	}
+
+	for _, fs := range fixture.Stores {
+		if storeCodes[fs.Code] {
+			result.Skipped++
+			continue
+		}
+		store := models.Store{Code: fs.Code, Name: fs.Name, Address: fs.Address}
+		if err := s.stores.Create(&store); err != nil {
+			return result, fmt.Errorf("store %q: %w", fs.Code, err)
+		}
+		storeCodes[store.Code] = true
+		result.Stores++
+	}
+
+	products, err := s.products.GetAll(models.ProductFilter{IncludeDeleted: true})
+	if err != nil {
+		return result, err
+	}
+	existing := make(map[string]models.ProductList, len(products))
+	for _, p := range products {
+		existing[p.Name] = p
+	}
+
+	for _, fp := range fixture.Products {
+		p, ok := existing[fp.Name]
+		if ok && p.DeletedAt != nil {
+			result.Skipped++
+			continue
+		}
+		created, variants, err := s.seedProduct(fp, p.ID, categoryIDs, actor)
+		if err != nil {
+			return result, fmt.Errorf("product %q: %w", fp.Name, err)
+		}
+		switch {
+		case created:
+			result.Products++
+		case variants == 0:
+			result.Skipped++
+		}
+		result.Variants += variants
+	}
+
+	return result, nil
+}
+
+// seedProduct - Create one product (unless id is that of the existing
+// product) and whatever it is missing of its packaging units, option types
+// and variants. Returns whether the product was created and the number of
+// variants created.
+func (s *SeedService) seedProduct(fp models.FixtureProduct, id int, categoryIDs map[string]int,
+	actor models.Actor) (bool, int, error) {
+	created := id == 0
+	if created {
+		categoryID, ok := categoryIDs[fp.Category]
+		if !ok {
+			return false, 0, fmt.Errorf("%w %q", models.ErrFixtureCategory, fp.Category)
+		}
+
+		product := models.Product{
+			Name:            fp.Name,
+			Price:           fp.Price,
+			Stock:           fp.Stock,
+			Unit:            fp.Unit,
+			CategoryID:      categoryID,
+			ReorderLevel:    fp.ReorderLevel,
+			ReorderQuantity: fp.ReorderQuantity,
+		}
+		if err := s.products.Create(&product, actor); err != nil {
+			return false, 0, err
+		}
+		id = product.ID
+	}
+
+	// An earlier run may have stopped after creating the product
+	product, err := s.products.GetByID(id, false)
+	if err != nil {
+		return created, 0, err
+	}
+	if len(fp.PackagingUnits) > 0 && len(product.PackagingUnits) == 0 {
+		if _, err := s.products.SetPackagingUnits(id, fp.PackagingUnits, actor); err != nil {
+			return created, 0, err
+		}
+	}
+	if len(fp.OptionTypes) > 0 && len(product.OptionTypes) == 0 {
+		if _, err := s.variants.SetOptionTypes(id, fp.OptionTypes, actor); err != nil {
+			return created, 0, err
+		}
+	}
+
+	skus := make(map[string]bool, len(product.Variants))
+	for _, v := range product.Variants {
+		skus[v.SKU] = true
+	}
+	variants := 0
+	for _, fv := range fp.Variants {
+		if skus[strings.TrimSpace(fv.SKU)] {
+			continue
+		}
+		variant := models.Variant{
+			ProductID: id,
+			SKU:       fv.SKU,
+			Barcode:   fv.Barcode,
+			Options:   fv.Options,
+			Price:     fv.Price,
+			Stock:     fv.Stock,
+		}
+		if err := s.variants.Create(&variant, actor); err != nil {
+			return created, variants, fmt.Errorf("variant %q: %w", fv.SKU, err)
+		}
+		variants++
+	}
+	return created, variants, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"cashier-api/database"
+	"cashier-api/models"
+)
+
+func TestParseFixture(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"yaml", "categories:\n  - name: Food\n", nil},
+		{"json", `{"products": [{"name": "Tea", "price": 5000, "stock": "1.5", "unit": "kg", "category": "Food"}]}`, nil},
+		{"unknown field", "categories:\n  - name: Food\n    colour: red\n", models.ErrInvalidFixture},
+		{"invalid quantity", `{"products": [{"name": "Tea", "stock": "a lot"}]}`, models.ErrInvalidFixture},
+		{"not a document", "- [", models.ErrInvalidFixture},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFixture([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func sampleFixture(t *testing.T) *models.Fixture {
+	t.Helper()
+	fixture, err := ParseFixture(database.SampleFixture)
+	if err != nil {
+		t.Fatal("sample fixture: ", err)
+	}
+	return fixture
+}
+
+func newSeedService(c *fakeCatalog) *SeedService {
+	return NewSeedService(c.categories, c.stores, c.products, c.variants)
+}
+
+func TestSeedIsIdempotent(t *testing.T) {
+	fixture := sampleFixture(t)
+	variants := 0
+	for _, p := range fixture.Products {
+		variants += len(p.Variants)
+	}
+	if variants == 0 {
+		t.Fatal("sample fixture has no variants")
+	}
+
+	c := newFakeCatalog()
+	seed := newSeedService(c)
+	actor := models.Actor{Name: "cli"}
+
+	result, err := seed.Seed(fixture, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.SeedResult{Categories: len(fixture.Categories), Stores: len(fixture.Stores),
+		Products: len(fixture.Products), Variants: variants}
+	if *result != want {
+		t.Errorf("first run = %+v, want %+v", *result, want)
+	}
+	for _, name := range c.variantRepo.audit {
+		if name != actor.Name {
+			t.Fatalf("variant created by %q, want %q", name, actor.Name)
+		}
+	}
+
+	result, err = seed.Seed(fixture, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = models.SeedResult{Skipped: len(fixture.Categories) + len(fixture.Stores) + len(fixture.Products)}
+	if *result != want {
+		t.Errorf("second run = %+v, want %+v", *result, want)
+	}
+	if len(c.variantRepo.audit) != variants {
+		t.Errorf("%d variants after two runs, want %d", len(c.variantRepo.audit), variants)
+	}
+}
+
+// TestSeedResumesMissingVariants - A run that failed after creating a
+// product gets its remaining variants created by the next run
+func TestSeedResumesMissingVariants(t *testing.T) {
+	fixture := &models.Fixture{
+		Categories: []models.FixtureCategory{{Name: "Beverages"}},
+		Products: []models.FixtureProduct{{
+			Name: "Vit 1000ml", Price: 3000, Stock: models.NewQuantity(40), Category: "Beverages",
+			PackagingUnits: []models.PackagingUnit{{Name: "box", Factor: models.NewQuantity(12)}},
+			OptionTypes:    []string{"Flavor"},
+			Variants: []models.FixtureVariant{
+				{SKU: "VIT1000-ORI", Options: map[string]string{"Flavor": "Original"}, Stock: models.NewQuantity(30)},
+				{SKU: "VIT1000-LMN", Options: map[string]string{"Flavor": "Lemon"}, Stock: models.NewQuantity(10)},
+			},
+		}},
+	}
+
+	c := newFakeCatalog()
+	seed := newSeedService(c)
+	actor := models.Actor{Name: "cli"}
+
+	c.variantRepo.failCreate = "VIT1000-LMN"
+	if _, err := seed.Seed(fixture, actor); err == nil {
+		t.Fatal("first run succeeded, want the variant error")
+	}
+
+	c.variantRepo.failCreate = ""
+	result, err := seed.Seed(fixture, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.SeedResult{Variants: 1, Skipped: 1}
+	if *result != want {
+		t.Errorf("second run = %+v, want %+v", *result, want)
+	}
+
+	product := c.productRepo.products[1]
+	variants, _ := c.variantRepo.GetByProductID(product.ID)
+	if len(c.productRepo.products) != 1 || len(variants) != 2 {
+		t.Errorf("%d products and %d variants, want 1 and 2", len(c.productRepo.products), len(variants))
+	}
+	if len(product.PackagingUnits) != 1 || len(product.OptionTypes) != 1 {
+		t.Errorf("packaging units %v, option types %v set more than once", product.PackagingUnits, product.OptionTypes)
+	}
+}
+
+// TestSeedCompletesProduct - Packaging units and option types a stopped run
+// did not get to are set before the variants
+func TestSeedCompletesProduct(t *testing.T) {
+	c := newFakeCatalog()
+	category := c.categoryRepo.add(models.Category{Name: "Beverages"})
+	c.productRepo.add(models.ProductDetail{Name: "Vit 1000ml", Price: 3000, CategoryID: category.ID})
+
+	fixture := &models.Fixture{Products: []models.FixtureProduct{{
+		Name: "Vit 1000ml", Price: 3000, Category: "Beverages",
+		OptionTypes: []string{"Flavor"},
+		Variants:    []models.FixtureVariant{{SKU: "VIT1000-ORI", Options: map[string]string{"Flavor": "Original"}}},
+	}}}
+
+	result, err := newSeedService(c).Seed(fixture, models.Actor{Name: "cli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Products != 0 || result.Variants != 1 {
+		t.Errorf("result = %+v, want only the variant created", *result)
+	}
+	if got := c.productRepo.products[1].OptionTypes; len(got) != 1 || got[0] != "Flavor" {
+		t.Errorf("option types = %v, want [Flavor]", got)
+	}
+}
+
+func TestSeedUnknownCategory(t *testing.T) {
+	fixture := &models.Fixture{Products: []models.FixtureProduct{{Name: "Tea", Price: 5000, Category: "Drinks"}}}
+
+	_, err := newSeedService(newFakeCatalog()).Seed(fixture, models.Actor{Name: "cli"})
+	if !errors.Is(err, models.ErrFixtureCategory) {
+		t.Errorf("error = %v, want %v", err, models.ErrFixtureCategory)
+	}
+}
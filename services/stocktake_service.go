@@ -0,0 +1,212 @@
+package services
+
+import (
+	"cashier-api/events"
+	"cashier-api/models"
+	"strings"
+)
+
+type StocktakeService struct {
+	stocktakeRepo StocktakeRepository
+	productRepo   ProductRepository
+	categoryRepo  CategoryRepository
+	storeRepo     StoreRepository
+	broker        *events.Broker // nil = no event stream
+}
+
+func NewStocktakeService(stocktakeRepo StocktakeRepository, productRepo ProductRepository,
+	categoryRepo CategoryRepository, storeRepo StoreRepository,
+	broker *events.Broker) *StocktakeService {
+	return &StocktakeService{
+		stocktakeRepo: stocktakeRepo,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		storeRepo:     storeRepo,
+		broker:        broker,
+	}
+}
+
+func (s *StocktakeService) GetAll() ([]models.Stocktake, error) {
+	return s.stocktakeRepo.GetAll()
+}
+
+// GetByID - Stock take with its items and their current variance
+func (s *StocktakeService) GetByID(id int) (*models.Stocktake, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+
+	stocktake, err := s.stocktakeRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if stocktake.Items, err = s.stocktakeRepo.GetItems(id); err != nil {
+		return nil, err
+	}
+	for i := range stocktake.Items {
+		computeVariance(&stocktake.Items[i])
+	}
+	return stocktake, nil
+}
+
+// Create - Open a stock take for the whole catalog or a category subtree, of
+// the central stock or of one store
+func (s *StocktakeService) Create(input models.StocktakeInput) (*models.Stocktake, error) {
+	if input.CategoryID != nil {
+		if *input.CategoryID <= 0 {
+			return nil, models.ErrInvalidCategoryID
+		}
+		if _, err := s.categoryRepo.GetByID(*input.CategoryID, false); err != nil {
+			return nil, err
+		}
+	}
+	if input.StoreID != nil {
+		if *input.StoreID <= 0 {
+			return nil, models.ErrInvalidStoreID
+		}
+		if _, err := s.storeRepo.GetByID(*input.StoreID); err != nil {
+			return nil, err
+		}
+	}
+
+	stocktake := &models.Stocktake{
+		Note:       strings.TrimSpace(input.Note),
+		CategoryID: input.CategoryID,
+		StoreID:    input.StoreID,
+	}
+	if err := s.stocktakeRepo.Create(stocktake); err != nil {
+		return nil, err
+	}
+	return stocktake, nil
+}
+
+// RecordCounts - Convert counts to the product unit and store them. All
+// counts of a request are stored together or not at all.
+func (s *StocktakeService) RecordCounts(id int, counts []models.StocktakeCount) (*models.Stocktake, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if len(counts) == 0 {
+		return nil, models.ErrEmptyCounts
+	}
+
+	for i := range counts {
+		count := &counts[i]
+		if count.Mode == "" {
+			count.Mode = models.CountModeAdd
+		}
+		if count.Mode != models.CountModeAdd && count.Mode != models.CountModeSet {
+			return nil, models.ErrInvalidCountMode
+		}
+		if count.ProductID <= 0 {
+			return nil, models.ErrInvalidID
+		}
+		if count.Mode == models.CountModeSet && count.Quantity < 0 {
+			return nil, models.ErrNegativeCount
+		}
+
+		product, err := s.productRepo.GetByID(count.ProductID, true)
+		if err != nil {
+			return nil, err
+		}
+		if count.Quantity, err = convertToProductUnit(product, count.Quantity, count.Unit); err != nil {
+			return nil, err
+		}
+		count.Unit = product.Unit
+	}
+
+	if err := s.stocktakeRepo.RecordCounts(id, counts); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Finalize - Apply the variances to the stock and return the variance report
+func (s *StocktakeService) Finalize(id int) (*models.VarianceReport, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if err := s.stocktakeRepo.Finalize(id); err != nil {
+		return nil, err
+	}
+	s.productRepo.InvalidateCache()
+
+	report, err := s.Report(id)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.productRepo
+	if report.Stocktake.StoreID != nil {
+		repo = repo.ForStore(*report.Stocktake.StoreID)
+	}
+	for _, category := range report.Categories {
+		for _, item := range category.Items {
+			// Applied is set for every item whose count differed
+			if item.Applied != nil {
+				publishStock(s.broker, repo, item.ProductID, models.StockSourceStocktake)
+			}
+		}
+	}
+	return report, nil
+}
+
+// Report - Variance by category and value; a preview while the stock take
+// is open, the final result once finalized
+func (s *StocktakeService) Report(id int) (*models.VarianceReport, error) {
+	stocktake, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.VarianceReport{Categories: []models.CategoryVariance{}, Clamped: []models.StocktakeItem{}}
+	for _, item := range stocktake.Items {
+		// Items are ordered by category, so each category is a consecutive run
+		if n := len(report.Categories); n == 0 || report.Categories[n-1].CategoryID != item.CategoryID {
+			report.Categories = append(report.Categories, models.CategoryVariance{
+				CategoryID:   item.CategoryID,
+				CategoryName: item.CategoryName,
+			})
+		}
+		category := &report.Categories[len(report.Categories)-1]
+		category.Items = append(category.Items, item)
+		addVariance(&category.VarianceSummary, item)
+		addVariance(&report.Totals, item)
+		if item.Clamped {
+			report.Clamped = append(report.Clamped, item)
+		}
+	}
+
+	stocktake.Items = nil
+	report.Stocktake = *stocktake
+	return report, nil
+}
+
+// computeVariance - Fill Variance and VarianceValue of a counted item
+func computeVariance(item *models.StocktakeItem) {
+	if item.Counted == nil {
+		return
+	}
+	variance := *item.Counted - item.Expected
+	value := variance.Cost(item.Price)
+	item.Variance = &variance
+	item.VarianceValue = &value
+	item.Clamped = item.Applied != nil && *item.Applied != variance
+}
+
+func addVariance(summary *models.VarianceSummary, item models.StocktakeItem) {
+	summary.Items++
+	if item.VarianceValue == nil {
+		return
+	}
+	summary.CountedItems++
+	if *item.VarianceValue < 0 {
+		summary.ShortageValue += *item.VarianceValue
+	} else {
+		summary.SurplusValue += *item.VarianceValue
+	}
+	summary.NetValue += *item.VarianceValue
+	if item.Clamped {
+		summary.ClampedItems++
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"cashier-api/events"
+	"cashier-api/models"
+)
+
+type stocktakeCatalog struct {
+	*fakeCatalog
+	stocktakeRepo *fakeStocktakeRepo
+	stocktakes    *StocktakeService
+}
+
+// newStocktakeCatalog - Food (1) > Snacks (2) and Drinks (3) with Indomie
+// (1, 10 pcs), Chitato (2, 5 pcs, in Snacks), Beras (3, 20 kg), Aqua (4,
+// 48 pcs sold by the box of 24) and an archived product (5); store 1
+func newStocktakeCatalog() *stocktakeCatalog {
+	c := newFakeCatalog()
+	c.categoryRepo.add(models.Category{ID: 1, Name: "Food"})
+	c.categoryRepo.add(models.Category{ID: 2, Name: "Snacks", ParentID: categoryID(1)})
+	c.categoryRepo.add(models.Category{ID: 3, Name: "Drinks"})
+	c.productRepo.add(models.ProductDetail{ID: 1, Name: "Indomie", Price: 3500, Stock: models.NewQuantity(10), CategoryID: 1})
+	c.productRepo.add(models.ProductDetail{ID: 2, Name: "Chitato", Price: 9000, Stock: models.NewQuantity(5), CategoryID: 2})
+	c.productRepo.add(models.ProductDetail{ID: 3, Name: "Beras", Price: 14000, Stock: models.NewQuantity(20),
+		Unit: models.UnitKilogram, CategoryID: 1})
+	c.productRepo.add(models.ProductDetail{ID: 4, Name: "Aqua", Price: 4000, Stock: models.NewQuantity(48), CategoryID: 3,
+		PackagingUnits: []models.PackagingUnit{{Name: "box", Factor: models.NewQuantity(24)}}})
+	archived := c.productRepo.add(models.ProductDetail{ID: 5, Name: "Rokok", Price: 30000, Stock: models.NewQuantity(7), CategoryID: 1})
+	archived.DeletedAt = &time.Time{}
+	c.storeRepo.stores = []models.Store{{ID: 1, Code: "JKT", Name: "Jakarta"}}
+
+	repo := newFakeStocktakeRepo(c.productRepo)
+	return &stocktakeCatalog{
+		fakeCatalog:   c,
+		stocktakeRepo: repo,
+		stocktakes:    NewStocktakeService(repo, c.productRepo, c.categoryRepo, c.storeRepo, c.broker),
+	}
+}
+
+// open - Open a stock take of input, failing the test on error
+func (c *stocktakeCatalog) open(t *testing.T, input models.StocktakeInput) *models.Stocktake {
+	t.Helper()
+	stocktake, err := c.stocktakes.Create(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stocktake
+}
+
+func itemProducts(items []models.StocktakeItem) []int {
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+	return ids
+}
+
+func TestStocktakeCreate(t *testing.T) {
+	store := 1
+	unknownStore := 9
+
+	tests := []struct {
+		name         string
+		input        models.StocktakeInput
+		wantErr      error
+		wantProducts []int // in report order: by category name, then product name
+	}{
+		{"whole catalog", models.StocktakeInput{}, nil, []int{4, 3, 1, 2}},
+		{"category subtree", models.StocktakeInput{CategoryID: categoryID(1)}, nil, []int{3, 1, 2}},
+		{"subcategory", models.StocktakeInput{CategoryID: categoryID(2)}, nil, []int{2}},
+		{"store", models.StocktakeInput{StoreID: &store}, nil, []int{4, 3, 1, 2}},
+		{"unknown category", models.StocktakeInput{CategoryID: categoryID(9)}, errors.New("category not found"), nil},
+		{"invalid category", models.StocktakeInput{CategoryID: categoryID(0)}, models.ErrInvalidCategoryID, nil},
+		{"unknown store", models.StocktakeInput{StoreID: &unknownStore}, models.ErrStoreNotFound, nil},
+		{"invalid store", models.StocktakeInput{StoreID: categoryID(-1)}, models.ErrInvalidStoreID, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newStocktakeCatalog()
+			tt.input.Note = "  October count "
+
+			stocktake, err := c.stocktakes.Create(tt.input)
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.stocktakeRepo.stocktakes) != 0 {
+					t.Error("rejected stock take was saved")
+				}
+				return
+			}
+
+			if stocktake.Note != "October count" || stocktake.Status != models.StocktakeOpen ||
+				stocktake.ItemCount != len(tt.wantProducts) {
+				t.Errorf("created %+v", stocktake)
+			}
+			detail, err := c.stocktakes.GetByID(stocktake.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := itemProducts(detail.Items); !slices.Equal(got, tt.wantProducts) {
+				t.Errorf("items %v, want %v", got, tt.wantProducts)
+			}
+			for _, item := range detail.Items {
+				if item.ProductID == 1 && (item.Expected != models.NewQuantity(10) || item.Price != 3500 || item.Counted != nil) {
+					t.Errorf("snapshot %+v", item)
+				}
+			}
+		})
+	}
+}
+
+// TestStocktakeOnePerStore - Only one stock take can be open per store (the
+// central stock counts as one); finalizing allows the next
+func TestStocktakeOnePerStore(t *testing.T) {
+	c := newStocktakeCatalog()
+	store := 1
+	central := c.open(t, models.StocktakeInput{})
+
+	if _, err := c.stocktakes.Create(models.StocktakeInput{CategoryID: categoryID(3)}); err != models.ErrStocktakeOpenExists {
+		t.Errorf("second central stock take: %v", err)
+	}
+	c.open(t, models.StocktakeInput{StoreID: &store})
+	if _, err := c.stocktakes.Create(models.StocktakeInput{StoreID: &store}); err != models.ErrStocktakeOpenExists {
+		t.Errorf("second store stock take: %v", err)
+	}
+
+	if _, err := c.stocktakes.Finalize(central.ID); err != nil {
+		t.Fatal(err)
+	}
+	next := c.open(t, models.StocktakeInput{})
+
+	list, err := c.stocktakes.GetAll()
+	if err != nil || len(list) != 3 || list[0].ID != next.ID {
+		t.Errorf("stock takes %+v, %v; want 3, newest first", list, err)
+	}
+}
+
+func TestStocktakeRecordCounts(t *testing.T) {
+	c := newStocktakeCatalog()
+	stocktake := c.open(t, models.StocktakeInput{})
+
+	// Two devices count the same shelf
+	for _, n := range []int64{4, 5} {
+		_, err := c.stocktakes.RecordCounts(stocktake.ID, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(n)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	detail, err := c.stocktakes.RecordCounts(stocktake.ID, []models.StocktakeCount{
+		{ProductID: 3, Quantity: models.NewQuantity(19500), Unit: "g"},
+		{ProductID: 4, Quantity: models.NewQuantity(2), Unit: " BOX ", Mode: models.CountModeSet},
+		{ProductID: 4, Quantity: models.NewQuantity(3)}, // a loose bottle or three
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counted := map[int]string{}
+	for _, item := range detail.Items {
+		if item.Counted != nil {
+			counted[item.ProductID] = item.Counted.String() + " " + item.Variance.String()
+		}
+	}
+	want := map[int]string{1: "9 -1", 3: "19.5 -0.5", 4: "51 3"}
+	for id, w := range want {
+		if counted[id] != w {
+			t.Errorf("product %d counted/variance %q, want %q", id, counted[id], w)
+		}
+	}
+	if len(counted) != 3 || detail.CountedItems != 3 {
+		t.Errorf("%d counted items, want 3", detail.CountedItems)
+	}
+
+	// A recount replaces
+	detail, err = c.stocktakes.RecordCounts(stocktake.ID, []models.StocktakeCount{
+		{ProductID: 1, Quantity: models.NewQuantity(8), Mode: models.CountModeSet},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range detail.Items {
+		if item.ProductID == 1 && (*item.Counted != models.NewQuantity(8) || *item.VarianceValue != -7000) {
+			t.Errorf("recount %s, variance value %d; want 8 and -7000", item.Counted, *item.VarianceValue)
+		}
+	}
+	if c.productRepo.products[1].Stock != models.NewQuantity(10) {
+		t.Error("counting changed the stock")
+	}
+}
+
+func TestStocktakeRecordCountsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		counts  []models.StocktakeCount
+		wantErr error
+	}{
+		{"no counts", 1, nil, models.ErrEmptyCounts},
+		{"unknown mode", 1, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(1), Mode: "replace"}}, models.ErrInvalidCountMode},
+		{"invalid product ID", 1, []models.StocktakeCount{{Quantity: models.NewQuantity(1)}}, models.ErrInvalidID},
+		{"negative set", 1, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(-1), Mode: models.CountModeSet}}, models.ErrNegativeCount},
+		{"correction below zero", 1, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(-3)}}, models.ErrNegativeCount},
+		{"fractional pieces", 1, []models.StocktakeCount{{ProductID: 1, Quantity: mustQuantity("1.5")}}, models.ErrFractionalQuantity},
+		{"unknown unit", 1, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(1), Unit: "crate"}}, models.ErrUnknownUnit},
+		{"incompatible unit", 1, []models.StocktakeCount{{ProductID: 3, Quantity: models.NewQuantity(1), Unit: "l"}}, models.ErrIncompatibleUnit},
+		{"product outside the stock take", 1, []models.StocktakeCount{{ProductID: 4, Quantity: models.NewQuantity(1)}}, models.ErrStocktakeProduct},
+		{"archived product", 1, []models.StocktakeCount{{ProductID: 5, Quantity: models.NewQuantity(1)}}, models.ErrStocktakeProduct},
+		{"unknown product", 1, []models.StocktakeCount{{ProductID: 9, Quantity: models.NewQuantity(1)}}, errors.New("product not found")},
+		{"one bad count", 1, []models.StocktakeCount{
+			{ProductID: 2, Quantity: models.NewQuantity(4)},
+			{ProductID: 4, Quantity: models.NewQuantity(1)},
+		}, models.ErrStocktakeProduct},
+		{"unknown stock take", 9, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(1)}}, models.ErrStocktakeNotFound},
+		{"finalized", 2, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(1)}}, models.ErrStocktakeNotOpen},
+		{"invalid ID", 0, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(1)}}, models.ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newStocktakeCatalog()
+			store := 1
+			food := c.open(t, models.StocktakeInput{CategoryID: categoryID(1)}) // 1
+			finalized := c.open(t, models.StocktakeInput{StoreID: &store})      // 2
+			if _, err := c.stocktakes.Finalize(finalized.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.stocktakes.RecordCounts(food.ID, []models.StocktakeCount{{ProductID: 1, Quantity: models.NewQuantity(2)}}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := c.stocktakes.RecordCounts(tt.id, tt.counts)
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			detail, _ := c.stocktakes.GetByID(food.ID)
+			if detail.CountedItems != 1 {
+				t.Errorf("%d counted items after a rejected request, want 1", detail.CountedItems)
+			}
+		})
+	}
+}
+
+func TestStocktakeFinalize(t *testing.T) {
+	c := newStocktakeCatalog()
+	stocktake := c.open(t, models.StocktakeInput{})
+	_, err := c.stocktakes.RecordCounts(stocktake.ID, []models.StocktakeCount{
+		{ProductID: 1, Quantity: models.NewQuantity(8)},  // 2 short
+		{ProductID: 2, Quantity: models.NewQuantity(5)},  // as expected
+		{ProductID: 4, Quantity: models.NewQuantity(50)}, // 2 extra
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The preview changes nothing
+	preview, err := c.stocktakes.Report(stocktake.ID)
+	if err != nil || preview.Totals.NetValue != 1000 || preview.Totals.ClampedItems != 0 {
+		t.Fatalf("preview %+v, %v", preview, err)
+	}
+	if c.productRepo.products[1].Stock != models.NewQuantity(10) {
+		t.Fatal("preview changed the stock")
+	}
+
+	// 9 Indomie sold while counting: only 1 left to take
+	c.productRepo.products[1].Stock = models.NewQuantity(1)
+	sub := c.broker.Subscribe(events.Filter{}, 0, false)
+	defer c.broker.Unsubscribe(sub)
+
+	report, err := c.stocktakes.Finalize(stocktake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stocks := map[int]int64{1: 0, 2: 5, 3: 20, 4: 50}
+	for id, want := range stocks {
+		if got := c.productRepo.products[id].Stock; got != models.NewQuantity(want) {
+			t.Errorf("product %d stock %s, want %d", id, got, want)
+		}
+	}
+
+	if report.Stocktake.Status != models.StocktakeFinalized || report.Stocktake.FinalizedAt == nil || report.Stocktake.Items != nil {
+		t.Errorf("stock take %+v", report.Stocktake)
+	}
+	wantTotals := models.VarianceSummary{Items: 4, CountedItems: 3, ShortageValue: -7000, SurplusValue: 8000, NetValue: 1000, ClampedItems: 1}
+	if report.Totals != wantTotals {
+		t.Errorf("totals %+v, want %+v", report.Totals, wantTotals)
+	}
+
+	var categories []string
+	for _, category := range report.Categories {
+		categories = append(categories, category.CategoryName)
+	}
+	if !slices.Equal(categories, []string{"Drinks", "Food", "Snacks"}) {
+		t.Errorf("categories %v", categories)
+	}
+	food := report.Categories[1]
+	wantFood := models.VarianceSummary{Items: 2, CountedItems: 1, ShortageValue: -7000, NetValue: -7000, ClampedItems: 1}
+	if food.VarianceSummary != wantFood || !slices.Equal(itemProducts(food.Items), []int{3, 1}) {
+		t.Errorf("food %+v", food)
+	}
+
+	if len(report.Clamped) != 1 || report.Clamped[0].ProductID != 1 || *report.Clamped[0].Applied != models.NewQuantity(-1) {
+		t.Errorf("clamped %+v, want Indomie with -1 applied", report.Clamped)
+	}
+	if snacks := report.Categories[2].Items[0]; snacks.Applied != nil || *snacks.Variance != 0 {
+		t.Errorf("counted as expected: %+v", snacks)
+	}
+
+	var changed []int
+	for len(sub.Events()) > 0 {
+		e := <-sub.Events()
+		if e.Type != models.EventStockChanged || e.Data.Source != models.StockSourceStocktake {
+			t.Errorf("event %s %+v", e.Type, e.Data)
+		}
+		changed = append(changed, e.Data.ProductID)
+	}
+	if !slices.Equal(changed, []int{4, 1}) {
+		t.Errorf("stock.changed for %v, want the adjusted products 4 and 1", changed)
+	}
+
+	if _, err := c.stocktakes.Finalize(stocktake.ID); err != models.ErrStocktakeNotOpen {
+		t.Errorf("finalized twice: %v", err)
+	}
+	if c.productRepo.products[4].Stock != models.NewQuantity(50) {
+		t.Error("second finalize applied the variance again")
+	}
+	if _, err := c.stocktakes.Finalize(9); err != models.ErrStocktakeNotFound {
+		t.Errorf("unknown stock take: %v", err)
+	}
+	if _, err := c.stocktakes.Finalize(0); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+	if _, err := c.stocktakes.GetByID(-1); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+}
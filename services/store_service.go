@@ -0,0 +1,56 @@
+package services
+
+import (
+	"cashier-api/models"
+	"strings"
+)
+
+type StoreService struct {
+	repo StoreRepository
+}
+
+func NewStoreService(repo StoreRepository) *StoreService {
+	return &StoreService{repo: repo}
+}
+
+func (s *StoreService) GetAll() ([]models.Store, error) {
+	return s.repo.GetAll()
+}
+
+func (s *StoreService) GetByID(id int) (*models.Store, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *StoreService) Create(store *models.Store) error {
+	if err := validateStore(store); err != nil {
+		return err
+	}
+	return s.repo.Create(store)
+}
+
+func (s *StoreService) Update(store *models.Store) error {
+	if store.ID <= 0 {
+		return models.ErrInvalidID
+	}
+	if err := validateStore(store); err != nil {
+		return err
+	}
+	return s.repo.Update(store)
+}
+
+// validateStore - Codes are stored upper case so "jkt-01" and "JKT-01" clash
+func validateStore(store *models.Store) error {
+	store.Code = strings.ToUpper(strings.TrimSpace(store.Code))
+	store.Name = strings.TrimSpace(store.Name)
+	store.Address = strings.TrimSpace(store.Address)
+	if store.Code == "" {
+		return models.ErrStoreCodeRequired
+	}
+	if store.Name == "" {
+		return models.ErrNameRequired
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"cashier-api/events"
+	"cashier-api/models"
+	"strings"
+)
+
+type TransferService struct {
+	transferRepo TransferRepository
+	storeRepo    StoreRepository
+	productRepo  ProductRepository
+	broker       *events.Broker // nil = no event stream
+}
+
+func NewTransferService(transferRepo TransferRepository, storeRepo StoreRepository,
+	productRepo ProductRepository, broker *events.Broker) *TransferService {
+	return &TransferService{
+		transferRepo: transferRepo,
+		storeRepo:    storeRepo,
+		productRepo:  productRepo,
+		broker:       broker,
+	}
+}
+
+func (s *TransferService) GetAll(filter models.TransferFilter) ([]models.Transfer, error) {
+	switch filter.Status {
+	case "", models.TransferDraft, models.TransferShipped, models.TransferReceived:
+	default:
+		return nil, models.ErrInvalidTransferStatus
+	}
+	return s.transferRepo.GetAll(filter)
+}
+
+// GetByID - Transfer with its lines and, once received, their discrepancies
+func (s *TransferService) GetByID(id int) (*models.Transfer, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+
+	transfer, err := s.transferRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if transfer.Lines, err = s.transferRepo.GetLines(id); err != nil {
+		return nil, err
+	}
+	for i := range transfer.Lines {
+		line := &transfer.Lines[i]
+		if line.Received != nil {
+			discrepancy := *line.Received - line.Quantity
+			line.Discrepancy = &discrepancy
+		}
+	}
+	return transfer, nil
+}
+
+// Create - Save a draft transfer; no stock moves until it is shipped
+func (s *TransferService) Create(input models.TransferInput, createdBy string) (*models.Transfer, error) {
+	transfer, err := s.build(input)
+	if err != nil {
+		return nil, err
+	}
+	transfer.CreatedBy = optionalActor(createdBy)
+
+	if err := s.transferRepo.Create(transfer); err != nil {
+		return nil, err
+	}
+	return s.GetByID(transfer.ID)
+}
+
+// Update - Replace a draft transfer
+func (s *TransferService) Update(id int, input models.TransferInput) (*models.Transfer, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	transfer, err := s.build(input)
+	if err != nil {
+		return nil, err
+	}
+	transfer.ID = id
+
+	if err := s.transferRepo.Update(transfer); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+func (s *TransferService) Delete(id int) error {
+	if id <= 0 {
+		return models.ErrInvalidID
+	}
+	return s.transferRepo.Delete(id)
+}
+
+// Ship - Take the goods from the source store
+func (s *TransferService) Ship(id int, shippedBy string) (*models.Transfer, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if err := s.transferRepo.Ship(id, optionalActor(shippedBy)); err != nil {
+		return nil, err
+	}
+	s.productRepo.InvalidateCache()
+
+	transfer, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.productRepo.ForStore(transfer.SourceStoreID)
+	for _, line := range transfer.Lines {
+		publishStock(s.broker, repo, line.ProductID, models.StockSourceTransfer)
+	}
+	return transfer, nil
+}
+
+// Receive - Add what arrived to the destination store. input.Lines lists
+// the products that did not arrive complete, in any unit they convert from.
+func (s *TransferService) Receive(id int, input models.TransferReceiveInput, receivedBy string) (*models.Transfer, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+
+	received := map[int]models.Quantity{}
+	for _, line := range input.Lines {
+		if line.ProductID <= 0 {
+			return nil, models.ErrInvalidID
+		}
+		if line.Quantity < 0 {
+			return nil, models.ErrNegativeReceived
+		}
+		if _, ok := received[line.ProductID]; ok {
+			return nil, models.ErrTransferDuplicateLine
+		}
+
+		product, err := s.productRepo.GetByID(line.ProductID, true)
+		if err != nil {
+			return nil, err
+		}
+		if received[line.ProductID], err = convertToProductUnit(product, line.Quantity, line.Unit); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.transferRepo.Receive(id, received, optionalActor(receivedBy)); err != nil {
+		return nil, err
+	}
+	s.productRepo.InvalidateCache()
+
+	transfer, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.productRepo.ForStore(transfer.DestinationStoreID)
+	for _, line := range transfer.Lines {
+		// Lines that arrived empty left the destination stock alone
+		if line.Received != nil && *line.Received != 0 {
+			publishStock(s.broker, repo, line.ProductID, models.StockSourceTransfer)
+		}
+	}
+	return transfer, nil
+}
+
+// build - Validate the stores and convert the lines to the product unit
+func (s *TransferService) build(input models.TransferInput) (*models.Transfer, error) {
+	if input.SourceStoreID <= 0 || input.DestinationStoreID <= 0 {
+		return nil, models.ErrInvalidStoreID
+	}
+	if input.SourceStoreID == input.DestinationStoreID {
+		return nil, models.ErrTransferSameStore
+	}
+	if len(input.Lines) == 0 {
+		return nil, models.ErrTransferEmpty
+	}
+	for _, storeID := range []int{input.SourceStoreID, input.DestinationStoreID} {
+		if _, err := s.storeRepo.GetByID(storeID); err != nil {
+			return nil, err
+		}
+	}
+
+	transfer := &models.Transfer{
+		SourceStoreID:      input.SourceStoreID,
+		DestinationStoreID: input.DestinationStoreID,
+		Note:               strings.TrimSpace(input.Note),
+	}
+	seen := map[int]bool{}
+	for _, line := range input.Lines {
+		if line.ProductID <= 0 {
+			return nil, models.ErrInvalidID
+		}
+		if line.Quantity <= 0 {
+			return nil, models.ErrTransferQuantity
+		}
+		if seen[line.ProductID] {
+			return nil, models.ErrTransferDuplicateLine
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.productRepo.GetByID(line.ProductID, false)
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := convertToProductUnit(product, line.Quantity, line.Unit)
+		if err != nil {
+			return nil, err
+		}
+		transfer.Lines = append(transfer.Lines, models.TransferLine{
+			ProductID: line.ProductID,
+			Quantity:  quantity,
+		})
+	}
+	return transfer, nil
+}
+
+// optionalActor - nil when the request did not say who made it
+func optionalActor(actor string) *string {
+	if actor = strings.TrimSpace(actor); actor == "" {
+		return nil
+	}
+	return &actor
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"cashier-api/events"
+	"cashier-api/models"
+)
+
+type transferCatalog struct {
+	*fakeCatalog
+	transferRepo *fakeTransferRepo
+	transfers    *TransferService
+}
+
+// newTransferCatalog - The products of newStocktakeCatalog in Jakarta (1),
+// Bandung (2) and Surabaya (3). Jakarta has 50 Indomie, 30 kg of Beras and 96
+// Aqua; Bandung 2 Indomie.
+func newTransferCatalog() *transferCatalog {
+	c := newStocktakeCatalog().fakeCatalog
+	c.storeRepo.stores = []models.Store{{ID: 1, Code: "JKT", Name: "Jakarta"}, {ID: 2, Code: "BDG", Name: "Bandung"},
+		{ID: 3, Code: "SBY", Name: "Surabaya"}}
+	c.productRepo.storeStock[1] = map[int]models.Quantity{1: models.NewQuantity(50), 3: models.NewQuantity(30), 4: models.NewQuantity(96)}
+	c.productRepo.storeStock[2] = map[int]models.Quantity{1: models.NewQuantity(2)}
+
+	repo := newFakeTransferRepo(c.productRepo, c.storeRepo)
+	return &transferCatalog{fakeCatalog: c, transferRepo: repo,
+		transfers: NewTransferService(repo, c.storeRepo, c.productRepo, c.broker)}
+}
+
+func (c *transferCatalog) stock(storeID int, productID int) string {
+	return c.productRepo.storeStock[storeID][productID].String()
+}
+
+// jakartaToBandung - 10 Indomie, 2 boxes of Aqua and 2500 g of Beras
+func jakartaToBandung() models.TransferInput {
+	return models.TransferInput{SourceStoreID: 1, DestinationStoreID: 2, Note: " Weekly restock ", Lines: []models.TransferLineInput{
+		{ProductID: 1, Quantity: models.NewQuantity(10)},
+		{ProductID: 4, Quantity: models.NewQuantity(2), Unit: "box"},
+		{ProductID: 3, Quantity: models.NewQuantity(2500), Unit: "g"},
+	}}
+}
+
+func TestTransferCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(input *models.TransferInput)
+		wantErr error
+	}{
+		{"valid", func(input *models.TransferInput) {}, nil},
+		{"same store", func(input *models.TransferInput) { input.DestinationStoreID = 1 }, models.ErrTransferSameStore},
+		{"invalid source", func(input *models.TransferInput) { input.SourceStoreID = 0 }, models.ErrInvalidStoreID},
+		{"invalid destination", func(input *models.TransferInput) { input.DestinationStoreID = -2 }, models.ErrInvalidStoreID},
+		{"unknown destination", func(input *models.TransferInput) { input.DestinationStoreID = 9 }, models.ErrStoreNotFound},
+		{"no lines", func(input *models.TransferInput) { input.Lines = nil }, models.ErrTransferEmpty},
+		{"zero quantity", func(input *models.TransferInput) { input.Lines[0].Quantity = 0 }, models.ErrTransferQuantity},
+		{"negative quantity", func(input *models.TransferInput) { input.Lines[0].Quantity = models.NewQuantity(-1) }, models.ErrTransferQuantity},
+		{"duplicate product", func(input *models.TransferInput) { input.Lines[1].ProductID = 1 }, models.ErrTransferDuplicateLine},
+		{"invalid product", func(input *models.TransferInput) { input.Lines[0].ProductID = 0 }, models.ErrInvalidID},
+		{"archived product", func(input *models.TransferInput) { input.Lines[0].ProductID = 5 }, errors.New("product not found")},
+		{"unknown product", func(input *models.TransferInput) { input.Lines[0].ProductID = 9 }, errors.New("product not found")},
+		{"fractional pieces", func(input *models.TransferInput) { input.Lines[0].Quantity = mustQuantity("0.5") }, models.ErrFractionalQuantity},
+		{"unknown unit", func(input *models.TransferInput) { input.Lines[0].Unit = "crate" }, models.ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTransferCatalog()
+			input := jakartaToBandung()
+			tt.change(&input)
+
+			transfer, err := c.transfers.Create(input, " ayu ")
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.transferRepo.transfers) != 0 {
+					t.Error("rejected transfer was saved")
+				}
+				return
+			}
+
+			if transfer.Status != models.TransferDraft || transfer.Note != "Weekly restock" ||
+				transfer.CreatedBy == nil || *transfer.CreatedBy != "ayu" || transfer.DestinationStoreName != "Bandung" {
+				t.Errorf("created %+v", transfer)
+			}
+			var lines []string
+			for _, line := range transfer.Lines {
+				lines = append(lines, line.ProductName+" "+line.Quantity.String()+" "+line.Unit)
+			}
+			if want := []string{"Aqua 48 pcs", "Beras 2.5 kg", "Indomie 10 pcs"}; !slices.Equal(lines, want) {
+				t.Errorf("lines %q, want %q", lines, want)
+			}
+			if c.stock(1, 1) != "50" {
+				t.Error("a draft moved stock")
+			}
+		})
+	}
+}
+
+func TestTransferLifecycle(t *testing.T) {
+	c := newTransferCatalog()
+	jakarta := c.broker.Subscribe(events.Filter{StoreID: 1}, 0, false)
+	defer c.broker.Unsubscribe(jakarta)
+	bandung := c.broker.Subscribe(events.Filter{StoreID: 2}, 0, false)
+	defer c.broker.Unsubscribe(bandung)
+
+	transfer, err := c.transfers.Create(models.TransferInput{SourceStoreID: 1, DestinationStoreID: 3,
+		Lines: []models.TransferLineInput{{ProductID: 2, Quantity: models.NewQuantity(1)}}}, "ayu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The draft can still be changed
+	transfer, err = c.transfers.Update(transfer.ID, jakartaToBandung())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.DestinationStoreID != 2 || len(transfer.Lines) != 3 || *transfer.CreatedBy != "ayu" {
+		t.Errorf("updated %+v", transfer)
+	}
+
+	transfer, err = c.transfers.Ship(transfer.ID, "budi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.Status != models.TransferShipped || transfer.ShippedBy == nil || *transfer.ShippedBy != "budi" {
+		t.Errorf("shipped %+v", transfer)
+	}
+	for id, want := range map[int]string{1: "40", 3: "27.5", 4: "48"} {
+		if got := c.stock(1, id); got != want {
+			t.Errorf("Jakarta stock of %d %s after shipping, want %s", id, got, want)
+		}
+	}
+	if c.stock(2, 1) != "2" {
+		t.Error("shipping added to the destination")
+	}
+	stockEvents(t, jakarta, 1, []int{4, 3, 1})
+	stockEvents(t, bandung, 2, nil)
+
+	for name, err := range map[string]error{
+		"update":     func() error { _, err := c.transfers.Update(transfer.ID, jakartaToBandung()); return err }(),
+		"ship again": func() error { _, err := c.transfers.Ship(transfer.ID, ""); return err }(),
+		"delete":     c.transfers.Delete(transfer.ID),
+	} {
+		if err != models.ErrTransferNotDraft {
+			t.Errorf("%s after shipping: %v", name, err)
+		}
+	}
+
+	// 2 Indomie and a box of Aqua went missing; the Beras arrived complete
+	transfer, err = c.transfers.Receive(transfer.ID, models.TransferReceiveInput{Lines: []models.TransferLineInput{
+		{ProductID: 1, Quantity: models.NewQuantity(8)},
+		{ProductID: 4, Quantity: models.NewQuantity(1), Unit: "box"},
+	}}, "citra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.Status != models.TransferReceived || transfer.ReceivedBy == nil || *transfer.ReceivedBy != "citra" {
+		t.Errorf("received %+v", transfer)
+	}
+	var discrepancies []string
+	for _, line := range transfer.Lines {
+		discrepancies = append(discrepancies, line.Received.String()+" "+line.Discrepancy.String())
+	}
+	if want := []string{"24 -24", "2.5 0", "8 -2"}; !slices.Equal(discrepancies, want) {
+		t.Errorf("received/discrepancy %q, want %q", discrepancies, want)
+	}
+	for id, want := range map[int]string{1: "10", 3: "2.5", 4: "24"} {
+		if got := c.stock(2, id); got != want {
+			t.Errorf("Bandung stock of %d %s after receiving, want %s", id, got, want)
+		}
+	}
+	stockEvents(t, bandung, 2, []int{4, 3, 1})
+	stockEvents(t, jakarta, 1, nil)
+
+	if _, err := c.transfers.Receive(transfer.ID, models.TransferReceiveInput{}, ""); err != models.ErrTransferNotShipped {
+		t.Errorf("received twice: %v", err)
+	}
+	if c.stock(2, 1) != "10" {
+		t.Error("second receive added stock")
+	}
+}
+
+// stockEvents - The events queued for sub, a client of the stream of store,
+// are stock.changed of products in that store
+func stockEvents(t *testing.T, sub *events.Subscription, store int, products []int) {
+	t.Helper()
+	var got []int
+	for len(sub.Events()) > 0 {
+		e := <-sub.Events()
+		if e.Type != models.EventStockChanged || e.Data.Source != models.StockSourceTransfer ||
+			e.Data.StoreID == nil || *e.Data.StoreID != store {
+			t.Errorf("event %s %+v, want stock.changed in store %d", e.Type, e.Data, store)
+		}
+		got = append(got, e.Data.ProductID)
+	}
+	if !slices.Equal(got, products) {
+		t.Errorf("stock.changed for %v, want %v", got, products)
+	}
+}
+
+func TestTransferShipInsufficientStock(t *testing.T) {
+	c := newTransferCatalog()
+	transfer, err := c.transfers.Create(models.TransferInput{SourceStoreID: 2, DestinationStoreID: 1, Lines: []models.TransferLineInput{
+		{ProductID: 1, Quantity: models.NewQuantity(2)},
+		{ProductID: 4, Quantity: models.NewQuantity(1)}, // none in Bandung
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.transfers.Ship(transfer.ID, ""); err != models.ErrInsufficientStock {
+		t.Fatalf("error = %v, want %v", err, models.ErrInsufficientStock)
+	}
+	if c.stock(2, 1) != "2" {
+		t.Error("part of a failed shipment was taken")
+	}
+	if stored, _ := c.transfers.GetByID(transfer.ID); stored.Status != models.TransferDraft || stored.CreatedBy != nil {
+		t.Errorf("transfer %+v, want an anonymous draft", stored)
+	}
+	if _, err := c.transfers.Ship(9, ""); err != models.ErrTransferNotFound {
+		t.Errorf("unknown transfer: %v", err)
+	}
+	if _, err := c.transfers.Ship(0, ""); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+}
+
+func TestTransferReceiveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		lines   []models.TransferLineInput
+		wantErr error
+	}{
+		{"more than shipped", 1, []models.TransferLineInput{{ProductID: 1, Quantity: models.NewQuantity(11)}}, models.ErrReceivedExceedsShipped},
+		{"more than shipped in another unit", 1, []models.TransferLineInput{{ProductID: 3, Quantity: mustQuantity("2.6"), Unit: "kg"}}, models.ErrReceivedExceedsShipped},
+		{"negative", 1, []models.TransferLineInput{{ProductID: 1, Quantity: models.NewQuantity(-1)}}, models.ErrNegativeReceived},
+		{"product twice", 1, []models.TransferLineInput{
+			{ProductID: 1, Quantity: models.NewQuantity(5)},
+			{ProductID: 1, Quantity: models.NewQuantity(3)},
+		}, models.ErrTransferDuplicateLine},
+		{"product not shipped", 1, []models.TransferLineInput{{ProductID: 2, Quantity: models.NewQuantity(1)}}, models.ErrTransferProduct},
+		{"one bad line", 1, []models.TransferLineInput{
+			{ProductID: 1, Quantity: models.NewQuantity(5)},
+			{ProductID: 4, Quantity: models.NewQuantity(3), Unit: "box"},
+		}, models.ErrReceivedExceedsShipped},
+		{"unknown product", 1, []models.TransferLineInput{{ProductID: 9, Quantity: models.NewQuantity(1)}}, errors.New("product not found")},
+		{"invalid product", 1, []models.TransferLineInput{{Quantity: models.NewQuantity(1)}}, models.ErrInvalidID},
+		{"unknown unit", 1, []models.TransferLineInput{{ProductID: 1, Quantity: models.NewQuantity(1), Unit: "crate"}}, models.ErrUnknownUnit},
+		{"draft", 2, nil, models.ErrTransferNotShipped},
+		{"unknown transfer", 9, nil, models.ErrTransferNotFound},
+		{"invalid ID", 0, nil, models.ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTransferCatalog()
+			shipped, err := c.transfers.Create(jakartaToBandung(), "") // 1
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.transfers.Ship(shipped.ID, ""); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.transfers.Create(jakartaToBandung(), ""); err != nil { // 2, draft
+				t.Fatal(err)
+			}
+
+			_, err = c.transfers.Receive(tt.id, models.TransferReceiveInput{Lines: tt.lines}, "")
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if stored, _ := c.transfers.GetByID(shipped.ID); stored.Status != models.TransferShipped || c.stock(2, 1) != "2" {
+				t.Error("rejected receipt was recorded")
+			}
+		})
+	}
+}
+
+// TestTransferReceiveNothing - A line that arrived empty adds no stock and
+// publishes no event; its whole quantity is the discrepancy
+func TestTransferReceiveNothing(t *testing.T) {
+	c := newTransferCatalog()
+	transfer, _ := c.transfers.Create(models.TransferInput{SourceStoreID: 1, DestinationStoreID: 3, Lines: []models.TransferLineInput{
+		{ProductID: 1, Quantity: models.NewQuantity(5)},
+		{ProductID: 4, Quantity: models.NewQuantity(24)},
+	}}, "")
+	if _, err := c.transfers.Ship(transfer.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	sub := c.broker.Subscribe(events.Filter{StoreID: 3}, 0, false)
+	defer c.broker.Unsubscribe(sub)
+
+	transfer, err := c.transfers.Receive(transfer.ID, models.TransferReceiveInput{Lines: []models.TransferLineInput{
+		{ProductID: 1, Quantity: 0},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := transfer.Lines[1]; line.ProductID != 1 || *line.Discrepancy != models.NewQuantity(-5) {
+		t.Errorf("line %+v, want all 5 missing", line)
+	}
+	if c.stock(3, 1) != "0" || c.stock(3, 4) != "24" {
+		t.Errorf("Surabaya stock %s Indomie, %s Aqua", c.stock(3, 1), c.stock(3, 4))
+	}
+	stockEvents(t, sub, 3, []int{4})
+}
+
+func TestTransferListAndDelete(t *testing.T) {
+	c := newTransferCatalog()
+	received, _ := c.transfers.Create(jakartaToBandung(), "")
+	c.transfers.Ship(received.ID, "")
+	c.transfers.Receive(received.ID, models.TransferReceiveInput{}, "")
+	toSurabaya := jakartaToBandung()
+	toSurabaya.DestinationStoreID = 3
+	draft, _ := c.transfers.Create(toSurabaya, "")
+
+	tests := []struct {
+		filter models.TransferFilter
+		want   []int
+	}{
+		{models.TransferFilter{}, []int{draft.ID, received.ID}},
+		{models.TransferFilter{Status: models.TransferReceived}, []int{received.ID}},
+		{models.TransferFilter{StoreID: 3}, []int{draft.ID}},
+		{models.TransferFilter{StoreID: 1, Status: models.TransferShipped}, nil},
+	}
+	for _, tt := range tests {
+		list, err := c.transfers.GetAll(tt.filter)
+		var got []int
+		for _, transfer := range list {
+			got = append(got, transfer.ID)
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%+v: %v, %v; want %v", tt.filter, got, err, tt.want)
+		}
+	}
+	if _, err := c.transfers.GetAll(models.TransferFilter{Status: "lost"}); err != models.ErrInvalidTransferStatus {
+		t.Errorf("unknown status: %v", err)
+	}
+
+	if err := c.transfers.Delete(received.ID); err != models.ErrTransferNotDraft {
+		t.Errorf("received transfer deleted: %v", err)
+	}
+	if err := c.transfers.Delete(draft.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.transfers.GetByID(draft.ID); err != models.ErrTransferNotFound {
+		t.Errorf("deleted draft: %v", err)
+	}
+	if err := c.transfers.Delete(draft.ID); err != models.ErrTransferNotFound {
+		t.Errorf("deleted twice: %v", err)
+	}
+	if err := c.transfers.Delete(0); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+}
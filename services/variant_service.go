@@ -0,0 +1,183 @@
+package services
+
+import (
+	"cashier-api/models"
+	"strings"
+)
+
+type VariantService struct {
+	variantRepo VariantRepository
+	productRepo ProductRepository
+}
+
+func NewVariantService(variantRepo VariantRepository, productRepo ProductRepository) *VariantService {
+	return &VariantService{
+		variantRepo: variantRepo,
+		productRepo: productRepo,
+	}
+}
+
+func (s *VariantService) GetAll(productID int) ([]models.Variant, error) {
+	if productID <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	if _, err := s.productRepo.GetByID(productID, false); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.GetByProductID(productID)
+}
+
+// Create - actor is recorded in the audit log of the product
+func (s *VariantService) Create(variant *models.Variant, actor models.Actor) error {
+	if variant.ProductID <= 0 {
+		return models.ErrInvalidID
+	}
+	if err := s.validate(variant); err != nil {
+		return err
+	}
+	if err := s.variantRepo.Create(variant, actor); err != nil {
+		return err
+	}
+	s.productRepo.InvalidateCache() // barcode filter
+	return s.reload(variant)
+}
+
+func (s *VariantService) Update(variant *models.Variant, actor models.Actor) error {
+	if variant.ProductID <= 0 || variant.ID <= 0 {
+		return models.ErrInvalidID
+	}
+	if err := s.validate(variant); err != nil {
+		return err
+	}
+	if err := s.variantRepo.Update(variant, actor); err != nil {
+		return err
+	}
+	s.productRepo.InvalidateCache() // barcode filter
+	return s.reload(variant)
+}
+
+func (s *VariantService) Delete(productID int, id int, actor models.Actor) error {
+	if productID <= 0 || id <= 0 {
+		return models.ErrInvalidID
+	}
+	if err := s.variantRepo.Delete(productID, id, actor); err != nil {
+		return err
+	}
+	s.productRepo.InvalidateCache() // barcode filter
+	return nil
+}
+
+// SetOptionTypes - Define the option types (e.g. Size, Color) variants must
+// specify. They can only change while the product has no variants.
+func (s *VariantService) SetOptionTypes(productID int, optionTypes []string, actor models.Actor) (*models.ProductDetail, error) {
+	if productID <= 0 {
+		return nil, models.ErrInvalidID
+	}
+
+	seen := map[string]bool{}
+	cleaned := make([]string, 0, len(optionTypes))
+	for _, t := range optionTypes {
+		t = strings.TrimSpace(t)
+		key := strings.ToLower(t)
+		if t == "" || seen[key] {
+			return nil, models.ErrInvalidOptionTypes
+		}
+		seen[key] = true
+		cleaned = append(cleaned, t)
+	}
+
+	product, err := s.productRepo.GetByID(productID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameStrings(product.OptionTypes, cleaned) {
+		count, err := s.variantRepo.CountByProductID(productID)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, models.ErrVariantsExist
+		}
+	}
+
+	if err := s.productRepo.SetOptionTypes(productID, cleaned, actor); err != nil {
+		return nil, err
+	}
+
+	product, err = s.productRepo.GetByID(productID, false)
+	if err != nil {
+		return nil, err
+	}
+	if product.Variants, err = s.variantRepo.GetByProductID(productID); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// validate - SKU, price and stock checks; options must cover exactly the
+// option types of the (active) parent product
+func (s *VariantService) validate(variant *models.Variant) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	if variant.SKU == "" {
+		return models.ErrSKURequired
+	}
+	if variant.Barcode != nil {
+		barcode := strings.TrimSpace(*variant.Barcode)
+		if barcode == "" {
+			variant.Barcode = nil
+		} else {
+			variant.Barcode = &barcode
+		}
+	}
+	if variant.Price != nil && *variant.Price <= 0 {
+		return models.ErrInvalidVariantPrice
+	}
+	if variant.Stock < 0 {
+		return models.ErrInvalidStock
+	}
+
+	product, err := s.productRepo.GetByID(variant.ProductID, false)
+	if err != nil {
+		return err
+	}
+	if models.IsCountUnit(product.Unit) && !variant.Stock.IsWhole() {
+		return models.ErrFractionalQuantity
+	}
+
+	if len(variant.Options) != len(product.OptionTypes) {
+		return models.ErrInvalidOptions
+	}
+	for _, optionType := range product.OptionTypes {
+		if strings.TrimSpace(variant.Options[optionType]) == "" {
+			return models.ErrInvalidOptions
+		}
+	}
+	if variant.Options == nil {
+		variant.Options = map[string]string{}
+	}
+
+	return nil
+}
+
+// reload - Refresh read-only fields such as the effective price
+func (s *VariantService) reload(variant *models.Variant) error {
+	stored, err := s.variantRepo.GetByID(variant.ProductID, variant.ID)
+	if err != nil {
+		return err
+	}
+	*variant = *stored
+	return nil
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"cashier-api/models"
+)
+
+func mustQuantity(s string) models.Quantity {
+	q, err := models.ParseQuantity(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func variantPrice(price int) *int {
+	return &price
+}
+
+// newVariantCatalog - T-Shirt (1, sizes and colors, with the variant
+// TS-M-RED), Beras (2, by kg and grade) and an archived Jacket (3)
+func newVariantCatalog() *fakeCatalog {
+	c := newFakeCatalog()
+	c.categoryRepo.add(models.Category{ID: 1, Name: "Clothing"})
+	c.productRepo.add(models.ProductDetail{ID: 1, Name: "T-Shirt", Price: 75000, Unit: models.UnitPieces,
+		CategoryID: 1, OptionTypes: []string{"Size", "Color"}})
+	c.productRepo.add(models.ProductDetail{ID: 2, Name: "Beras", Price: 14000, Unit: models.UnitKilogram,
+		CategoryID: 1, OptionTypes: []string{"Grade"}})
+	jacket := c.productRepo.add(models.ProductDetail{ID: 3, Name: "Jacket", Price: 250000, CategoryID: 1})
+	jacket.DeletedAt = &time.Time{}
+	c.variantRepo.Create(&models.Variant{ProductID: 1, SKU: "TS-M-RED",
+		Options: map[string]string{"Size": "M", "Color": "Red"}, Stock: models.NewQuantity(4)}, models.Actor{Name: "seed"})
+	c.variantRepo.audit = nil
+	return c
+}
+
+func TestVariantCreate(t *testing.T) {
+	barcode := func(s string) *string { return &s }
+	redL := map[string]string{"Size": "L", "Color": "Red"}
+
+	tests := []struct {
+		name      string
+		variant   models.Variant
+		wantErr   error
+		wantPrice int
+	}{
+		{"product price", models.Variant{ProductID: 1, SKU: "TS-L-RED", Options: redL, Stock: models.NewQuantity(3)}, nil, 75000},
+		{"price override", models.Variant{ProductID: 1, SKU: "TS-L-RED", Options: redL, Price: variantPrice(80000)}, nil, 80000},
+		{"fractional kg", models.Variant{ProductID: 2, SKU: "BRS-PREMIUM", Options: map[string]string{"Grade": "Premium"},
+			Stock: mustQuantity("12.5")}, nil, 14000},
+		{"blank SKU", models.Variant{ProductID: 1, SKU: "  ", Options: redL}, models.ErrSKURequired, 0},
+		{"zero price", models.Variant{ProductID: 1, SKU: "TS-L-RED", Options: redL, Price: variantPrice(0)}, models.ErrInvalidVariantPrice, 0},
+		{"negative stock", models.Variant{ProductID: 1, SKU: "TS-L-RED", Options: redL, Stock: models.NewQuantity(-1)}, models.ErrInvalidStock, 0},
+		{"fractional pieces", models.Variant{ProductID: 1, SKU: "TS-L-RED", Options: redL, Stock: mustQuantity("0.5")}, models.ErrFractionalQuantity, 0},
+		{"option missing", models.Variant{ProductID: 1, SKU: "TS-L", Options: map[string]string{"Size": "L"}}, models.ErrInvalidOptions, 0},
+		{"blank option", models.Variant{ProductID: 1, SKU: "TS-L", Options: map[string]string{"Size": "L", "Color": " "}}, models.ErrInvalidOptions, 0},
+		{"unknown option type", models.Variant{ProductID: 1, SKU: "TS-L", Options: map[string]string{"Size": "L", "Fit": "Slim"}}, models.ErrInvalidOptions, 0},
+		{"extra option", models.Variant{ProductID: 1, SKU: "TS-L", Options: map[string]string{"Size": "L", "Color": "Red", "Fit": "Slim"}}, models.ErrInvalidOptions, 0},
+		{"no options", models.Variant{ProductID: 2, SKU: "BRS", Options: map[string]string{}}, models.ErrInvalidOptions, 0},
+		{"duplicate SKU", models.Variant{ProductID: 1, SKU: " TS-M-RED ", Options: redL}, models.ErrDuplicateSKU, 0},
+		{"duplicate options", models.Variant{ProductID: 1, SKU: "TS-M-RED-2", Options: map[string]string{"Size": "M", "Color": "Red"}}, models.ErrDuplicateVariant, 0},
+		{"archived product", models.Variant{ProductID: 3, SKU: "JKT", Barcode: barcode("899")}, errors.New("product not found"), 0},
+		{"unknown product", models.Variant{ProductID: 9, SKU: "X"}, errors.New("product not found"), 0},
+		{"invalid product ID", models.Variant{SKU: "X"}, models.ErrInvalidID, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newVariantCatalog()
+
+			variant := tt.variant
+			err := c.variants.Create(&variant, models.Actor{Name: "ayu"})
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.variantRepo.audit) != 0 {
+					t.Error("rejected variant was saved")
+				}
+				return
+			}
+			if variant.ID != 2 || variant.EffectivePrice != tt.wantPrice {
+				t.Errorf("created %+v, want ID 2 at %d", variant, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestVariantCreateCleansInput(t *testing.T) {
+	c := newVariantCatalog()
+	blank, padded := "  ", " 8991234567890 "
+
+	first := models.Variant{ProductID: 1, SKU: " TS-L-RED ", Barcode: &blank, Options: map[string]string{"Size": "L", "Color": "Red"}}
+	if err := c.variants.Create(&first, models.Actor{Name: "ayu"}); err != nil {
+		t.Fatal(err)
+	}
+	if first.SKU != "TS-L-RED" || first.Barcode != nil {
+		t.Errorf("SKU %q barcode %v, want trimmed and no barcode", first.SKU, first.Barcode)
+	}
+
+	second := models.Variant{ProductID: 1, SKU: "TS-S-RED", Barcode: &padded, Options: map[string]string{"Size": "S", "Color": "Red"}}
+	if err := c.variants.Create(&second, models.Actor{Name: "ayu"}); err != nil {
+		t.Fatal(err)
+	}
+	if second.Barcode == nil || *second.Barcode != "8991234567890" {
+		t.Errorf("barcode %v, want trimmed", second.Barcode)
+	}
+
+	// Products without option types take variants without options
+	plain := models.Variant{ProductID: 2, SKU: "BRS-5KG"}
+	c.productRepo.products[2].OptionTypes = []string{}
+	if err := c.variants.Create(&plain, models.Actor{Name: "ayu"}); err != nil {
+		t.Fatal(err)
+	}
+	if plain.Options == nil {
+		t.Error("options nil, want an empty object")
+	}
+}
+
+func TestVariantUpdate(t *testing.T) {
+	red := map[string]string{"Size": "M", "Color": "Red"}
+
+	tests := []struct {
+		name      string
+		variant   models.Variant
+		wantErr   error
+		wantPrice int
+	}{
+		{"override the price", models.Variant{ID: 1, ProductID: 1, SKU: "TS-M-RED", Options: red, Price: variantPrice(70000)}, nil, 70000},
+		{"back to the product price", models.Variant{ID: 1, ProductID: 1, SKU: "TS-M-RED", Options: red}, nil, 75000},
+		{"other options", models.Variant{ID: 1, ProductID: 1, SKU: "TS-M-BLUE", Options: map[string]string{"Size": "M", "Color": "Blue"}}, nil, 75000},
+		{"SKU of another variant", models.Variant{ID: 1, ProductID: 1, SKU: "TS-L-RED", Options: red}, models.ErrDuplicateSKU, 0},
+		{"options of another variant", models.Variant{ID: 1, ProductID: 1, SKU: "TS-M-RED", Options: map[string]string{"Size": "L", "Color": "Red"}}, models.ErrDuplicateVariant, 0},
+		{"invalid options", models.Variant{ID: 1, ProductID: 1, SKU: "TS-M-RED", Options: map[string]string{"Size": "M"}}, models.ErrInvalidOptions, 0},
+		{"unknown variant", models.Variant{ID: 9, ProductID: 1, SKU: "TS-M-RED", Options: red}, models.ErrVariantNotFound, 0},
+		{"variant of another product", models.Variant{ID: 1, ProductID: 2, SKU: "TS-M-RED", Options: map[string]string{"Grade": "A"}}, models.ErrVariantNotFound, 0},
+		{"invalid ID", models.Variant{ProductID: 1, SKU: "TS-M-RED", Options: red}, models.ErrInvalidID, 0},
+		{"invalid product ID", models.Variant{ID: 1, SKU: "TS-M-RED", Options: red}, models.ErrInvalidID, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newVariantCatalog()
+			c.variantRepo.Create(&models.Variant{ProductID: 1, SKU: "TS-L-RED",
+				Options: map[string]string{"Size": "L", "Color": "Red"}}, models.Actor{Name: "seed"})
+			c.variantRepo.audit = nil
+
+			variant := tt.variant
+			err := c.variants.Update(&variant, models.Actor{Name: "ayu"})
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.variantRepo.audit) != 0 {
+					t.Error("rejected update was saved")
+				}
+				return
+			}
+			if variant.EffectivePrice != tt.wantPrice || variant.SKU != tt.variant.SKU {
+				t.Errorf("updated %+v, want %s at %d", variant, tt.variant.SKU, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestVariantDelete(t *testing.T) {
+	c := newVariantCatalog()
+	actor := models.Actor{Name: "ayu"}
+
+	if err := c.variants.Delete(1, 0, actor); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+	if err := c.variants.Delete(2, 1, actor); err != models.ErrVariantNotFound {
+		t.Errorf("variant of another product: %v", err)
+	}
+	if err := c.variants.Delete(1, 1, actor); err != nil {
+		t.Fatal(err)
+	}
+	if variants, _ := c.variants.GetAll(1); len(variants) != 0 {
+		t.Errorf("variants %+v after the delete", variants)
+	}
+	if err := c.variants.Delete(1, 1, actor); err != models.ErrVariantNotFound {
+		t.Errorf("deleted twice: %v", err)
+	}
+}
+
+func TestVariantGetAll(t *testing.T) {
+	c := newVariantCatalog()
+
+	variants, err := c.variants.GetAll(1)
+	if err != nil || len(variants) != 1 || variants[0].SKU != "TS-M-RED" || variants[0].EffectivePrice != 75000 {
+		t.Errorf("variants %+v, %v", variants, err)
+	}
+	if variants, err := c.variants.GetAll(2); err != nil || variants == nil || len(variants) != 0 {
+		t.Errorf("product without variants: %v, %v; want an empty list", variants, err)
+	}
+	if _, err := c.variants.GetAll(3); !sameError(err, errors.New("product not found")) {
+		t.Errorf("archived product: %v", err)
+	}
+	if _, err := c.variants.GetAll(0); err != models.ErrInvalidID {
+		t.Errorf("invalid ID: %v", err)
+	}
+
+	// The product detail carries the variants
+	product, err := c.products.GetByID(1, false)
+	if err != nil || len(product.Variants) != 1 || product.Variants[0].ID != 1 {
+		t.Errorf("product detail variants: %+v, %v", product, err)
+	}
+}
+
+func TestSetOptionTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		productID   int
+		optionTypes []string
+		wantErr     error
+		want        []string
+	}{
+		{"no variants yet", 2, []string{" Grade ", "Origin"}, nil, []string{"Grade", "Origin"}},
+		{"cleared", 2, []string{}, nil, []string{}},
+		{"unchanged with variants", 1, []string{"Size", "Color"}, nil, []string{"Size", "Color"}},
+		{"changed with variants", 1, []string{"Size"}, models.ErrVariantsExist, nil},
+		{"reordered with variants", 1, []string{"Color", "Size"}, models.ErrVariantsExist, nil},
+		{"duplicate", 2, []string{"Grade", "grade"}, models.ErrInvalidOptionTypes, nil},
+		{"blank", 2, []string{"Grade", " "}, models.ErrInvalidOptionTypes, nil},
+		{"archived product", 3, []string{"Size"}, errors.New("product not found"), nil},
+		{"invalid ID", 0, []string{"Size"}, models.ErrInvalidID, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newVariantCatalog()
+
+			product, err := c.variants.SetOptionTypes(tt.productID, tt.optionTypes, models.Actor{Name: "ayu"})
+			if !sameError(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(c.productRepo.audit) != 0 {
+					t.Error("rejected option types were saved")
+				}
+				return
+			}
+			if !slices.Equal(product.OptionTypes, tt.want) || !slices.Equal(c.productRepo.products[tt.productID].OptionTypes, tt.want) {
+				t.Errorf("option types %q, want %q", product.OptionTypes, tt.want)
+			}
+			if tt.productID == 1 && len(product.Variants) != 1 {
+				t.Errorf("variants %+v, want them in the response", product.Variants)
+			}
+		})
+	}
+}
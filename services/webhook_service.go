@@ -0,0 +1,164 @@
+package services
+
+import (
+	"cashier-api/models"
+	"cashier-api/repositories"
+	"cashier-api/webhooks"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// minSecretLength - Shortest secret accepted from a client
+const minSecretLength = 16
+
+// WebhookService - allowPrivate accepts URLs on loopback, private and
+// link-local addresses (WEBHOOK_ALLOW_PRIVATE)
+type WebhookService struct {
+	repo         *repositories.WebhookRepository
+	allowPrivate bool
+}
+
+func NewWebhookService(repo *repositories.WebhookRepository, allowPrivate bool) *WebhookService {
+	return &WebhookService{repo: repo, allowPrivate: allowPrivate}
+}
+
+func (s *WebhookService) GetAll() ([]models.WebhookSubscription, error) {
+	return s.repo.GetAll()
+}
+
+func (s *WebhookService) GetByID(id int) (*models.WebhookSubscription, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	return s.repo.GetByID(id)
+}
+
+// Create - Save a subscription. Without a secret one is generated; the
+// returned subscription is the only place it is shown.
+func (s *WebhookService) Create(input models.WebhookSubscriptionInput) (*models.WebhookSubscription, error) {
+	subscription, err := s.buildSubscription(input)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Secret == "" {
+		if subscription.Secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Create(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Update - Replace a subscription; an empty secret keeps the current one
+func (s *WebhookService) Update(id int, input models.WebhookSubscriptionInput) (*models.WebhookSubscription, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	subscription, err := s.buildSubscription(input)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.ID = id
+	if err := s.repo.Update(subscription); err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+func (s *WebhookService) Delete(id int) error {
+	if id <= 0 {
+		return models.ErrInvalidID
+	}
+	return s.repo.Delete(id)
+}
+
+// GetDeliveries - Newest deliveries, of one subscription when
+// filter.SubscriptionID is set
+func (s *WebhookService) GetDeliveries(filter models.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	switch filter.Status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return nil, models.ErrInvalidDeliveryStatus
+	}
+	if filter.SubscriptionID != 0 {
+		if _, err := s.repo.GetByID(filter.SubscriptionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = models.DefaultDeliveryLimit
+	}
+	if filter.Limit > models.MaxDeliveryLimit {
+		filter.Limit = models.MaxDeliveryLimit
+	}
+	return s.repo.GetDeliveries(filter)
+}
+
+// RetryDelivery - Queue a dead letter again
+func (s *WebhookService) RetryDelivery(id int64) (*models.WebhookDelivery, error) {
+	if id <= 0 {
+		return nil, models.ErrInvalidID
+	}
+	return s.repo.RetryDelivery(id)
+}
+
+// buildSubscription - Validate the input; event types are de-duplicated
+func (s *WebhookService) buildSubscription(input models.WebhookSubscriptionInput) (*models.WebhookSubscription, error) {
+	target, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, models.ErrWebhookURL
+	}
+	if !s.allowPrivate {
+		if err := webhooks.CheckURL(target.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	var events []string
+	for _, event := range input.Events {
+		event = strings.TrimSpace(event)
+		if !slices.Contains(models.WebhookEvents, event) {
+			return nil, fmt.Errorf("%w %q", models.ErrUnknownWebhookEvent, event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, models.ErrWebhookEvents
+	}
+
+	if input.Secret != "" && len(input.Secret) < minSecretLength {
+		return nil, models.ErrWebhookSecretTooShort
+	}
+
+	active := true
+	if input.Active != nil {
+		active = *input.Active
+	}
+	return &models.WebhookSubscription{
+		URL:         target.String(),
+		Events:      events,
+		Secret:      input.Secret,
+		Description: strings.TrimSpace(input.Description),
+		Active:      active,
+	}, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"cashier-api/models"
+)
+
+// TestWebhookPrivateURL - Subscriptions to internal addresses are rejected
+// before anything is saved
+func TestWebhookPrivateURL(t *testing.T) {
+	s := NewWebhookService(nil, false)
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"http://127.0.0.1:9000/hooks", models.ErrWebhookPrivateURL},
+		{"http://localhost/hooks", models.ErrWebhookPrivateURL},
+		{"http://169.254.169.254/latest/meta-data/", models.ErrWebhookPrivateURL},
+		{"https://192.168.1.20/hooks", models.ErrWebhookPrivateURL},
+		{"ftp://shop.example.com/hooks", models.ErrWebhookURL},
+	}
+
+	for _, tt := range tests {
+		input := models.WebhookSubscriptionInput{URL: tt.url, Events: []string{models.EventProductUpdated}}
+		if _, err := s.Create(input); err != tt.wantErr {
+			t.Errorf("Create %s: error = %v, want %v", tt.url, err, tt.wantErr)
+		}
+		if _, err := s.Update(1, input); err != tt.wantErr {
+			t.Errorf("Update %s: error = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported file formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Content types used for upload detection and download responses
+const (
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+const sheetName = "Sheet1"
+
+var ErrUnsupportedFormat = errors.New("unsupported file format (use csv or xlsx)")
+
+// FormatFromContentType - Map a media type to a format ("" when unknown)
+func FormatFromContentType(mediaType string) string {
+	switch mediaType {
+	case ContentTypeCSV, "application/csv":
+		return FormatCSV
+	case ContentTypeXLSX:
+		return FormatXLSX
+	}
+	return ""
+}
+
+// FormatFromFilename - Map a file extension to a format ("" when unknown)
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	}
+	return ""
+}
+
+// ContentType - Response Content-Type for a format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return ContentTypeXLSX
+	}
+	return ContentTypeCSV + "; charset=utf-8"
+}
+
+// Read - Read all rows of a CSV file or of the first sheet of an XLSX file.
+// Cells escaped by Write get their original text back.
+func Read(r io.Reader, format string) ([][]string, error) {
+	rows, err := read(r, format)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = unescapeCell(cell)
+		}
+	}
+	return rows, nil
+}
+
+func read(r io.Reader, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		// Strip a UTF-8 BOM written by spreadsheet applications
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+		return rows, nil
+	case FormatXLSX:
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("workbook has no sheets")
+		}
+		return f.GetRows(sheets[0])
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Write - Write rows as CSV or as a single-sheet XLSX workbook. Cell values
+// keep their Go type in XLSX so numbers stay numeric. Strings that a
+// spreadsheet application would run as a formula are escaped.
+func Write(w io.Writer, format string, rows [][]interface{}) error {
+	rows = escapeRows(rows)
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		for _, row := range rows {
+			record := make([]string, len(row))
+			for i, v := range row {
+				record[i] = formatCell(v)
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatXLSX:
+		f := excelize.NewFile()
+		defer f.Close()
+
+		for i, row := range rows {
+			cell, err := excelize.CoordinatesToCellName(1, i+1)
+			if err != nil {
+				return err
+			}
+			if err := f.SetSheetRow(sheetName, cell, &row); err != nil {
+				return err
+			}
+		}
+		return f.Write(w)
+	}
+	return ErrUnsupportedFormat
+}
+
+// formatCell - CSV text of a value; floats never use exponent notation
+func formatCell(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// formulaPrefixes - Leading characters that make a spreadsheet application
+// treat a cell as a formula (e.g. =HYPERLINK(...)) or split it
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeRows - Copy of rows with formula-like strings prefixed by a quote,
+// which spreadsheet applications show as text
+func escapeRows(rows [][]interface{}) [][]interface{} {
+	escaped := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		escaped[i] = make([]interface{}, len(row))
+		for j, v := range row {
+			if s, ok := v.(string); ok && s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+				v = "'" + s
+			}
+			escaped[i][j] = v
+		}
+	}
+	return escaped
+}
+
+// unescapeCell - Undo escapeRows, so that exported files import unchanged
+func unescapeCell(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
@@ -0,0 +1,78 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var formulaRows = [][]interface{}{
+	{"id", "name", "price"},
+	{1, `=HYPERLINK("http://evil.example","Click")`, 3500},
+	{2, "+1", -5},
+	{3, "@SUM(A1)", 0},
+	{4, "\tTab", 1},
+	{5, "Plain - name", 2},
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, formulaRows); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`'=HYPERLINK(`, `'+1`, `'@SUM(A1)`, "'\tTab", "Plain - name", ",-5\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("CSV output lacks %q:\n%s", want, out)
+		}
+	}
+	if formulaRows[1][1] != `=HYPERLINK("http://evil.example","Click")` {
+		t.Error("Write modified the caller's rows")
+	}
+}
+
+func TestWriteXLSXEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatXLSX, formulaRows); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	value, err := f.GetCellValue(sheetName, "B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(value, "'=") {
+		t.Errorf("B2 = %q, want an escaped formula", value)
+	}
+	if formula, _ := f.GetCellFormula(sheetName, "B2"); formula != "" {
+		t.Errorf("B2 has formula %q", formula)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, formulaRows); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := Read(&buf, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := rows[1][1], formulaRows[1][1]; got != want {
+			t.Errorf("%s: name read back as %q, want %q", format, got, want)
+		}
+		if got := rows[2][1]; got != "+1" {
+			t.Errorf("%s: name read back as %q, want +1", format, got)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultLocalURL - Path the local files are served from (see Handler)
+const DefaultLocalURL = "/media"
+
+// Local - Files under a directory of the local filesystem
+type Local struct {
+	dir       string
+	publicURL string
+}
+
+func NewLocal(dir, publicURL string) (*Local, error) {
+	if dir == "" {
+		dir = "uploads"
+	}
+	if publicURL == "" {
+		publicURL = DefaultLocalURL
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir, publicURL: publicURL}, nil
+}
+
+// Put - Write to a temporary file first so readers never see a partial file
+func (s *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Local) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *Local) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
+
+// Handler - Serve the stored files (no directory listings); mount it under
+// DefaultLocalURL + "/"
+func (s *Local) Handler() http.Handler {
+	files := http.StripPrefix(DefaultLocalURL+"/", http.FileServer(http.Dir(s.dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const key = "products/1/image.jpg"
+	if err := s.Put(ctx, key, strings.NewReader("jpeg data"), 9, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "products", "1", "image.jpg"))
+	if err != nil || string(data) != "jpeg data" {
+		t.Fatalf("stored file = %q, %v", data, err)
+	}
+
+	// No temporary files are left next to the stored one
+	entries, err := os.ReadDir(filepath.Join(dir, "products", "1"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("directory has %d entries, %v; want 1", len(entries), err)
+	}
+
+	if got := s.URL(key); got != "/media/"+key {
+		t.Errorf("URL = %q", got)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/"+key, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "jpeg data" {
+		t.Errorf("GET /media/%s = %d %q", key, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/products/1/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("directory listing = %d, want 404", rec.Code)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "products", "1", "image.jpg")); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(filepath.Join(dir, "uploads"), "https://cdn.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"../outside.txt", "/outside.txt", "a/../../outside.txt"} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err != ErrInvalidKey {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := s.Delete(ctx, key); err != ErrInvalidKey {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.txt")); !os.IsNotExist(err) {
+		t.Error("Put wrote outside the storage directory")
+	}
+
+	if got := s.URL("a.jpg"); got != "https://cdn.example.com/a.jpg" {
+		t.Errorf("URL = %q", got)
+	}
+}
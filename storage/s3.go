@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 - Objects in a bucket of an S3-compatible service. Works with AWS S3
+// and with a local MinIO for development.
+type S3 struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+func NewS3(cfg Config) (*S3, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, errors.New("s3 storage needs STORAGE_S3_ENDPOINT and STORAGE_S3_BUCKET")
+	}
+
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		endpoint := url.URL{Scheme: "http", Host: cfg.S3Endpoint, Path: "/" + cfg.S3Bucket}
+		if cfg.S3UseSSL {
+			endpoint.Scheme = "https"
+		}
+		publicURL = endpoint.String()
+	}
+
+	return &S3{client: client, bucket: cfg.S3Bucket, publicURL: publicURL}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// TestS3 - Runs against the S3-compatible service of STORAGE_S3_TEST_ENDPOINT,
+// e.g. a local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	STORAGE_S3_TEST_ENDPOINT=localhost:9000 go test ./storage
+func TestS3(t *testing.T) {
+	endpoint := os.Getenv("STORAGE_S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("STORAGE_S3_TEST_ENDPOINT not set")
+	}
+	cfg := Config{
+		S3Endpoint:  endpoint,
+		S3Bucket:    envOr("STORAGE_S3_TEST_BUCKET", "cashier-test"),
+		S3AccessKey: envOr("STORAGE_S3_TEST_ACCESS_KEY", "minioadmin"),
+		S3SecretKey: envOr("STORAGE_S3_TEST_SECRET_KEY", "minioadmin"),
+	}
+	s, err := NewS3(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	exists, err := s.client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		if err := s.client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const key = "test/products/1/image.jpg"
+	if err := s.Put(ctx, key, strings.NewReader("jpeg data"), 9, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	object, err := s.client.GetObject(ctx, cfg.S3Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(object)
+	object.Close()
+	if err != nil || string(data) != "jpeg data" {
+		t.Fatalf("stored object = %q, %v", data, err)
+	}
+	info, err := s.client.StatObject(ctx, cfg.S3Bucket, key, minio.StatObjectOptions{})
+	if err != nil || info.ContentType != "image/jpeg" {
+		t.Errorf("content type = %q, %v", info.ContentType, err)
+	}
+
+	if got, want := s.URL(key), "http://"+endpoint+"/"+cfg.S3Bucket+"/"+key; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	if err := s.Put(ctx, "../escape", strings.NewReader("x"), 1, "text/plain"); err != ErrInvalidKey {
+		t.Errorf("Put with an invalid key = %v", err)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.client.StatObject(ctx, cfg.S3Bucket, key, minio.StatObjectOptions{}); err == nil {
+		t.Error("object still exists after Delete")
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
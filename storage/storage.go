@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Storage - Blob store for uploaded files (product images and thumbnails)
+type Storage interface {
+	// Put - Store size bytes from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete - Remove key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// URL - Public URL of key
+	URL(key string) string
+}
+
+// Config - Settings for the driver selected by Driver (local or s3)
+type Config struct {
+	Driver string
+
+	// Local filesystem
+	LocalDir string
+
+	// S3-compatible object storage (AWS S3, MinIO, ...)
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+
+	// PublicURL - Base URL the stored files are served from. Defaults to
+	// /media for local storage and to the bucket URL for S3.
+	PublicURL string
+}
+
+// New - Create the storage selected by cfg.Driver
+func New(cfg Config) (Storage, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "local":
+		return NewLocal(cfg.LocalDir, cfg.PublicURL)
+	case "s3":
+		return NewS3(cfg)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
+
+// ErrInvalidKey - Keys are relative slash separated paths without ".."
+var ErrInvalidKey = errors.New("invalid storage key")
+
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// joinURL - Base URL and key with exactly one slash between them
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
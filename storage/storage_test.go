@@ -0,0 +1,46 @@
+package storage
+
+import "testing"
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"products/1/abc.jpg", true},
+		{"a", true},
+		{"products/1/..jpg", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"products/../../secret", false},
+		{"products/..", false},
+		{"products/./a.jpg", false},
+		{"products//a.jpg", false},
+		{"products/", false},
+		{`products\..\a.jpg`, false},
+	}
+
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.valid {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.valid)
+		}
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	for _, base := range []string{"/media", "/media/", "/media//"} {
+		if got := joinURL(base, "products/1/a.jpg"); got != "/media/products/1/a.jpg" {
+			t.Errorf("joinURL(%q) = %q", base, got)
+		}
+	}
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	if _, err := New(Config{Driver: "ftp"}); err == nil {
+		t.Fatal("New accepted an unknown driver")
+	}
+	if _, err := New(Config{Driver: "s3"}); err == nil {
+		t.Fatal("New accepted s3 without endpoint and bucket")
+	}
+}
@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"cashier-api/models"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// lookupTimeout - Longest wait for the DNS answer when a subscription is saved
+const lookupTimeout = 5 * time.Second
+
+// PublicAddress - Whether ip may receive deliveries: not loopback, private
+// (10/8, 172.16/12, 192.168/16, fc00::/7), link-local (169.254/16 with the
+// cloud metadata endpoints, fe80::/10) or unspecified
+func PublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// CheckURL - Reject a subscription URL whose host is, or resolves to, an
+// address that is not public. A host that does not resolve (yet) is
+// accepted; the dispatcher checks the address again on every connection.
+func CheckURL(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return models.ErrWebhookURL
+	}
+	host := target.Hostname()
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !PublicAddress(ip) {
+			return models.ErrWebhookPrivateURL
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if !PublicAddress(ip) {
+			return models.ErrWebhookPrivateURL
+		}
+	}
+	return nil
+}
+
+// dialPublic - net.Dialer Control refusing connections to addresses that are
+// not public, after DNS resolution, so a name re-pointed to an internal
+// address after CheckURL is caught too
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", models.ErrWebhookPrivateURL, addrPort.Addr())
+	}
+	return nil
+}
+
+// newClient - HTTP client of the dispatcher. Redirects are not followed (a
+// 3xx answer is a failed attempt), and unless allowPrivate only public
+// addresses are dialed.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublic}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil // the proxy would connect on our behalf, unchecked
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"cashier-api/models"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"127.8.8.8", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"172.32.0.1", true},
+		{"192.168.1.10", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:93.184.215.14", true},
+	}
+
+	for _, tt := range tests {
+		if got := PublicAddress(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"https://93.184.215.14/hooks", nil},
+		{"http://127.0.0.1:8080/hooks", models.ErrWebhookPrivateURL},
+		{"http://[::1]/hooks", models.ErrWebhookPrivateURL},
+		{"http://10.0.0.5/hooks", models.ErrWebhookPrivateURL},
+		{"http://169.254.169.254/latest/meta-data/", models.ErrWebhookPrivateURL},
+		{"http://localhost:9000/hooks", models.ErrWebhookPrivateURL},
+		{"http://[::ffff:192.168.0.1]/hooks", models.ErrWebhookPrivateURL},
+		{"http://unresolvable.invalid/hooks", nil}, // checked again when dialing
+		{"http://%zz/hooks", models.ErrWebhookURL},
+	}
+
+	for _, tt := range tests {
+		if err := CheckURL(tt.url); err != tt.wantErr {
+			t.Errorf("CheckURL(%s) = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+// TestDispatchRefusesPrivateAddress - Without AllowPrivate the receiver on
+// 127.0.0.1 is never connected to, even though the URL passed no check
+func TestDispatchRefusesPrivateAddress(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	cfg := testConfig
+	cfg.AllowPrivate = false
+	store := &fakeStore{}
+	// A name resolving to loopback, as after a DNS change
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	store.add(models.WebhookDelivery{ID: 1, URL: url, Payload: []byte(`{}`), Secret: "0123456789abcdef"})
+
+	if err := NewDispatcher(store, cfg).DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 0 {
+		t.Error("private address connected to")
+	}
+	d := store.get(1)
+	if d.Status != models.DeliveryPending || d.LastError == nil ||
+		!strings.Contains(*d.LastError, models.ErrWebhookPrivateURL.Error()) {
+		t.Errorf("delivery %s, last error %v; want a failed attempt naming the private address", d.Status, d.LastError)
+	}
+}
+
+func TestDispatchRefusesRedirect(t *testing.T) {
+	var redirected atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected.Add(1)
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	store.add(models.WebhookDelivery{ID: 1, URL: server.URL, Payload: []byte(`{}`), Secret: "0123456789abcdef"})
+
+	if err := NewDispatcher(store, testConfig).DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if redirected.Load() != 0 {
+		t.Error("redirect followed")
+	}
+	d := store.get(1)
+	if d.Status != models.DeliveryPending || d.LastStatus == nil || *d.LastStatus != http.StatusTemporaryRedirect {
+		t.Errorf("delivery %s with status %v, want a failed attempt with 307", d.Status, d.LastStatus)
+	}
+}
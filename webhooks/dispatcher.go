@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"bytes"
+	"cashier-api/models"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request headers of every delivery
+const (
+	EventHeader     = "X-Webhook-Event"     // event type, e.g. product.updated
+	EventIDHeader   = "X-Webhook-ID"        // event ID, the same on retries
+	DeliveryHeader  = "X-Webhook-Delivery"  // delivery ID
+	TimestampHeader = "X-Webhook-Timestamp" // Unix seconds, part of the signature
+	SignatureHeader = "X-Webhook-Signature" // see Sign
+)
+
+// batchSize - Deliveries claimed and sent in parallel at a time
+const batchSize = 20
+
+// Store - Implemented by repositories.WebhookRepository
+type Store interface {
+	ClaimDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	MarkDelivered(id int64, status int) error
+	MarkFailed(id int64, status int, message string, retryAt *time.Time) error
+}
+
+// Config - Delivery and retry settings
+type Config struct {
+	Interval    time.Duration // how often the outbox is checked for due deliveries
+	Timeout     time.Duration // per attempt
+	MaxAttempts int           // attempts before a delivery becomes a dead letter
+	RetryBase   time.Duration // wait after the first failed attempt, doubled after each further one
+	RetryMax    time.Duration // longest wait between two attempts
+
+	// AllowPrivate - Deliver to loopback, private and link-local addresses
+	// (local development); otherwise such connections are refused
+	AllowPrivate bool
+}
+
+// Dispatcher - Sends the queued deliveries of the outbox. A delivery is
+// retried with exponential backoff until the receiver answers 2xx or
+// MaxAttempts is reached. Several instances can run side by side; each
+// claimed delivery is leased to one of them.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	cfg    Config
+}
+
+func NewDispatcher(store Store, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		client: newClient(cfg.Timeout, cfg.AllowPrivate),
+		cfg:    cfg,
+	}
+}
+
+// Run - Dispatch immediately and then every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchDue(ctx); err != nil {
+			log.Printf("webhooks: dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue - Send every due delivery, a batch at a time, until none is left
+func (d *Dispatcher) DispatchDue(ctx context.Context) error {
+	// Long enough for a whole batch, which is sent in parallel
+	lease := d.cfg.Timeout + time.Minute
+
+	for ctx.Err() == nil {
+		deliveries, err := d.store.ClaimDeliveries(batchSize, lease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, len(deliveries))
+		for i := range deliveries {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = d.attempt(ctx, deliveries[i])
+			}(i)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// attempt - Send one delivery and record the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		return d.store.MarkDelivered(delivery.ID, status)
+	}
+	if ctx.Err() != nil {
+		// Shutting down: the lease runs out and the attempt is repeated
+		return nil
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= d.cfg.MaxAttempts {
+		log.Printf("webhooks: giving up on delivery %d (%s to %s) after %d attempts: %v",
+			delivery.ID, delivery.EventType, delivery.URL, attempts, err)
+		return d.store.MarkFailed(delivery.ID, status, err.Error(), nil)
+	}
+
+	retryAt := time.Now().Add(Backoff(attempts, d.cfg.RetryBase, d.cfg.RetryMax))
+	return d.store.MarkFailed(delivery.ID, status, err.Error(), &retryAt)
+}
+
+// send - POST the event to the subscription URL. Returns the response status
+// (0 without a response) and an error unless it was 2xx.
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cashier-api-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff - Wait before the next attempt after attempts failed ones: base,
+// 2*base, 4*base, ... capped at max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		return max
+	}
+	return wait
+}
@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cashier-api/models"
+)
+
+// fakeStore - In-memory outbox with the semantics of WebhookRepository
+type fakeStore struct {
+	mu         sync.Mutex
+	deliveries []*models.WebhookDelivery
+}
+
+func (s *fakeStore) add(d models.WebhookDelivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.Status = models.DeliveryPending
+	d.NextAttemptAt = time.Now()
+	s.deliveries = append(s.deliveries, &d)
+}
+
+func (s *fakeStore) ClaimDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var claimed []models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, *d)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkDelivered(id int64, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.find(id)
+	now := time.Now()
+	d.Status = models.DeliveryDelivered
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.LastStatus = &status
+	d.DeliveredAt = &now
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(id int64, status int, message string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.find(id)
+	now := time.Now()
+	d.Status = models.DeliveryDead
+	if retryAt != nil {
+		d.Status = models.DeliveryPending
+		d.NextAttemptAt = *retryAt
+	}
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.LastStatus = &status
+	d.LastError = &message
+	return nil
+}
+
+func (s *fakeStore) find(id int64) *models.WebhookDelivery {
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	panic("unknown delivery " + strconv.FormatInt(id, 10))
+}
+
+// due - Make a pending delivery due now, as if its retry time had passed
+func (s *fakeStore) due(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.find(id).NextAttemptAt = time.Now()
+}
+
+func (s *fakeStore) get(id int64) models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.find(id)
+}
+
+// testConfig - The test receivers listen on 127.0.0.1
+var testConfig = Config{
+	Timeout:     5 * time.Second,
+	MaxAttempts: 3,
+	RetryBase:   time.Minute,
+	RetryMax:    time.Hour,
+
+	AllowPrivate: true,
+}
+
+func TestDispatchSignsDelivery(t *testing.T) {
+	const secret = "0123456789abcdef"
+	payload := []byte(`{"id":"evt-1","type":"product.updated","data":{"id":7}}`)
+
+	var (
+		mu       sync.Mutex
+		received *http.Request
+		body     []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	store.add(models.WebhookDelivery{
+		ID: 1, URL: server.URL, EventID: "evt-1", EventType: models.EventProductUpdated,
+		Payload: payload, Secret: secret,
+	})
+
+	if err := NewDispatcher(store, testConfig).DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received == nil {
+		t.Fatal("receiver was not called")
+	}
+	if string(body) != string(payload) {
+		t.Errorf("body = %s, want %s", body, payload)
+	}
+	timestamp, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", TimestampHeader, err)
+	}
+	signature := received.Header.Get(SignatureHeader)
+	if want := Sign(secret, timestamp, payload); signature != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, signature, want)
+	}
+	if !Verify(secret, timestamp, body, signature) {
+		t.Error("Verify rejected the signature")
+	}
+	for header, want := range map[string]string{
+		EventHeader:    models.EventProductUpdated,
+		EventIDHeader:  "evt-1",
+		DeliveryHeader: "1",
+	} {
+		if got := received.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	if d := store.get(1); d.Status != models.DeliveryDelivered || d.Attempts != 1 {
+		t.Errorf("delivery status = %s after %d attempts, want %s after 1", d.Status, d.Attempts, models.DeliveryDelivered)
+	}
+}
+
+func TestDispatchRetriesServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	store.add(models.WebhookDelivery{ID: 1, URL: server.URL, Payload: []byte(`{}`), Secret: "0123456789abcdef"})
+	dispatcher := NewDispatcher(store, testConfig)
+
+	for attempts := 1; attempts < testConfig.MaxAttempts; attempts++ {
+		before := time.Now()
+		if err := dispatcher.DispatchDue(context.Background()); err != nil {
+			t.Fatalf("DispatchDue: %v", err)
+		}
+		after := time.Now()
+
+		d := store.get(1)
+		if d.Status != models.DeliveryPending || d.Attempts != attempts {
+			t.Fatalf("delivery status = %s after %d attempts, want %s after %d",
+				d.Status, d.Attempts, models.DeliveryPending, attempts)
+		}
+		if d.LastStatus == nil || *d.LastStatus != http.StatusServiceUnavailable {
+			t.Errorf("last status = %v, want %d", d.LastStatus, http.StatusServiceUnavailable)
+		}
+		wait := Backoff(attempts, testConfig.RetryBase, testConfig.RetryMax)
+		if d.NextAttemptAt.Before(before.Add(wait)) || d.NextAttemptAt.After(after.Add(wait)) {
+			t.Errorf("attempt %d: next attempt in %s, want %s", attempts, d.NextAttemptAt.Sub(before), wait)
+		}
+
+		// Not due before the retry time
+		if err := dispatcher.DispatchDue(context.Background()); err != nil {
+			t.Fatalf("DispatchDue: %v", err)
+		}
+		if d := store.get(1); d.Attempts != attempts {
+			t.Fatalf("delivery retried before its retry time (%d attempts)", d.Attempts)
+		}
+		store.due(1)
+	}
+}
+
+func TestDispatchDeadLetter(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	store.add(models.WebhookDelivery{ID: 1, URL: server.URL, Payload: []byte(`{}`), Secret: "0123456789abcdef"})
+	dispatcher := NewDispatcher(store, testConfig)
+
+	for i := 0; i < testConfig.MaxAttempts+1; i++ {
+		if err := dispatcher.DispatchDue(context.Background()); err != nil {
+			t.Fatalf("DispatchDue: %v", err)
+		}
+		if store.get(1).Status == models.DeliveryPending {
+			store.due(1)
+		}
+	}
+
+	d := store.get(1)
+	if d.Status != models.DeliveryDead {
+		t.Errorf("delivery status = %s, want %s", d.Status, models.DeliveryDead)
+	}
+	if d.Attempts != testConfig.MaxAttempts {
+		t.Errorf("attempts = %d, want %d", d.Attempts, testConfig.MaxAttempts)
+	}
+	if d.LastError == nil || *d.LastError == "" {
+		t.Error("last error not recorded")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != testConfig.MaxAttempts {
+		t.Errorf("receiver called %d times, want %d", calls, testConfig.MaxAttempts)
+	}
+}